@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+)
+
+type AdminHandler struct {
+	authService *service.AuthService
+}
+
+func NewAdminHandler(authService *service.AuthService) *AdminHandler {
+	return &AdminHandler{authService: authService}
+}
+
+// UnlockUser 解除用户的登录锁定
+func (h *AdminHandler) UnlockUser(c *gin.Context) {
+	err := h.authService.UnlockUser(c.GetString("user_id"), c.Param("id"))
+	if err != nil {
+		switch err {
+		case service.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "账号已解锁"})
+}
@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
 )
 
 type AuthHandler struct {
@@ -16,10 +20,12 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 }
 
 type RegisterRequest struct {
-	IDCard   string `json:"id_card" binding:"required"`
-	Name     string `json:"name" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Role     string `json:"role" binding:"required,oneof=student teacher"`
+	IDCard       string `json:"id_card" binding:"required"`
+	DocumentType string `json:"document_type" binding:"omitempty,oneof=id_card passport hk_macau_permit taiwan_permit"`
+	Name         string `json:"name" binding:"required"`
+	Email        string `json:"email" binding:"omitempty,email"`
+	Password     string `json:"password" binding:"required"`
+	Role         string `json:"role" binding:"required,oneof=student teacher"`
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
@@ -30,10 +36,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	input := service.RegisterInput{
-		IDCard:   req.IDCard,
-		Name:     req.Name,
-		Password: req.Password,
-		Role:     req.Role,
+		IDCard:       req.IDCard,
+		DocumentType: req.DocumentType,
+		Name:         req.Name,
+		Email:        req.Email,
+		Password:     req.Password,
+		Role:         req.Role,
 	}
 
 	user, err := h.authService.Register(input)
@@ -44,10 +52,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":      user.ID,
-		"id_card": user.IDCard,
-		"name":    user.Name,
-		"role":    user.Role,
+		"id":            user.PublicID,
+		"id_card":       user.MaskedIDCard(),
+		"document_type": user.DocumentType,
+		"name":          user.Name,
+		"role":          user.Role,
 	})
 }
 
@@ -66,18 +75,36 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	input := service.LoginInput{
 		IDCard:   req.IDCard,
 		Password: req.Password,
+		IP:       c.ClientIP(),
 	}
 
-	token, err := h.authService.Login(input)
+	result, err := h.authService.Login(input)
 	if err != nil {
+		var blocked *service.LoginBlockedError
+		if errors.As(err, &blocked) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		status := http.StatusUnauthorized
 		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 需要两步验证时返回挑战令牌，客户端凭它调用 /login/mfa
+	if result.ChallengeToken != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":       true,
+			"mfa_setup_required": result.MFASetupRequired,
+			"challenge_token":    result.ChallengeToken,
+			"expires_in":         int(pkg.MFATokenTTL.Seconds()),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"token":      token,
-		"expires_in": 3600,
+		"token":      result.Token,
+		"expires_in": int(pkg.TokenTTL.Seconds()),
 		"token_type": "Bearer",
 	})
 }
@@ -4,18 +4,21 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
 )
 
 type CourseHandler struct {
 	courseService *service.CourseService
+	cursors       *pkg.CursorSigner
 }
 
-func NewCourseHandler(courseService *service.CourseService) *CourseHandler {
-	return &CourseHandler{courseService: courseService}
+func NewCourseHandler(courseService *service.CourseService, cursors *pkg.CursorSigner) *CourseHandler {
+	return &CourseHandler{courseService: courseService, cursors: cursors}
 }
 
 func (h *CourseHandler) CreateCourse(c *gin.Context) {
@@ -32,7 +35,8 @@ func (h *CourseHandler) CreateCourse(c *gin.Context) {
 		switch err {
 		case service.ErrUnauthorized:
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		case service.ErrTeacherNotFound, service.ErrInvalidDateFormat, service.ErrPastStartDate:
+		case service.ErrTeacherNotFound, service.ErrInvalidDateFormat, service.ErrPastStartDate,
+			service.ErrInvalidAudience, service.ErrInvalidYearLevel, service.ErrInvalidCategory, service.ErrInvalidCourseSize:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -44,46 +48,115 @@ func (h *CourseHandler) CreateCourse(c *gin.Context) {
 }
 
 func (h *CourseHandler) GetCourses(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "id")
-	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "ASC"))
-
-	fieldsParam := c.DefaultQuery("fields", "")
-	var fields []string
-	if fieldsParam != "" {
-		fields = strings.Split(fieldsParam, ",")
+	input, err := parseListInput(c, h.cursors, model.CourseProjection)
+	if err != nil {
+		listInputError(c, err)
+		return
 	}
 
-	// 验证排序字段
-	if !model.AllowedSortFields[sortBy] {
-		sortBy = "id"
+	response, err := h.courseService.GetCourses(input)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		return
 	}
 
-	// 验证排序方向
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchCourseQuery 课程搜索参数，所有条件均可选并可任意组合
+type SearchCourseQuery struct {
+	Code          string     `form:"code"`
+	Name          string     `form:"name"`
+	TeacherID     string     `form:"teacher_id"`
+	TeacherName   string     `form:"teacher_name"`
+	Category      string     `form:"category"`
+	Term          string     `form:"term"`
+	StartFrom     *time.Time `form:"start_from" time_format:"2006-01-02"`
+	StartTo       *time.Time `form:"start_to" time_format:"2006-01-02"`
+	MinHours      *int       `form:"min_hours" binding:"omitempty,min=0"`
+	MaxHours      *int       `form:"max_hours" binding:"omitempty,min=0"`
+	MinCredits    *float64   `form:"min_credits" binding:"omitempty,min=0"`
+	MaxCredits    *float64   `form:"max_credits" binding:"omitempty,min=0"`
+	AvailableOnly bool       `form:"available_only"`
+	Status        string     `form:"status"`
+	Tags          string     `form:"tags"` // 逗号分隔
+}
+
+func (q SearchCourseQuery) toFilter() model.CourseFilter {
+	filter := model.CourseFilter{
+		Code:          q.Code,
+		Name:          q.Name,
+		TeacherID:     q.TeacherID,
+		TeacherName:   q.TeacherName,
+		Category:      q.Category,
+		Term:          q.Term,
+		StartFrom:     q.StartFrom,
+		StartTo:       q.StartTo,
+		MinHours:      q.MinHours,
+		MaxHours:      q.MaxHours,
+		MinCredits:    q.MinCredits,
+		MaxCredits:    q.MaxCredits,
+		AvailableOnly: q.AvailableOnly,
+		Status:        q.Status,
+	}
+	if q.Tags != "" {
+		filter.Tags = strings.Split(q.Tags, ",")
 	}
+	return filter
+}
 
-	input := service.GetCoursesInput{
-		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-		},
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-		Fields:    fields,
+func (h *CourseHandler) SearchCourses(c *gin.Context) {
+	var query SearchCourseQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	response, err := h.courseService.GetCourses(input)
+	h.searchCourses(c, query.toFilter())
+}
+
+func (h *CourseHandler) searchCourses(c *gin.Context, filter model.CourseFilter) {
+	input, err := parseListInput(c, h.cursors, model.CourseProjection)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		listInputError(c, err)
+		return
+	}
+
+	response, err := h.courseService.SearchCourses(filter, input)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidCourseStatus:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+func (h *CourseHandler) GetCourse(c *gin.Context) {
+	courseID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效课程ID"})
+		return
+	}
+
+	course, err := h.courseService.GetCourse(c.GetString("user_id"), courseID)
+	if err != nil {
+		switch err {
+		case service.ErrCourseNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, course)
+}
+
 func (h *CourseHandler) DeleteCourse(c *gin.Context) {
 	teacherID := c.GetString("user_id")
 	courseID, err := strconv.ParseUint(c.Param("id"), 10, 32)
@@ -110,128 +183,50 @@ func (h *CourseHandler) DeleteCourse(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "课程删除成功"})
 }
 
-func (h *CourseHandler) GetTeacherCourses(c *gin.Context) {
-	teacherID := c.Param("id")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "id")
-	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "ASC"))
-
-	fieldsParam := c.DefaultQuery("fields", "")
-	var fields []string
-	if fieldsParam != "" {
-		fields = strings.Split(fieldsParam, ",")
-	}
-
-	// 验证排序字段
-	if !model.AllowedSortFields[sortBy] {
-		sortBy = "id"
-	}
-
-	// 验证排序方向
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
-	}
-
-	input := service.GetCoursesInput{
-		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-		},
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-		Fields:    fields,
-	}
-
-	response, err := h.courseService.GetTeacherCourses(teacherID, input)
+func (h *CourseHandler) FullTextSearch(c *gin.Context) {
+	pagination, err := parsePagination(c, h.cursors)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-func (h *CourseHandler) GetCoursesByTeacherName(c *gin.Context) {
-	teacherName := c.Param("teachername")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "id")
-	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "ASC"))
-	fieldsParam := c.DefaultQuery("fields", "")
-	var fields []string
-	if fieldsParam != "" {
-		fields = strings.Split(fieldsParam, ",")
-	}
-
-	// 验证排序字段
-	if !model.AllowedSortFields[sortBy] {
-		sortBy = "id"
-	}
-
-	// 验证排序方向
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
+	if pagination.IsCursor() && !pagination.Cursor.IsStart() && pagination.Cursor.SortBy != "score" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": pkg.ErrInvalidCursor.Error()})
+		return
 	}
-
-	input := service.GetCoursesInput{
-		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-		},
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-		Fields:    fields,
+	fields, err := parseFields(c, model.CourseProjection)
+	if err != nil {
+		listInputError(c, err)
+		return
 	}
 
-	response, err := h.courseService.GetCoursesByTeacherName(teacherName, input)
+	input := service.GetCoursesInput{Viewer: c.GetString("user_id"), Pagination: pagination, Fields: fields}
+	response, err := h.courseService.FullTextSearch(c.Query("q"), input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		switch err {
+		case service.ErrEmptySearchQuery, pkg.ErrInvalidCursor:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
-func (h *CourseHandler) GetCoursesByCourseName(c *gin.Context) {
-	courseName := c.Param("coursename")
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "id")
-	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "ASC"))
-	fieldsParam := c.DefaultQuery("fields", "")
-	var fields []string
-	if fieldsParam != "" {
-		fields = strings.Split(fieldsParam, ",")
-	}
-
-	// 验证排序字段
-	if !model.AllowedSortFields[sortBy] {
-		sortBy = "id"
-	}
-
-	// 验证排序方向
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
-	}
-
-	input := service.GetCoursesInput{
-		Pagination: model.Pagination{
-			Page:     page,
-			PageSize: pageSize,
-		},
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
-		Fields:    fields,
-	}
+// GetTeacherCourses 等价于 /courses/search?teacher_id=
+func (h *CourseHandler) GetTeacherCourses(c *gin.Context) {
+	h.searchCourses(c, model.CourseFilter{TeacherID: c.Param("id")})
+}
 
-	response, err := h.courseService.GetCoursesByCourseName(courseName, input)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
-		return
-	}
+// GetCoursesByTeacherName 等价于 /courses/search?teacher_name=
+func (h *CourseHandler) GetCoursesByTeacherName(c *gin.Context) {
+	h.searchCourses(c, model.CourseFilter{TeacherName: c.Param("teachername")})
+}
 
-	c.JSON(http.StatusOK, response)
+// GetCoursesByCourseName 等价于 /courses/search?name=
+func (h *CourseHandler) GetCoursesByCourseName(c *gin.Context) {
+	h.searchCourses(c, model.CourseFilter{Name: c.Param("coursename")})
 }
 
 func (h *CourseHandler) UpdateCourse(c *gin.Context) {
@@ -255,7 +250,8 @@ func (h *CourseHandler) UpdateCourse(c *gin.Context) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		case service.ErrCourseNotFound:
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-		case service.ErrInvalidDateFormat, service.ErrInvalidStudentNum:
+		case service.ErrInvalidDateFormat, service.ErrInvalidStudentNum, service.ErrInvalidCourseStatus,
+			service.ErrInvalidAudience, service.ErrInvalidYearLevel, service.ErrInvalidCategory, service.ErrInvalidCourseSize:
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		default:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -3,30 +3,31 @@ package handler
 import (
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
 )
 
 type EnrollmentHandler struct {
 	service *service.EnrollmentService
+	cursors *pkg.CursorSigner
 }
 
-func NewEnrollmentHandler(service *service.EnrollmentService) *EnrollmentHandler {
-	return &EnrollmentHandler{service: service}
+func NewEnrollmentHandler(service *service.EnrollmentService, cursors *pkg.CursorSigner) *EnrollmentHandler {
+	return &EnrollmentHandler{service: service, cursors: cursors}
 }
 
 func (h *EnrollmentHandler) Enroll(c *gin.Context) {
-	studentIDCard := c.GetString("user_id")
+	studentID := c.GetString("user_id")
 	courseID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效课程ID"})
 		return
 	}
 
-	if err := h.service.Enroll(studentIDCard, courseID); err != nil {
+	if err := h.service.Enroll(studentID, courseID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -35,31 +36,15 @@ func (h *EnrollmentHandler) Enroll(c *gin.Context) {
 }
 
 func (h *EnrollmentHandler) GetStudentCourses(c *gin.Context) {
-	studentIDCard := c.GetString("user_id")
-
-	// 绑定分页参数
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-	sortBy := c.DefaultQuery("sort_by", "id")
-	sortOrder := c.DefaultQuery("sort_order", "ASC")
-
-	fieldsParam := c.DefaultQuery("fields", "")
-	var fields []string
-	if fieldsParam != "" {
-		fields = strings.Split(fieldsParam, ",")
-	}
-
-	// 验证排序字段
-	if !model.AllowedSortFields[sortBy] {
-		sortBy = "id"
-	}
+	studentID := c.GetString("user_id")
 
-	// 验证排序方向
-	if sortOrder != "ASC" && sortOrder != "DESC" {
-		sortOrder = "ASC"
+	input, err := parseListInput(c, h.cursors, model.CourseProjection)
+	if err != nil {
+		listInputError(c, err)
+		return
 	}
 
-	response, err := h.service.GetStudentCourses(studentIDCard, page, pageSize, sortBy, sortOrder, fields)
+	response, err := h.service.GetStudentCourses(studentID, input.Pagination, input.SortBy, input.SortOrder, input.Fields)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -69,17 +54,45 @@ func (h *EnrollmentHandler) GetStudentCourses(c *gin.Context) {
 }
 
 func (h *EnrollmentHandler) DeleteEnroll(c *gin.Context) {
-	studentIDCard := c.GetString("user_id")
+	studentID := c.GetString("user_id")
 	courseID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "无效课程ID"})
 		return
 	}
 
-	if err := h.service.DeleteEnrollment(studentIDCard, courseID); err != nil {
+	if err := h.service.DeleteEnrollment(studentID, courseID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "课程退选成功"})
 }
+
+// RecordResult 教师登记学生的修读结果
+func (h *EnrollmentHandler) RecordResult(c *gin.Context) {
+	courseID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效课程ID"})
+		return
+	}
+
+	var input service.RecordResultInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.service.RecordResult(c.GetString("user_id"), courseID, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"course_id":  enrollment.CourseID,
+		"student_id": input.StudentID,
+		"status":     enrollment.Status,
+		"score":      enrollment.Score,
+	})
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+type JWKSHandler struct {
+	tokens *pkg.TokenSigner
+}
+
+func NewJWKSHandler(tokens *pkg.TokenSigner) *JWKSHandler {
+	return &JWKSHandler{tokens: tokens}
+}
+
+// JWKS 公开令牌签名公钥，只使用 HMAC 密钥时返回空列表
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokens.JWKS())
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+type MFALoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+type MFAChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CompleteMFALogin 登录第二步，提交 TOTP 验证码或恢复码换取正式令牌
+func (h *AuthHandler) CompleteMFALogin(c *gin.Context) {
+	var req MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.authService.CompleteMFALogin(service.MFALoginInput{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		IP:             c.ClientIP(),
+	})
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+
+	resp := gin.H{
+		"token":      result.Token,
+		"expires_in": int(pkg.TokenTTL.Seconds()),
+		"token_type": "Bearer",
+	}
+	if len(result.RecoveryCodes) > 0 {
+		resp["recovery_codes"] = result.RecoveryCodes
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SetupMFAWithChallenge 角色要求两步验证但尚未启用的用户，在登录过程中获取 TOTP 密钥
+func (h *AuthHandler) SetupMFAWithChallenge(c *gin.Context) {
+	var req MFAChallengeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setup, err := h.authService.SetupMFAWithChallenge(req.ChallengeToken)
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// SetupMFA 生成待确认的 TOTP 密钥和二维码 URI
+func (h *AuthHandler) SetupMFA(c *gin.Context) {
+	setup, err := h.authService.SetupMFA(c.GetString("user_id"))
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, setup)
+}
+
+// EnableMFA 提交验证码确认密钥，启用两步验证并返回恢复码
+func (h *AuthHandler) EnableMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.EnableMFA(c.GetString("user_id"), req.Code, c.ClientIP())
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableMFA 关闭两步验证
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.authService.DisableMFA(c.GetString("user_id"), req.Code, c.ClientIP()); err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "已关闭两步验证"})
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(c.GetString("user_id"), req.Code, c.ClientIP())
+	if err != nil {
+		mfaError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+func mfaError(c *gin.Context, err error) {
+	var blocked *service.LoginBlockedError
+	if errors.As(err, &blocked) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err {
+	case service.ErrInvalidMFACode, service.ErrInvalidChallenge:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case service.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case service.ErrMFAAlreadyEnabled, service.ErrMFANotEnabled, service.ErrMFASetupRequired, service.ErrMFAMandatory:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+)
+
+type OrganizationHandler struct {
+	orgService *service.OrganizationService
+}
+
+func NewOrganizationHandler(orgService *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{orgService: orgService}
+}
+
+func (h *OrganizationHandler) ListDepartments(c *gin.Context) {
+	departments, err := h.orgService.ListDepartments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": departments})
+}
+
+func (h *OrganizationHandler) ListMajors(c *gin.Context) {
+	departmentID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的院系ID"})
+		return
+	}
+
+	majors, err := h.orgService.ListMajors(departmentID)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": majors})
+}
+
+func (h *OrganizationHandler) ListCohorts(c *gin.Context) {
+	majorID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的专业ID"})
+		return
+	}
+
+	cohorts, err := h.orgService.ListCohorts(majorID)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": cohorts})
+}
+
+func (h *OrganizationHandler) CreateDepartment(c *gin.Context) {
+	var input service.CreateDepartmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	department, err := h.orgService.CreateDepartment(input)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, department)
+}
+
+func (h *OrganizationHandler) CreateMajor(c *gin.Context) {
+	var input service.CreateMajorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	major, err := h.orgService.CreateMajor(input)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, major)
+}
+
+func (h *OrganizationHandler) CreateCohort(c *gin.Context) {
+	var input service.CreateCohortInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cohort, err := h.orgService.CreateCohort(input)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, cohort)
+}
+
+// AssignOrganization 设置用户的院系、专业和行政班
+func (h *OrganizationHandler) AssignOrganization(c *gin.Context) {
+	var input service.AssignOrganizationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.orgService.AssignOrganization(c.Param("id"), input)
+	if err != nil {
+		organizationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":              user.PublicID,
+		"department_id":   user.DepartmentID,
+		"major_id":        user.MajorID,
+		"cohort_id":       user.CohortID,
+		"enrollment_year": user.EnrollmentYear,
+	})
+}
+
+func organizationError(c *gin.Context, err error) {
+	switch err {
+	case service.ErrUserNotFound, service.ErrDepartmentNotFound, service.ErrMajorNotFound, service.ErrCohortNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case service.ErrOrganizationMismatch, service.ErrStudentOnlyAssignment, service.ErrInvalidOrganizationArg:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
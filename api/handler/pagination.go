@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+// parseListInput 解析列表接口通用的分页、排序和字段参数，字段需在 projection 中
+func parseListInput(c *gin.Context, cursors *pkg.CursorSigner, projection *model.Projection) (service.GetCoursesInput, error) {
+	pagination, err := parsePagination(c, cursors)
+	if err != nil {
+		return service.GetCoursesInput{}, err
+	}
+
+	sortBy := c.DefaultQuery("sort_by", "id")
+	sortOrder := strings.ToUpper(c.DefaultQuery("sort_order", "ASC"))
+
+	fields, err := parseFields(c, projection)
+	if err != nil {
+		return service.GetCoursesInput{}, err
+	}
+
+	// 验证排序字段
+	sortField, ok := model.AllowedSortFields[sortBy]
+	if !ok {
+		sortField = "id"
+	}
+
+	// 验证排序方向
+	if sortOrder != "ASC" && sortOrder != "DESC" {
+		sortOrder = "ASC"
+	}
+
+	// 游标只能在生成它的排序方式下使用
+	if cursor := pagination.Cursor; cursor != nil {
+		if cursor.IsStart() {
+			cursor.SortBy, cursor.Order = sortField, sortOrder
+		} else if cursor.SortBy != sortField || cursor.Order != sortOrder {
+			return service.GetCoursesInput{}, pkg.ErrInvalidCursor
+		}
+	}
+
+	return service.GetCoursesInput{
+		Viewer:     c.GetString("user_id"),
+		Pagination: pagination,
+		SortBy:     sortField,
+		SortOrder:  sortOrder,
+		Fields:     fields,
+	}, nil
+}
+
+// parseFields 解析逗号分隔的 fields 参数，字段需在 projection 中，为空表示全部字段
+func parseFields(c *gin.Context, projection *model.Projection) ([]string, error) {
+	var fields []string
+	for _, f := range strings.Split(c.Query("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if err := projection.Validate(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// parsePagination 解析分页参数。
+// 请求中带有 cursor 参数时使用游标分页，cursor 为空表示从第一页开始，非空时用 cursors 校验签名。
+func parsePagination(c *gin.Context, cursors *pkg.CursorSigner) (model.Pagination, error) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	pagination := model.Pagination{
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	if raw, ok := c.GetQuery("cursor"); ok {
+		cursor := &model.Cursor{}
+		if raw != "" {
+			if err := cursors.Decode(raw, cursor); err != nil {
+				return model.Pagination{}, err
+			}
+		}
+		pagination.Cursor = cursor
+	}
+
+	return pagination, nil
+}
+
+// listInputError 将参数错误写入响应，未知字段时附带可选字段列表
+func listInputError(c *gin.Context, err error) {
+	var fieldErr *model.UnknownFieldError
+	if errors.As(err, &fieldErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "allowed_fields": fieldErr.Allowed})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+type PasswordHandler struct {
+	passwordService *service.PasswordService
+}
+
+func NewPasswordHandler(passwordService *service.PasswordService) *PasswordHandler {
+	return &PasswordHandler{passwordService: passwordService}
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+	Code        string `json:"code"` // 启用了两步验证时必填
+}
+
+// ChangePassword 修改当前用户的密码，其他设备上的登录随之失效
+func (h *PasswordHandler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.passwordService.ChangePassword(c.GetString("user_id"), service.ChangePasswordInput{
+		OldPassword: req.OldPassword,
+		NewPassword: req.NewPassword,
+		Code:        req.Code,
+		IP:          c.ClientIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var blocked *service.LoginBlockedError
+		if errors.As(err, &blocked) {
+			mfaError(c, err)
+			return
+		}
+		switch err {
+		case service.ErrWrongPassword:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case service.ErrInvalidMFACode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case service.ErrSamePassword, service.ErrMFACodeRequired, service.ErrCurrentPasswordRequired:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case service.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":      token,
+		"expires_in": int(pkg.TokenTTL.Seconds()),
+		"token_type": "Bearer",
+	})
+}
+
+type ForgotPasswordRequest struct {
+	IDCard string `json:"id_card" binding:"required"`
+}
+
+// ForgotPassword 申请重置密码，无论账号是否存在都返回相同结果
+func (h *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.passwordService.ForgotPassword(service.ForgotPasswordInput{IDCard: req.IDCard, IP: c.ClientIP()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "如果该账号已绑定邮箱，重置邮件将发送到该邮箱"})
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// ResetPassword 使用邮件中的令牌设置新密码
+func (h *PasswordHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.passwordService.ResetPassword(service.ResetPasswordInput{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+		IP:          c.ClientIP(),
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPassword) || err == service.ErrInvalidResetToken {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "密码已重置，请重新登录"})
+}
@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	cursors        *pkg.CursorSigner
+}
+
+func NewProfileHandler(profileService *service.ProfileService, cursors *pkg.CursorSigner) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService, cursors: cursors}
+}
+
+type UpdateProfileRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=50"`
+	Email       *string `json:"email" binding:"omitempty,max=254"`
+	Phone       *string `json:"phone" binding:"omitempty,max=20"`
+	Language    *string `json:"language"`
+	AvatarURL   *string `json:"avatar_url" binding:"omitempty,max=512"`
+	Bio         *string `json:"bio" binding:"omitempty,max=2000"`
+	OfficeHours *string `json:"office_hours" binding:"omitempty,max=255"`
+
+	// 修改邮箱时必填当前密码，启用了两步验证时还需填写验证码
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"`
+}
+
+// GetProfile 查看自己的资料
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	user, err := h.profileService.GetProfile(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, profileResponse(user))
+}
+
+// UpdateProfile 修改自己的资料，只修改请求中出现的字段
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(c.GetString("user_id"), service.UpdateProfileInput{
+		Name:        req.Name,
+		Email:       req.Email,
+		Phone:       req.Phone,
+		Language:    req.Language,
+		AvatarURL:   req.AvatarURL,
+		Bio:         req.Bio,
+		OfficeHours: req.OfficeHours,
+
+		CurrentPassword: req.CurrentPassword,
+		Code:            req.Code,
+		IP:              c.ClientIP(),
+	})
+	if err != nil {
+		var blocked *service.LoginBlockedError
+		if errors.As(err, &blocked) {
+			mfaError(c, err)
+			return
+		}
+		switch err {
+		case service.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case service.ErrInvalidName, service.ErrInvalidEmail, service.ErrInvalidPhone, service.ErrInvalidLanguage, service.ErrInvalidAvatarURL,
+			service.ErrCurrentPasswordRequired, service.ErrMFACodeRequired:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case service.ErrTeacherOnlyField, service.ErrWrongPassword:
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case service.ErrInvalidMFACode:
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, profileResponse(user))
+}
+
+// GetTeacherProfile 教师公开主页，id 与课程列表中的 teacher_id 相同。
+// 课程列表支持与 /courses 相同的分页、排序和字段参数。
+func (h *ProfileHandler) GetTeacherProfile(c *gin.Context) {
+	input, err := parseListInput(c, h.cursors, model.CourseProjection)
+	if err != nil {
+		listInputError(c, err)
+		return
+	}
+
+	profile, err := h.profileService.GetTeacherProfile(c.Param("id"), input)
+	if err != nil {
+		switch err {
+		case service.ErrTeacherNotFound:
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	teacher := profile.Teacher
+	c.JSON(http.StatusOK, gin.H{
+		"id":           teacher.PublicID,
+		"name":         teacher.Name,
+		"department":   profile.Department,
+		"avatar_url":   teacher.AvatarURL,
+		"bio":          teacher.Bio,
+		"office_hours": teacher.OfficeHours,
+		"courses":      profile.Courses,
+	})
+}
+
+// profileResponse 本人可见的资料，证件号脱敏
+func profileResponse(user *model.User) gin.H {
+	resp := gin.H{
+		"id":              user.PublicID,
+		"id_card":         user.MaskedIDCard(),
+		"document_type":   user.DocumentType,
+		"name":            user.Name,
+		"role":            user.Role,
+		"email":           user.Email,
+		"phone":           user.Phone,
+		"department_id":   user.DepartmentID,
+		"major_id":        user.MajorID,
+		"cohort_id":       user.CohortID,
+		"enrollment_year": user.EnrollmentYear,
+		"year_level":      model.YearLevel(user.EnrollmentYear, time.Now()),
+		"language":        user.Language,
+		"avatar_url":      user.AvatarURL,
+		"mfa_enabled":     user.MFAEnabled,
+	}
+	if user.Role == "teacher" {
+		resp["bio"] = user.Bio
+		resp["office_hours"] = user.OfficeHours
+	}
+	return resp
+}
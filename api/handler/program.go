@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/service"
+)
+
+type ProgramHandler struct {
+	programService *service.ProgramService
+}
+
+func NewProgramHandler(programService *service.ProgramService) *ProgramHandler {
+	return &ProgramHandler{programService: programService}
+}
+
+// GetProgram 查看专业的培养方案
+func (h *ProgramHandler) GetProgram(c *gin.Context) {
+	majorID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的专业ID"})
+		return
+	}
+
+	program, err := h.programService.GetProgram(majorID)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, program)
+}
+
+// SaveProgram 设置专业的培养方案
+func (h *ProgramHandler) SaveProgram(c *gin.Context) {
+	majorID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的专业ID"})
+		return
+	}
+
+	var input service.SaveProgramInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	program, err := h.programService.SaveProgram(majorID, input)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, program)
+}
+
+// GetMyAudit 当前学生的学业审核
+func (h *ProgramHandler) GetMyAudit(c *gin.Context) {
+	h.audit(c, c.GetString("user_id"))
+}
+
+// GetStudentAudit 管理员查看指定学生的学业审核
+func (h *ProgramHandler) GetStudentAudit(c *gin.Context) {
+	h.audit(c, c.Param("id"))
+}
+
+func (h *ProgramHandler) audit(c *gin.Context, studentID string) {
+	audit, err := h.programService.Audit(studentID)
+	if err != nil {
+		programError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, audit)
+}
+
+func programError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrInvalidProgram) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err {
+	case service.ErrMajorNotFound, service.ErrProgramNotFound, service.ErrStudentNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case service.ErrMajorNotAssigned:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -8,8 +8,13 @@ import (
 	"github.com/liuyifan1996/course-selection-system/pkg"
 )
 
+// SessionValidator 校验令牌对应的会话是否仍然有效，例如密码修改后旧令牌失效
+type SessionValidator interface {
+	ValidateSession(userID string, tokenVersion int) error
+}
+
 // 简化版认证中间件
-func AuthMiddleware() gin.HandlerFunc {
+func AuthMiddleware(tokens *pkg.TokenSigner, sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -24,11 +29,15 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := pkg.ParseToken(tokenString)
+		claims, err := tokens.ParseToken(tokenString)
 		if err != nil {
 			c.AbortWithStatusJSON(401, gin.H{"error": "无效令牌"})
 			return
 		}
+		if err := sessions.ValidateSession(claims.UserID, claims.Version); err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
 
 		// 将用户信息存入上下文
 		c.Set("user_id", claims.UserID)
@@ -36,3 +45,17 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireRole 只允许指定角色访问，需在 AuthMiddleware 之后使用
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, r := range roles {
+			if role == r {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足"})
+	}
+}
@@ -6,16 +6,100 @@ import (
 	"gorm.io/gorm"
 )
 
+// 课程状态
+const (
+	CourseStatusOpen      = "open"      // 开放选课
+	CourseStatusClosed    = "closed"    // 停止选课
+	CourseStatusCancelled = "cancelled" // 已取消
+)
+
+// 课程类别，用于培养方案按类别统计学分
+const (
+	CourseCategoryGeneral    = "general"    // 通识教育
+	CourseCategoryFoundation = "foundation" // 学科基础
+	CourseCategoryCore       = "core"       // 专业核心
+	CourseCategoryElective   = "elective"   // 专业选修
+	CourseCategoryPractice   = "practice"   // 实践环节
+)
+
 type Course struct {
 	gorm.Model
 	ID            int64     `gorm:"primaryKey;autoIncrement"`
+	Code          string    `gorm:"size:20;index"` // 课程编号，同一门课程各学期开课的编号相同
 	Name          string    `gorm:"size:60;not null"`
-	TeacherID     string    `gorm:"size:20;not null"`
+	TeacherID     string    `gorm:"size:36;not null;index"` // 教师的 PublicID
 	Remark        string    `gorm:"size:200"`
 	StudentMaxNum int       `gorm:"not null"`
 	Hours         int       `gorm:"not null"`
+	Credits       float64   `gorm:"type:decimal(4,1);not null;default:0"`
+	Category      string    `gorm:"size:20"`       // 课程类别，为空表示未分类
+	Term          string    `gorm:"size:20;index"` // 学期，如 2025-2026-1
+	Status        string    `gorm:"size:20;not null;default:'open'"`
 	StartDate     time.Time `gorm:"type:date;not null"`
+	MinYearLevel  int       `gorm:"not null;default:0"` // 允许选课的最低年级，0 表示不限
+	MaxYearLevel  int       `gorm:"not null;default:0"` // 允许选课的最高年级，0 表示不限
 
 	// 关联关系
-	Students []User `gorm:"many2many:enrollments;foreignKey:ID;joinForeignKey:CourseID;References:ID;joinReferences:StudentID"`
+	Students  []User           `gorm:"many2many:enrollments;foreignKey:ID;joinForeignKey:CourseID;References:ID;joinReferences:StudentID"`
+	Tags      []CourseTag      `gorm:"foreignKey:CourseID"`
+	Audiences []CourseAudience `gorm:"foreignKey:CourseID"`
+}
+
+// CourseTag 课程标签，一门课程可以有多个标签
+type CourseTag struct {
+	CourseID int64  `gorm:"primaryKey" json:"-"`
+	Tag      string `gorm:"primaryKey;size:30" json:"tag"`
+}
+
+// CourseProjection 课程列表可选择的字段。
+// 表达式中的 teachers、course_counts、viewer_enrollments 由课程列表查询统一关联。
+var CourseProjection = NewProjection(
+	ProjectedField{Name: "id", Expr: "courses.id"},
+	ProjectedField{Name: "code", Expr: "courses.code"},
+	ProjectedField{Name: "name", Expr: "courses.name"},
+	ProjectedField{Name: "teacher_id", Expr: "courses.teacher_id"},
+	ProjectedField{Name: "teacher_name", Expr: "teachers.name"},
+	ProjectedField{Name: "remark", Expr: "courses.remark"},
+	ProjectedField{Name: "student_maxnum", Expr: "courses.student_max_num"},
+	ProjectedField{Name: "enrolled_count", Expr: "COALESCE(course_counts.enrolled_count, 0)"},
+	ProjectedField{Name: "remaining_seats", Expr: "courses.student_max_num - COALESCE(course_counts.enrolled_count, 0)"},
+	ProjectedField{Name: "is_enrolled", Expr: "CASE WHEN viewer_enrollments.course_id IS NULL THEN 0 ELSE 1 END"},
+	ProjectedField{Name: "hours", Expr: "courses.hours"},
+	ProjectedField{Name: "credits", Expr: "courses.credits"},
+	ProjectedField{Name: "category", Expr: "courses.category"},
+	ProjectedField{Name: "term", Expr: "courses.term"},
+	ProjectedField{Name: "status", Expr: "courses.status"},
+	ProjectedField{Name: "start_date", Expr: "courses.start_date"},
+	ProjectedField{Name: "min_year_level", Expr: "courses.min_year_level"},
+	ProjectedField{Name: "max_year_level", Expr: "courses.max_year_level"},
+)
+
+// CourseFilter 课程搜索条件，零值字段表示不过滤
+type CourseFilter struct {
+	Code          string
+	Name          string
+	TeacherID     string
+	TeacherName   string   // 按教师姓名模糊匹配，由服务层转换为 TeacherIDs，仓库不处理
+	TeacherIDs    []string // 课程的教师需在其中，为空表示不过滤
+	Category      string
+	Term          string
+	StartFrom     *time.Time
+	StartTo       *time.Time
+	MinHours      *int
+	MaxHours      *int
+	MinCredits    *float64
+	MaxCredits    *float64
+	AvailableOnly bool // 只返回还有剩余名额的课程
+	Status        string
+	Tags          []string // 需同时包含所有标签
+
+	// ViewerID 当前查看课程的用户ID，只用于计算 is_enrolled，不参与过滤
+	ViewerID int64
+}
+
+// CourseSearchHit 全文检索的一条结果，Course 只包含按 CourseProjection 选择的字段
+type CourseSearchHit struct {
+	Course     map[string]interface{} `json:"course"`
+	Score      float64                `json:"score"`
+	Highlights map[string]string      `json:"highlights,omitempty"`
 }
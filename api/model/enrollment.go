@@ -1,6 +1,26 @@
 package model
 
+// 选课状态
+const (
+	EnrollmentStatusEnrolled = "enrolled" // 在修
+	EnrollmentStatusPassed   = "passed"   // 已通过，学分计入学业审核
+	EnrollmentStatusFailed   = "failed"   // 未通过
+)
+
 type Enrollment struct {
-	CourseID  int64 `gorm:"primarykey"`
-	StudentID int64 `gorm:"primarykey"`
+	CourseID  int64    `gorm:"primarykey"`
+	StudentID int64    `gorm:"primarykey"`
+	Status    string   `gorm:"size:20;not null;default:'enrolled'"`
+	Score     *float64 `gorm:"type:decimal(5,2)"` // 成绩，未登记时为空
+}
+
+// CourseRecord 学生的一条修读记录，由选课记录和课程信息组成
+type CourseRecord struct {
+	CourseID int64   `json:"course_id"`
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Credits  float64 `json:"credits"`
+	Category string  `json:"category"`
+	Term     string  `json:"term"`
+	Status   string  `json:"status"`
 }
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// LoginAttempt 某个账号或IP的连续登录失败记录
+type LoginAttempt struct {
+	Key           string `gorm:"column:attempt_key;primaryKey;size:100"` // account:<证件号盲索引> 或 ip:<地址>
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	NextAllowedAt *time.Time // 退避期内拒绝登录
+	LockedUntil   *time.Time // 锁定期内拒绝登录
+}
+
+// BlockedUntil 返回在 now 时刻拒绝登录的截止时间，未被限制时返回零值
+func (a *LoginAttempt) BlockedUntil(now time.Time) time.Time {
+	var until time.Time
+	if a.LockedUntil != nil && a.LockedUntil.After(now) {
+		until = *a.LockedUntil
+	}
+	if a.NextAllowedAt != nil && a.NextAllowedAt.After(now) && a.NextAllowedAt.After(until) {
+		until = *a.NextAllowedAt
+	}
+	return until
+}
+
+// IsLocked 是否处于锁定期
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(now)
+}
@@ -0,0 +1,51 @@
+package model
+
+import "time"
+
+// Department 院系
+type Department struct {
+	ID   int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	Code string `gorm:"size:20;not null;uniqueIndex" json:"code"`
+	Name string `gorm:"size:100;not null" json:"name"`
+}
+
+// Major 专业，隶属于一个院系
+type Major struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	DepartmentID int64  `gorm:"not null;index" json:"department_id"`
+	Code         string `gorm:"size:20;not null;uniqueIndex" json:"code"`
+	Name         string `gorm:"size:100;not null" json:"name"`
+}
+
+// Cohort 行政班，由专业、入学年份和班级组成，如 计算机科学与技术 2023级 1班
+type Cohort struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement" json:"id"`
+	MajorID   int64  `gorm:"not null;uniqueIndex:idx_cohort" json:"major_id"`
+	Year      int    `gorm:"not null;uniqueIndex:idx_cohort" json:"year"` // 入学年份
+	ClassName string `gorm:"size:50;not null;uniqueIndex:idx_cohort" json:"class_name"`
+}
+
+// 课程对某专业的要求
+const (
+	RequirementRequired = "required" // 必修
+	RequirementElective = "elective" // 选修
+)
+
+// CourseAudience 课程面向的专业。课程没有任何记录时不限专业
+type CourseAudience struct {
+	CourseID    int64  `gorm:"primaryKey" json:"-"`
+	MajorID     int64  `gorm:"primaryKey" json:"major_id"`
+	Requirement string `gorm:"size:20;not null;default:'elective'" json:"requirement"`
+}
+
+// YearLevel 按入学年份计算当前年级，每年9月升级，未知入学年份返回 0
+func YearLevel(enrollmentYear int, now time.Time) int {
+	if enrollmentYear == 0 {
+		return 0
+	}
+	academicYear := now.Year()
+	if now.Month() < time.September {
+		academicYear--
+	}
+	return academicYear - enrollmentYear + 1
+}
@@ -3,13 +3,33 @@ package model
 type Pagination struct {
 	Page     int `form:"page" binding:"min=1"`              // 当前页码，最小为1
 	PageSize int `form:"page_size" binding:"min=5,max=100"` // 每页数量，范围5-100
+
+	// Cursor 不为 nil 时使用游标（keyset）分页，忽略 Page
+	Cursor *Cursor `form:"-"`
 }
 
-// 允许排序的字段白名单
-var AllowedSortFields = map[string]bool{
-	"id":        true,
-	"hours":     true,
-	"startdate": true,
+// 允许排序的字段白名单，值为投影表中对应的字段名
+var AllowedSortFields = map[string]string{
+	"id":        "id",
+	"hours":     "hours",
+	"startdate": "start_date",
+}
+
+// SortValueType 排序字段值的类型
+type SortValueType int
+
+const (
+	SortValueString SortValueType = iota
+	SortValueInt
+	SortValueTime
+)
+
+// SortValueTypes 排序字段的值类型，键为投影表中的字段名，未列出的按字符串处理。
+// 游标中的边界值按此类型还原，不根据字符串的形式猜测。
+var SortValueTypes = map[string]SortValueType{
+	"id":         SortValueInt,
+	"hours":      SortValueInt,
+	"start_date": SortValueTime,
 }
 
 func (p Pagination) Offset() int {
@@ -20,10 +40,40 @@ func (p Pagination) Limit() int {
 	return p.PageSize
 }
 
+// IsCursor 是否为游标分页模式
+func (p Pagination) IsCursor() bool {
+	return p.Cursor != nil
+}
+
+// Cursor 游标分页的位置，记录上一页边界行的排序值和ID。
+// 编码后对客户端不透明，排序方式变化时游标失效。
+type Cursor struct {
+	SortBy   string `json:"s"`
+	Order    string `json:"o"`
+	Value    string `json:"v,omitempty"` // 边界行排序列的值，按 id 排序时为空
+	ID       int64  `json:"i,omitempty"` // 边界行ID，为 0 表示从头开始
+	Backward bool   `json:"b,omitempty"` // 是否向前翻页
+}
+
+// IsStart 是否为首页游标
+func (c *Cursor) IsStart() bool {
+	return c.ID == 0
+}
+
+// PageResult 仓库层分页查询结果
+type PageResult struct {
+	Rows  []map[string]interface{}
+	Total int64
+	Next  *Cursor // 游标模式下的下一页位置，没有更多数据时为 nil
+	Prev  *Cursor // 游标模式下的上一页位置，已在首页时为 nil
+}
+
 type PaginatedResponse[T any] struct {
-	Data       []T   `json:"data"`
-	Total      int64 `json:"total"`       // 总记录数
-	Page       int   `json:"page"`        // 当前页码
-	PageSize   int   `json:"page_size"`   // 每页数量
-	TotalPages int   `json:"total_pages"` // 总页数
+	Data       []T    `json:"data"`
+	Total      int64  `json:"total"`                 // 总记录数
+	Page       int    `json:"page,omitempty"`        // 当前页码，游标模式下为空
+	PageSize   int    `json:"page_size"`             // 每页数量
+	TotalPages int    `json:"total_pages,omitempty"` // 总页数，游标模式下为空
+	NextCursor string `json:"next_cursor,omitempty"` // 下一页游标
+	PrevCursor string `json:"prev_cursor,omitempty"` // 上一页游标
 }
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// PasswordHistory 用户用过的密码，只保存 bcrypt 哈希，用于防止重复使用
+type PasswordHistory struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	UserID    int64  `gorm:"not null;index"`
+	Hash      string `gorm:"type:varchar(60);not null"`
+	CreatedAt time.Time
+}
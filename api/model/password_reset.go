@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// PasswordResetToken 密码重置令牌，只保存令牌的 SHA-256，使用后即失效
+type PasswordResetToken struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	UserID    int64     `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// Usable 令牌未使用且未过期
+func (t *PasswordResetToken) Usable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,42 @@
+package model
+
+// Program 专业培养方案，规定毕业所需的总学分、必修课程、选修课组和各类别学分。
+// 课程以课程编号关联，同一门课程在任一学期修读均可计入。
+type Program struct {
+	ID           int64   `gorm:"primaryKey;autoIncrement" json:"id"`
+	MajorID      int64   `gorm:"not null;uniqueIndex" json:"major_id"`
+	Name         string  `gorm:"size:100;not null" json:"name"`
+	TotalCredits float64 `gorm:"type:decimal(5,1);not null;default:0" json:"total_credits"` // 毕业最低总学分，0 表示不要求
+
+	RequiredCourses []ProgramCourse       `gorm:"foreignKey:ProgramID" json:"required_courses"`
+	ElectiveGroups  []ElectiveGroup       `gorm:"foreignKey:ProgramID" json:"elective_groups"`
+	CategoryCredits []CategoryRequirement `gorm:"foreignKey:ProgramID" json:"category_credits"`
+}
+
+// ProgramCourse 培养方案中的必修课程
+type ProgramCourse struct {
+	ProgramID  int64  `gorm:"primaryKey" json:"-"`
+	CourseCode string `gorm:"primaryKey;size:20" json:"course_code"`
+}
+
+// ElectiveGroup 选修课组，组内课程的学分合计须达到 MinCredits
+type ElectiveGroup struct {
+	ID         int64                 `gorm:"primaryKey;autoIncrement" json:"id"`
+	ProgramID  int64                 `gorm:"not null;index" json:"-"`
+	Name       string                `gorm:"size:100;not null" json:"name"`
+	MinCredits float64               `gorm:"type:decimal(5,1);not null" json:"min_credits"`
+	Courses    []ElectiveGroupCourse `gorm:"foreignKey:GroupID" json:"courses"`
+}
+
+// ElectiveGroupCourse 选修课组包含的课程
+type ElectiveGroupCourse struct {
+	GroupID    int64  `gorm:"primaryKey" json:"-"`
+	CourseCode string `gorm:"primaryKey;size:20" json:"course_code"`
+}
+
+// CategoryRequirement 某一课程类别的最低学分
+type CategoryRequirement struct {
+	ProgramID  int64   `gorm:"primaryKey" json:"-"`
+	Category   string  `gorm:"primaryKey;size:20" json:"category"`
+	MinCredits float64 `gorm:"type:decimal(5,1);not null" json:"min_credits"`
+}
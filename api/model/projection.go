@@ -0,0 +1,85 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProjectedField 对外字段名与查询表达式的对应关系
+type ProjectedField struct {
+	Name string // 响应中使用的字段名
+	Expr string // SQL 表达式，可以是列名或子查询
+}
+
+// Projection 资源的字段投影表，客户端只能通过对外字段名选择返回字段
+type Projection struct {
+	fields []ProjectedField
+	exprs  map[string]string
+}
+
+func NewProjection(fields ...ProjectedField) *Projection {
+	p := &Projection{fields: fields, exprs: make(map[string]string, len(fields))}
+	for _, f := range fields {
+		p.exprs[f.Name] = f.Expr
+	}
+	return p
+}
+
+// UnknownFieldError 请求了投影表之外的字段
+type UnknownFieldError struct {
+	Field   string
+	Allowed []string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("未知字段: %s，可选字段: %s", e.Field, strings.Join(e.Allowed, ", "))
+}
+
+// Allowed 返回所有可选字段名
+func (p *Projection) Allowed() []string {
+	names := make([]string, 0, len(p.fields))
+	for _, f := range p.fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// Expr 返回字段对应的表达式
+func (p *Projection) Expr(name string) (string, bool) {
+	expr, ok := p.exprs[name]
+	return expr, ok
+}
+
+// Validate 检查字段名是否都在投影表中
+func (p *Projection) Validate(names []string) error {
+	for _, name := range names {
+		if _, ok := p.exprs[name]; !ok {
+			return &UnknownFieldError{Field: name, Allowed: p.Allowed()}
+		}
+	}
+	return nil
+}
+
+// Select 生成 SELECT 子句，names 为空时返回全部字段，extra 中缺少的字段会被追加
+func (p *Projection) Select(names []string, extra ...string) (string, error) {
+	if len(names) == 0 {
+		names = p.Allowed()
+	}
+	if err := p.Validate(names); err != nil {
+		return "", err
+	}
+	if err := p.Validate(extra); err != nil {
+		return "", err
+	}
+
+	seen := make(map[string]bool, len(names)+len(extra))
+	columns := make([]string, 0, len(names)+len(extra))
+	for _, name := range append(append([]string(nil), names...), extra...) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		columns = append(columns, p.exprs[name]+" AS "+name)
+	}
+	return strings.Join(columns, ", "), nil
+}
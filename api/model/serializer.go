@@ -0,0 +1,26 @@
+package model
+
+import (
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	// 标记为 serializer:encrypted 的字段在数据库中以密文保存
+	schema.RegisterSerializer("encrypted", fieldcrypt.Serializer{})
+}
+
+// EncryptedColumns 各表中加密保存的列，轮换密钥时按此重新加密
+var EncryptedColumns = map[string][]string{
+	"users": {"id_card", "name", "totp_secret", "email", "phone"},
+}
+
+// EncryptedRowKeys 各表中作为密文附加认证数据的行标识列，与模型的 EncryptionRowKey 一致
+var EncryptedRowKeys = map[string]string{
+	"users": "public_id",
+}
+
+// BlindIndexColumns 各表中加密列对应的盲索引列
+var BlindIndexColumns = map[string]map[string]string{
+	"users": {"id_card": "id_card_hash"},
+}
@@ -1,11 +1,84 @@
 package model
 
+import (
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm"
+)
+
+// 证件类型
+const (
+	DocumentTypeIDCard        = "id_card"         // 居民身份证
+	DocumentTypePassport      = "passport"        // 护照
+	DocumentTypeHKMacauPermit = "hk_macau_permit" // 港澳居民来往内地通行证
+	DocumentTypeTaiwanPermit  = "taiwan_permit"   // 台湾居民来往大陆通行证
+)
+
 type User struct {
-	ID       int64  `gorm:"primaryKey;autoIncrement"`
-	IDCard   string `gorm:"unique;type:varchar(20)"`
-	Password string `gorm:"type:varchar(60)"`
-	Name     string `gorm:"type:varchar(60);not null"`
-	Role     string `gorm:"type:enum('student','teacher');not null"`
+	ID           int64  `gorm:"primaryKey;autoIncrement" json:"-"`
+	PublicID     string `gorm:"type:varchar(36);uniqueIndex" json:"id"`          // 对外标识，用于令牌、URL和关联
+	IDCard       string `gorm:"type:varchar(255);serializer:encrypted" json:"-"` // 证件号码，居民身份证统一存18位，加密保存
+	IDCardHash   string `gorm:"type:char(64);uniqueIndex" json:"-"`              // 证件号码的盲索引，用于精确查找
+	DocumentType string `gorm:"type:varchar(20);not null;default:'id_card'" json:"document_type"`
+	Password     string `gorm:"type:varchar(60)" json:"-"` // bcrypt 哈希，见 pkg.HashPassword
+	Name         string `gorm:"type:varchar(512);not null;serializer:encrypted" json:"name"`
+	Role         string `gorm:"type:varchar(20);not null" json:"role"`           // student、teacher 或 admin
+	Email        string `gorm:"type:varchar(512);serializer:encrypted" json:"-"` // 用于接收密码重置等通知
+	TokenVersion int    `gorm:"not null;default:0" json:"-"`                     // 修改或重置密码时递增，使已签发的令牌失效
+
+	// 个人资料
+	Phone          string `gorm:"type:varchar(255);serializer:encrypted" json:"-"`
+	EnrollmentYear int    `gorm:"not null;default:0" json:"enrollment_year,omitempty"` // 入学年份，学生分配行政班时同步
+	Language       string `gorm:"type:varchar(10);not null;default:'zh-CN'" json:"language"`
+	AvatarURL      string `gorm:"type:varchar(512)" json:"avatar_url"`
+	Bio            string `gorm:"type:text" json:"bio,omitempty"`                  // 教师简介
+	OfficeHours    string `gorm:"type:varchar(255)" json:"office_hours,omitempty"` // 教师答疑时间
+
+	// 所属院系、专业和行政班，由管理员维护。教师只有院系
+	DepartmentID *int64 `gorm:"index" json:"department_id"`
+	MajorID      *int64 `gorm:"index" json:"major_id,omitempty"`
+	CohortID     *int64 `gorm:"index" json:"cohort_id,omitempty"`
+
+	// 两步验证（TOTP）。MFAEnabled 为 false 时 TOTPSecret 是尚未确认的待启用密钥
+	TOTPSecret      string `gorm:"type:varchar(255);serializer:encrypted" json:"-"`
+	TOTPLastCounter int64  `gorm:"not null;default:0" json:"-"` // 最近一次使用的时间窗口，防止验证码重放
+	MFAEnabled      bool   `gorm:"not null;default:false" json:"mfa_enabled"`
+	RecoveryCodes   string `gorm:"type:text" json:"-"` // 未使用的恢复码的 SHA-256，逗号分隔
+
+	Courses []Course `gorm:"many2many:enrollments;foreignKey:ID;joinForeignKey:StudentID;References:ID;joinReferences:CourseID" json:"-"`
+}
+
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.PublicID == "" {
+		u.PublicID = pkg.NewPublicID()
+	}
+	return nil
+}
+
+// BeforeSave 证件号码变化时同步盲索引
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.IDCard == "" {
+		return nil
+	}
+	hash, err := fieldcrypt.BlindIndex(u.IDCard)
+	if err != nil {
+		return err
+	}
+	u.IDCardHash = hash
+	return nil
+}
+
+// AfterFind 解密加密保存的个人信息，查询用户时须同时选择 public_id
+func (u *User) AfterFind(tx *gorm.DB) error {
+	return fieldcrypt.OpenFields(tx, u)
+}
+
+// EncryptionRowKey 加密字段绑定到用户的对外标识，密文不能在用户之间互换
+func (u *User) EncryptionRowKey() string {
+	return u.PublicID
+}
 
-	Courses []Course `gorm:"many2many:enrollments;foreignKey:ID;joinForeignKey:StudentID;References:ID;joinReferences:CourseID"`
+// MaskedIDCard 脱敏后的证件号，只用于展示
+func (u *User) MaskedIDCard() string {
+	return pkg.MaskIDCard(u.IDCard)
 }
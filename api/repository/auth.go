@@ -1,15 +1,30 @@
 package repository
 
 import (
+	"errors"
+
 	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
 	"gorm.io/gorm"
 )
 
 type AuthRepository interface {
 	FindByIDCard(idCard string) (*model.User, error)
+	FindByPublicID(publicID string) (*model.User, error)
+	FindByID(id int64) (*model.User, error)
 	CreateUser(user *model.User) error
+	// UpdateUser 只更新 columns 中的列，不会覆盖并发请求修改的其他字段
+	UpdateUser(user *model.User, columns ...string) error
+	// ConsumeTOTPCounter 仅当已使用的时间窗口早于 counter 时记录 counter，返回是否记录成功。
+	// 并发提交同一个验证码时只有一个请求成功
+	ConsumeTOTPCounter(userID, counter int64) (bool, error)
+	// ReplaceRecoveryCodes 仅当恢复码仍为 old 时替换为 new，返回是否替换成功
+	ReplaceRecoveryCodes(userID int64, old, new string) (bool, error)
 }
 
+// ErrNoColumns 更新用户时未指定列
+var ErrNoColumns = errors.New("未指定要更新的列")
+
 type GormAuthRepository struct {
 	db *gorm.DB
 }
@@ -19,14 +34,57 @@ func NewGormAuthRepository(db *gorm.DB) *GormAuthRepository {
 }
 
 func (r *GormAuthRepository) FindByIDCard(idCard string) (*model.User, error) {
+	hash, err := fieldcrypt.BlindIndex(idCard)
+	if err != nil {
+		return nil, err
+	}
+
+	var user model.User
+	err = r.db.Where("id_card_hash = ?", hash).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *GormAuthRepository) FindByPublicID(publicID string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("id_card = ?", idCard).First(&user).Error
+	err := r.db.Where("public_id = ?", publicID).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
+func (r *GormAuthRepository) FindByID(id int64) (*model.User, error) {
+	var user model.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *GormAuthRepository) CreateUser(user *model.User) error {
 	return r.db.Create(user).Error
 }
+
+func (r *GormAuthRepository) UpdateUser(user *model.User, columns ...string) error {
+	if len(columns) == 0 {
+		return ErrNoColumns
+	}
+	return r.db.Model(user).Select(columns).Updates(user).Error
+}
+
+func (r *GormAuthRepository) ConsumeTOTPCounter(userID, counter int64) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_counter < ?", userID, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *GormAuthRepository) ReplaceRecoveryCodes(userID int64, old, new string) (bool, error) {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND recovery_codes = ?", userID, old).
+		Update("recovery_codes", new)
+	return result.RowsAffected == 1, result.Error
+}
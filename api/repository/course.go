@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"slices"
+
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"gorm.io/gorm"
 )
@@ -8,11 +10,15 @@ import (
 type CourseRepository interface {
 	Create(course *model.Course) error
 	GetByID(id int64) (*model.Course, error)
-	GetByTeacherID(teacherID string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error)
-	GetByTeacherName(teacherName string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error)
-	GetByCourseName(courseName string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error)
-	GetAll(pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error)
+	// GetRows 按 CourseProjection 选择 fields 中的字段，返回课程ID到结果行的映射，不存在的课程被忽略
+	GetRows(ids []int64, viewerID int64, fields []string) (map[int64]map[string]interface{}, error)
+	GetDetail(id int64, viewerID int64) (map[string]interface{}, error)
+	ListAll() ([]model.Course, error)
+	Search(filter model.CourseFilter, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error)
 	Update(course *model.Course, updateData map[string]interface{}) error
+	ReplaceTags(courseID int64, tags []string) error
+	GetAudiences(courseID int64) ([]model.CourseAudience, error)
+	ReplaceAudiences(courseID int64, audiences []model.CourseAudience) error
 	Delete(id int64) error
 	GetEnrollmentCount(courseID int64) (int64, error)
 }
@@ -31,114 +37,229 @@ func (r *GormCourseRepository) Create(course *model.Course) error {
 
 func (r *GormCourseRepository) GetByID(id int64) (*model.Course, error) {
 	var course model.Course
-	err := r.db.First(&course, id).Error
+	err := r.db.Preload("Tags").Preload("Audiences").First(&course, id).Error
 	return &course, err
 }
 
-func (r *GormCourseRepository) GetByTeacherID(teacherID string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error) {
-	var courses []map[string]interface{}
-	var total int64
-
-	query := r.db.Model(model.Course{})
-
-	if len(fields) > 0 {
-		query = query.Select(fields)
+func (r *GormCourseRepository) GetRows(ids []int64, viewerID int64, fields []string) (map[int64]map[string]interface{}, error) {
+	fields, dropTeacherID := courseFields(fields)
+	selects, err := model.CourseProjection.Select(fields, "id")
+	if err != nil {
+		return nil, err
 	}
 
-	if err := query.Where("teacher_id = ?", teacherID).Count(&total).Error; err != nil {
-		return nil, 0, err
+	var rows []map[string]interface{}
+	err = courseListQuery(r.db, viewerID).
+		Select(selects).
+		Where("courses.id IN ?", ids).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
 	}
-
-	err := query.Offset(pagination.Offset()).
-		Limit(pagination.Limit()).
-		Order(sortBy + " " + sortOrder).
-		Find(&courses).Error
-
-	return courses, total, err
+	if err := normalizeCourseRows(rows, dropTeacherID); err != nil {
+		return nil, err
+	}
+	return rowsByID(rows, fields), nil
 }
 
-func (r *GormCourseRepository) GetByTeacherName(teacherName string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error) {
-	var courses []map[string]interface{}
-	var total int64
+func (r *GormCourseRepository) ListAll() ([]model.Course, error) {
+	var courses []model.Course
+	err := r.db.Find(&courses).Error
+	return courses, err
+}
 
-	// 先查询符合条件的教师
-	var teachers []model.User
-	if err := r.db.Where("name LIKE ? AND role = 'teacher'", "%"+teacherName+"%").Find(&teachers).Error; err != nil {
-		return nil, 0, err
+func (r *GormCourseRepository) Search(filter model.CourseFilter, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	query, err := r.applyCourseFilter(courseListQuery(r.db, filter.ViewerID), filter)
+	if err != nil {
+		return nil, err
 	}
-
-	var teacherIDs []string
-	for _, t := range teachers {
-		teacherIDs = append(teacherIDs, t.IDCard)
+	fields, dropTeacherID := courseFields(fields)
+	result, err := findPage(query, model.CourseProjection, pagination, sortBy, sortOrder, fields)
+	if err != nil {
+		return nil, err
 	}
-
-	if len(teacherIDs) == 0 {
-		return nil, 0, nil
+	if err := normalizeCourseRows(result.Rows, dropTeacherID); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	query := r.db.Model(model.Course{})
-	// 如果有指定字段，则只查询这些字段
-	if len(fields) > 0 {
-		query = query.Select(fields)
+func (r *GormCourseRepository) GetDetail(id int64, viewerID int64) (map[string]interface{}, error) {
+	selects, err := model.CourseProjection.Select(nil)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := query.Where("teacher_id IN ?", teacherIDs).Count(&total).Error; err != nil {
-		return nil, 0, err
+	var rows []map[string]interface{}
+	err = courseListQuery(r.db, viewerID).
+		Select(selects).
+		Where("courses.id = ?", id).
+		Limit(1).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
 	}
-
-	err := query.Offset(pagination.Offset()).
-		Limit(pagination.Limit()).
-		Order(sortBy + " " + sortOrder).
-		Find(&courses).Error
-
-	return courses, total, err
-}
-
-func (r *GormCourseRepository) GetByCourseName(courseName string, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error) {
-	var courses []map[string]interface{}
-	var total int64
-
-	query := r.db.Model(&model.Course{})
-	if len(fields) > 0 {
-		query = query.Select(fields)
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
 	}
-	if err := query.Where("name LIKE ?", "%"+courseName+"%").Count(&total).Error; err != nil {
-		return nil, 0, err
+	if err := normalizeCourseRows(rows, false); err != nil {
+		return nil, err
 	}
 
-	err := query.Offset(pagination.Offset()).
-		Limit(pagination.Limit()).
-		Order(sortBy + " " + sortOrder).
-		Find(&courses).Error
+	var tags []string
+	if err := r.db.Model(&model.CourseTag{}).Where("course_id = ?", id).Order("tag").Pluck("tag", &tags).Error; err != nil {
+		return nil, err
+	}
+	rows[0]["tags"] = tags
 
-	return courses, total, err
+	return rows[0], nil
 }
 
-func (r *GormCourseRepository) GetAll(pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error) {
-	var courses []map[string]interface{}
-	var total int64
+// courseListQuery 课程列表的基础查询，一次性关联教师、选课人数和当前用户的选课记录，
+// 避免逐条查询。三个关联都是一对一，不影响计数。
+func courseListQuery(db *gorm.DB, viewerID int64) *gorm.DB {
+	return db.Model(&model.Course{}).
+		Joins("LEFT JOIN users teachers ON teachers.public_id = courses.teacher_id").
+		Joins("LEFT JOIN (SELECT course_id, COUNT(*) AS enrolled_count FROM enrollments GROUP BY course_id) course_counts ON course_counts.course_id = courses.id").
+		Joins("LEFT JOIN enrollments viewer_enrollments ON viewer_enrollments.course_id = courses.id AND viewer_enrollments.student_id = ?", viewerID)
+}
 
-	query := r.db.Model(&model.Course{})
-	if len(fields) > 0 {
-		query = query.Select(fields)
+// courseFields 教师姓名以教师的 public_id 作为解密的附加认证数据，
+// 只选择了 teacher_name 时补上 teacher_id，返回 true 表示解密后需要移除
+func courseFields(fields []string) ([]string, bool) {
+	if len(fields) == 0 || !slices.Contains(fields, "teacher_name") || slices.Contains(fields, "teacher_id") {
+		return fields, false
 	}
+	return append(slices.Clone(fields), "teacher_id"), true
+}
 
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// rowsByID 按ID索引结果行，fields 中没有 id 时从行中移除
+func rowsByID(rows []map[string]interface{}, fields []string) map[int64]map[string]interface{} {
+	dropID := len(fields) > 0 && !slices.Contains(fields, "id")
+	byID := make(map[int64]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		byID[toInt64(row["id"])] = row
+		if dropID {
+			delete(row, "id")
+		}
 	}
+	return byID
+}
 
-	err := query.Offset(pagination.Offset()).
-		Limit(pagination.Limit()).
-		Order(sortBy + " " + sortOrder).
-		Find(&courses).Error
+// normalizeCourseRows 将 is_enrolled 统一为布尔值（各数据库返回的类型不同），
+// 并解密关联查询得到的教师姓名
+func normalizeCourseRows(rows []map[string]interface{}, dropTeacherID bool) error {
+	for _, row := range rows {
+		if v, ok := row["is_enrolled"]; ok {
+			row["is_enrolled"] = toInt64(v) != 0
+		}
+		if v, ok := row["teacher_name"]; ok && v != nil {
+			name, err := decryptColumn("users", "name", columnString(row["teacher_id"]), v)
+			if err != nil {
+				return err
+			}
+			row["teacher_name"] = name
+		}
+		if dropTeacherID {
+			delete(row, "teacher_id")
+		}
+	}
+	return nil
+}
 
-	return courses, total, err
+// applyCourseFilter 将搜索条件组合成查询，各条件之间为 AND 关系。
+// query 需由 courseListQuery 构建。
+func (r *GormCourseRepository) applyCourseFilter(query *gorm.DB, filter model.CourseFilter) (*gorm.DB, error) {
+	if filter.Name != "" {
+		query = whereContains(query, "courses.name", filter.Name)
+	}
+	if filter.TeacherID != "" {
+		query = query.Where("courses.teacher_id = ?", filter.TeacherID)
+	}
+	if len(filter.TeacherIDs) > 0 {
+		query = query.Where("courses.teacher_id IN ?", filter.TeacherIDs)
+	}
+	if filter.Code != "" {
+		query = query.Where("courses.code = ?", filter.Code)
+	}
+	if filter.Category != "" {
+		query = query.Where("courses.category = ?", filter.Category)
+	}
+	if filter.Term != "" {
+		query = query.Where("courses.term = ?", filter.Term)
+	}
+	if filter.StartFrom != nil {
+		query = query.Where("courses.start_date >= ?", *filter.StartFrom)
+	}
+	if filter.StartTo != nil {
+		query = query.Where("courses.start_date <= ?", *filter.StartTo)
+	}
+	if filter.MinHours != nil {
+		query = query.Where("courses.hours >= ?", *filter.MinHours)
+	}
+	if filter.MaxHours != nil {
+		query = query.Where("courses.hours <= ?", *filter.MaxHours)
+	}
+	if filter.MinCredits != nil {
+		query = query.Where("courses.credits >= ?", *filter.MinCredits)
+	}
+	if filter.MaxCredits != nil {
+		query = query.Where("courses.credits <= ?", *filter.MaxCredits)
+	}
+	if filter.Status != "" {
+		query = query.Where("courses.status = ?", filter.Status)
+	}
+	if filter.AvailableOnly {
+		query = query.Where("courses.student_max_num > COALESCE(course_counts.enrolled_count, 0)")
+	}
+	for _, tag := range filter.Tags {
+		query = query.Where("EXISTS (SELECT 1 FROM course_tags WHERE course_tags.course_id = courses.id AND course_tags.tag = ?)", tag)
+	}
+	return query, nil
 }
 
 func (r *GormCourseRepository) Update(course *model.Course, updateData map[string]interface{}) error {
 	return r.db.Model(course).Updates(updateData).Error
 }
 
+func (r *GormCourseRepository) ReplaceTags(courseID int64, tags []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("course_id = ?", courseID).Delete(&model.CourseTag{}).Error; err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+		courseTags := make([]model.CourseTag, 0, len(tags))
+		for _, tag := range tags {
+			courseTags = append(courseTags, model.CourseTag{CourseID: courseID, Tag: tag})
+		}
+		return tx.Create(&courseTags).Error
+	})
+}
+
+func (r *GormCourseRepository) GetAudiences(courseID int64) ([]model.CourseAudience, error) {
+	var audiences []model.CourseAudience
+	err := r.db.Where("course_id = ?", courseID).Order("major_id").Find(&audiences).Error
+	return audiences, err
+}
+
+func (r *GormCourseRepository) ReplaceAudiences(courseID int64, audiences []model.CourseAudience) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("course_id = ?", courseID).Delete(&model.CourseAudience{}).Error; err != nil {
+			return err
+		}
+		if len(audiences) == 0 {
+			return nil
+		}
+		rows := make([]model.CourseAudience, 0, len(audiences))
+		for _, a := range audiences {
+			rows = append(rows, model.CourseAudience{CourseID: courseID, MajorID: a.MajorID, Requirement: a.Requirement})
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
 func (r *GormCourseRepository) Delete(id int64) error {
 	return r.db.Delete(&model.Course{}, id).Error
 }
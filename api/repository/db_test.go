@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"gorm.io/gorm"
+)
+
+// newTestDB 打开只在本测试中使用的内存 SQLite 数据库并建好 models 对应的表
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := config.InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: "file::memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// likeEscaper 转义 LIKE 模式中的通配符，使用户输入按字面匹配。
+// 各数据库默认的转义字符不同，统一用 ESCAPE '!' 指定。
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// whereContains 不区分大小写地匹配包含 value 的行。
+// MySQL 的默认排序规则和 SQLite 的 LIKE 不区分大小写（SQLite 只限 ASCII），PostgreSQL 需要使用 ILIKE。
+func whereContains(query *gorm.DB, column, value string) *gorm.DB {
+	op := "LIKE"
+	if query.Dialector.Name() == "postgres" {
+		op = "ILIKE"
+	}
+	return query.Where(column+" "+op+" ? ESCAPE '!'", "%"+likeEscaper.Replace(value)+"%")
+}
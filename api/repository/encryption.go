@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm"
+)
+
+// RotateEncryptedColumns 按批次用当前主密钥重新加密 model.EncryptedColumns 中的列，并校正盲索引。
+// 解密明文和 v1 格式的旧密文需要 kr.AllowLegacy 为 true。返回被更新的行数，可重复执行。
+func RotateEncryptedColumns(db *gorm.DB, kr *fieldcrypt.Keyring, batchSize int) (int, error) {
+	updated := 0
+	for table, columns := range model.EncryptedColumns {
+		n, err := rotateTable(db, kr, table, columns, model.EncryptedRowKeys[table], model.BlindIndexColumns[table], batchSize)
+		updated += n
+		if err != nil {
+			return updated, fmt.Errorf("%s: %w", table, err)
+		}
+	}
+	return updated, nil
+}
+
+func rotateTable(db *gorm.DB, kr *fieldcrypt.Keyring, table string, columns []string, rowKey string, indexes map[string]string, batchSize int) (int, error) {
+	selects := append([]string{"id", rowKey}, columns...)
+	for _, indexColumn := range indexes {
+		selects = append(selects, indexColumn)
+	}
+
+	updated := 0
+	var lastID int64
+	for {
+		var rows []map[string]interface{}
+		err := db.Table(table).Select(selects).Where("id > ?", lastID).Order("id").Limit(batchSize).Find(&rows).Error
+		if err != nil {
+			return updated, err
+		}
+		if len(rows) == 0 {
+			return updated, nil
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, row := range rows {
+				changes, err := rotateRow(kr, table, columnString(row[rowKey]), row, columns, indexes)
+				if err != nil {
+					return fmt.Errorf("id=%v: %w", row["id"], err)
+				}
+				if len(changes) == 0 {
+					continue
+				}
+				if err := tx.Table(table).Where("id = ?", row["id"]).Updates(changes).Error; err != nil {
+					return err
+				}
+				updated++
+			}
+			return nil
+		})
+		if err != nil {
+			return updated, err
+		}
+		lastID = toInt64(rows[len(rows)-1]["id"])
+	}
+}
+
+func rotateRow(kr *fieldcrypt.Keyring, table, rowKey string, row map[string]interface{}, columns []string, indexes map[string]string) (map[string]interface{}, error) {
+	changes := make(map[string]interface{})
+	for _, column := range columns {
+		value := columnString(row[column])
+		if value == "" {
+			continue
+		}
+
+		ctx := fieldcrypt.Context{Table: table, Column: column, Row: rowKey}
+		plaintext, err := kr.Decrypt(value, ctx)
+		if err != nil {
+			return nil, err
+		}
+		if kr.NeedsRotation(value) {
+			if changes[column], err = kr.Encrypt(plaintext, ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		if indexColumn, ok := indexes[column]; ok {
+			if hash := kr.BlindIndex(plaintext); columnString(row[indexColumn]) != hash {
+				changes[indexColumn] = hash
+			}
+		}
+	}
+	return changes, nil
+}
+
+// decryptColumn 解密未经过 GORM 序列化器读取的加密列，rowKey 为所在行的 EncryptedRowKeys 列的值
+func decryptColumn(table, column, rowKey string, v interface{}) (string, error) {
+	kr, err := fieldcrypt.Default()
+	if err != nil {
+		return "", err
+	}
+	return kr.Decrypt(columnString(v), fieldcrypt.Context{Table: table, Column: column, Row: rowKey})
+}
+
+func columnString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	}
+	return ""
+}
@@ -1,79 +1,125 @@
 package repository
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type EnrollmentRepository struct {
+// ErrCourseFull 课程选课人数已达到上限
+var ErrCourseFull = errors.New("课程人数已满")
+
+type EnrollmentRepository interface {
+	GetStudentByPublicID(publicID string) (*model.User, error)
+	GetCourseByID(courseID int) (*model.Course, error)
+	GetEnrollment(studentID, courseID int64) (*model.Enrollment, error)
+	CreateEnrollment(enrollment *model.Enrollment) error
+	// CreateEnrollmentWithinCapacity 课程人数未满时写入选课记录，已满时返回 ErrCourseFull。
+	// 统计人数和写入是原子的，并发选课不会超过人数上限
+	CreateEnrollmentWithinCapacity(enrollment *model.Enrollment) error
+	DeleteEnrollment(enrollment *model.Enrollment) error
+	CountEnrollmentsByCourse(courseID int64) (int64, error)
+	GetStudentEnrollments(studentID int64) ([]model.Enrollment, error)
+	GetStudentCourses(studentID int64, enrollmentIDs []int64, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error)
+	UpdateEnrollment(enrollment *model.Enrollment) error
+	GetStudentRecords(studentID int64) ([]model.CourseRecord, error)
+}
+
+type GormEnrollmentRepository struct {
 	db *gorm.DB
 }
 
-func NewEnrollmentRepository(db *gorm.DB) *EnrollmentRepository {
-	return &EnrollmentRepository{db: db}
+func NewGormEnrollmentRepository(db *gorm.DB) *GormEnrollmentRepository {
+	return &GormEnrollmentRepository{db: db}
 }
 
-func (r *EnrollmentRepository) GetStudentByIDCard(idCard string) (*model.User, error) {
+func (r *GormEnrollmentRepository) GetStudentByPublicID(publicID string) (*model.User, error) {
 	var student model.User
-	err := r.db.Where("id_card = ? AND role = 'student'", idCard).First(&student).Error
+	err := r.db.Where("public_id = ? AND role = 'student'", publicID).First(&student).Error
 	return &student, err
 }
 
-func (r *EnrollmentRepository) GetCourseByID(courseID int) (*model.Course, error) {
+func (r *GormEnrollmentRepository) GetCourseByID(courseID int) (*model.Course, error) {
 	var course model.Course
-	err := r.db.First(&course, courseID).Error
+	err := r.db.Preload("Audiences").First(&course, courseID).Error
 	return &course, err
 }
 
-func (r *EnrollmentRepository) GetEnrollment(studentID, courseID int64) (*model.Enrollment, error) {
+func (r *GormEnrollmentRepository) GetEnrollment(studentID, courseID int64) (*model.Enrollment, error) {
 	var enrollment model.Enrollment
 	err := r.db.Where("student_id = ? AND course_id = ?", studentID, courseID).First(&enrollment).Error
 	return &enrollment, err
 }
 
-func (r *EnrollmentRepository) CreateEnrollment(enrollment *model.Enrollment) error {
+func (r *GormEnrollmentRepository) CreateEnrollment(enrollment *model.Enrollment) error {
 	return r.db.Create(enrollment).Error
 }
 
-func (r *EnrollmentRepository) DeleteEnrollment(enrollment *model.Enrollment) error {
+// CreateEnrollmentWithinCapacity 在事务中锁定课程行后统计人数，同一课程的并发选课依次执行
+func (r *GormEnrollmentRepository) CreateEnrollmentWithinCapacity(enrollment *model.Enrollment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var course model.Course
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "student_max_num").
+			First(&course, enrollment.CourseID).Error
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&model.Enrollment{}).Where("course_id = ?", enrollment.CourseID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= int64(course.StudentMaxNum) {
+			return ErrCourseFull
+		}
+		return tx.Create(enrollment).Error
+	})
+}
+
+func (r *GormEnrollmentRepository) DeleteEnrollment(enrollment *model.Enrollment) error {
 	return r.db.Delete(enrollment).Error
 }
 
-func (r *EnrollmentRepository) CountEnrollmentsByCourse(courseID int64) (int64, error) {
+func (r *GormEnrollmentRepository) CountEnrollmentsByCourse(courseID int64) (int64, error) {
 	var count int64
 	err := r.db.Model(&model.Enrollment{}).Where("course_id = ?", courseID).Count(&count).Error
 	return count, err
 }
 
-func (r *EnrollmentRepository) GetStudentEnrollments(studentID int64) ([]model.Enrollment, error) {
+func (r *GormEnrollmentRepository) GetStudentEnrollments(studentID int64) ([]model.Enrollment, error) {
 	var enrollments []model.Enrollment
 	err := r.db.Where("student_id = ?", studentID).Find(&enrollments).Error
 	return enrollments, err
 }
 
-func (r *EnrollmentRepository) GetStudentCourses(enrollmentIDs []int64, pagination model.Pagination, sortBy, sortOrder string, fields []string) ([]map[string]interface{}, int64, error) {
-	var courses []map[string]interface{}
-	var total int64
-
-	query := r.db.Model(&model.Course{})
-	// 选择字段
-	if len(fields) > 0 {
-		query = query.Select(fields)
+func (r *GormEnrollmentRepository) GetStudentCourses(studentID int64, enrollmentIDs []int64, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	query := courseListQuery(r.db, studentID).Where("courses.id IN ?", enrollmentIDs)
+	fields, dropTeacherID := courseFields(fields)
+	result, err := findPage(query, model.CourseProjection, pagination, sortBy, sortOrder, fields)
+	if err != nil {
+		return nil, err
 	}
-	query = query.Where("id IN ?", enrollmentIDs)
-
-	// 获取总数
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+	if err := normalizeCourseRows(result.Rows, dropTeacherID); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	// 分页查询
-	err := query.Offset(pagination.Offset()).
-		Limit(pagination.Limit()).
-		Order(fmt.Sprintf("%s %s", sortBy, sortOrder)).
-		Find(&courses).Error
+func (r *GormEnrollmentRepository) UpdateEnrollment(enrollment *model.Enrollment) error {
+	return r.db.Save(enrollment).Error
+}
 
-	return courses, total, err
+// GetStudentRecords 学生全部的修读记录，按学期排序
+func (r *GormEnrollmentRepository) GetStudentRecords(studentID int64) ([]model.CourseRecord, error) {
+	var records []model.CourseRecord
+	err := r.db.Model(&model.Enrollment{}).
+		Select("courses.id AS course_id, courses.code, courses.name, courses.credits, courses.category, courses.term, enrollments.status").
+		Joins("JOIN courses ON courses.id = enrollments.course_id AND courses.deleted_at IS NULL").
+		Where("enrollments.student_id = ?", studentID).
+		Order("courses.term, courses.id").
+		Scan(&records).Error
+	return records, err
 }
@@ -0,0 +1,129 @@
+package repository
+
+import "gorm.io/gorm"
+
+// IntegrityIssue 一项数据一致性检查的结果
+type IntegrityIssue struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Count       int64  `json:"count"`
+	Repairable  bool   `json:"repairable"`         // 能否自动修复，不能的需要人工处理
+	Blocking    bool   `json:"blocking"`           // 存在时无法添加外键或 CHECK 约束
+	Repaired    int64  `json:"repaired,omitempty"` // 修复时删除的行数
+}
+
+// integrityCheck 在 table 中用 where 选出不一致的行。repair 为 true 时修复方式是删除这些行。
+type integrityCheck struct {
+	name        string
+	description string
+	table       string
+	where       string
+	repair      bool
+	blocking    bool
+}
+
+// integrityChecks 随当前的表结构修改。其中 blocking 的检查项与迁移 0004 中的
+// enrollmentConstraintViolations 相同，但迁移不能引用本包：已发布的迁移不再修改，
+// 它检查的是执行该迁移时的表结构，而这里的检查项会随以后的迁移变化。
+var integrityChecks = []integrityCheck{
+	{
+		name:        "enrollment_missing_course",
+		description: "选课记录引用了不存在的课程",
+		table:       "enrollments",
+		where:       "NOT EXISTS (SELECT 1 FROM courses WHERE courses.id = enrollments.course_id)",
+		repair:      true,
+		blocking:    true,
+	},
+	{
+		name:        "enrollment_missing_student",
+		description: "选课记录引用了不存在的学生",
+		table:       "enrollments",
+		where:       "NOT EXISTS (SELECT 1 FROM users WHERE users.id = enrollments.student_id)",
+		repair:      true,
+		blocking:    true,
+	},
+	{
+		name:        "enrollment_deleted_course",
+		description: "选课记录引用了已删除的课程，其中可能有成绩，不自动删除",
+		table:       "enrollments",
+		where:       "EXISTS (SELECT 1 FROM courses WHERE courses.id = enrollments.course_id AND courses.deleted_at IS NOT NULL)",
+	},
+	{
+		name:        "enrollment_not_student",
+		description: "选课记录的用户不是学生",
+		table:       "enrollments",
+		where:       "EXISTS (SELECT 1 FROM users WHERE users.id = enrollments.student_id AND users.role <> 'student')",
+	},
+	{
+		name:        "course_missing_teacher",
+		description: "课程的授课教师不存在，需要重新指定教师",
+		table:       "courses",
+		where:       "NOT EXISTS (SELECT 1 FROM users WHERE users.public_id = courses.teacher_id)",
+		blocking:    true,
+	},
+	{
+		name:        "course_teacher_not_teacher",
+		description: "课程的授课教师不是教师账号",
+		table:       "courses",
+		where:       "EXISTS (SELECT 1 FROM users WHERE users.public_id = courses.teacher_id AND users.role <> 'teacher')",
+	},
+	{
+		name:        "course_invalid_size",
+		description: "课程的人数上限或学时不是正数",
+		table:       "courses",
+		where:       "student_max_num <= 0 OR hours <= 0",
+		blocking:    true,
+	},
+}
+
+// CheckIntegrity 检查外键和 CHECK 约束无法覆盖或添加约束前遗留的不一致数据，只返回有问题的项
+func CheckIntegrity(db *gorm.DB) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+	for _, c := range integrityChecks {
+		var count int64
+		if err := db.Table(c.table).Where(c.where).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			issues = append(issues, c.issue(count))
+		}
+	}
+	return issues, nil
+}
+
+// RepairIntegrity 删除能自动修复的不一致数据，返回修复前发现的全部问题及各项删除的行数
+func RepairIntegrity(db *gorm.DB) ([]IntegrityIssue, error) {
+	var issues []IntegrityIssue
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, c := range integrityChecks {
+			var count int64
+			if err := tx.Table(c.table).Where(c.where).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				continue
+			}
+			issue := c.issue(count)
+			if c.repair {
+				result := tx.Exec("DELETE FROM " + c.table + " WHERE " + c.where)
+				if result.Error != nil {
+					return result.Error
+				}
+				issue.Repaired = result.RowsAffected
+			}
+			issues = append(issues, issue)
+		}
+		return nil
+	})
+	return issues, err
+}
+
+func (c integrityCheck) issue(count int64) IntegrityIssue {
+	return IntegrityIssue{
+		Name:        c.name,
+		Description: c.description,
+		Count:       count,
+		Repairable:  c.repair,
+		Blocking:    c.blocking,
+	}
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttemptRepository 登录失败记录的存储
+type LoginAttemptRepository interface {
+	Get(key string) (*model.LoginAttempt, error)
+	// Update 原子地读取、修改并保存记录，记录不存在时传入只有 Key 的零值记录
+	Update(key string, fn func(attempt *model.LoginAttempt)) (*model.LoginAttempt, error)
+	Delete(key string) error
+}
+
+// MemoryLoginAttemptRepository 单实例部署使用的内存实现
+type MemoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]model.LoginAttempt
+	ttl      time.Duration
+}
+
+// NewMemoryLoginAttemptRepository ttl 为记录在最后一次失败后保留的时间
+func NewMemoryLoginAttemptRepository(ttl time.Duration) *MemoryLoginAttemptRepository {
+	return &MemoryLoginAttemptRepository{attempts: make(map[string]model.LoginAttempt), ttl: ttl}
+}
+
+func (r *MemoryLoginAttemptRepository) Get(key string) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return &model.LoginAttempt{Key: key}, nil
+	}
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) Update(key string, fn func(attempt *model.LoginAttempt)) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweep(time.Now())
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = model.LoginAttempt{Key: key}
+	}
+	fn(&attempt)
+	r.attempts[key] = attempt
+	return &attempt, nil
+}
+
+func (r *MemoryLoginAttemptRepository) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+// sweep 清理过期记录，防止被大量随机账号或IP撑满内存
+func (r *MemoryLoginAttemptRepository) sweep(now time.Time) {
+	for key, attempt := range r.attempts {
+		if attempt.BlockedUntil(now).IsZero() && (attempt.LastFailureAt == nil || now.Sub(*attempt.LastFailureAt) > r.ttl) {
+			delete(r.attempts, key)
+		}
+	}
+}
+
+// GormLoginAttemptRepository 多实例部署共享的数据库实现
+type GormLoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewGormLoginAttemptRepository(db *gorm.DB) *GormLoginAttemptRepository {
+	return &GormLoginAttemptRepository{db: db}
+}
+
+func (r *GormLoginAttemptRepository) Get(key string) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	err := r.db.Where("attempt_key = ?", key).First(&attempt).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.LoginAttempt{Key: key}, nil
+	}
+	return &attempt, err
+}
+
+func (r *GormLoginAttemptRepository) Update(key string, fn func(attempt *model.LoginAttempt)) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// 先确保记录存在，再加行锁读取，避免并发失败时计数丢失
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.LoginAttempt{Key: key}).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("attempt_key = ?", key).First(&attempt).Error; err != nil {
+			return err
+		}
+		fn(&attempt)
+		return tx.Save(&attempt).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+func (r *GormLoginAttemptRepository) Delete(key string) error {
+	return r.db.Where("attempt_key = ?", key).Delete(&model.LoginAttempt{}).Error
+}
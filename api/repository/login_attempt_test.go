@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+)
+
+func loginAttemptRepositories(t *testing.T) map[string]LoginAttemptRepository {
+	return map[string]LoginAttemptRepository{
+		"memory": NewMemoryLoginAttemptRepository(time.Hour),
+		"gorm":   NewGormLoginAttemptRepository(newTestDB(t, &model.LoginAttempt{})),
+	}
+}
+
+func TestLoginAttemptRepository(t *testing.T) {
+	for name, repo := range loginAttemptRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			attempt, err := repo.Get("account:a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempt.Key != "account:a" || attempt.Failures != 0 {
+				t.Errorf("不存在的记录应返回零值: %+v", attempt)
+			}
+
+			now := time.Now()
+			for i := 0; i < 2; i++ {
+				if _, err := repo.Update("account:a", func(a *model.LoginAttempt) {
+					a.Failures++
+					a.LastFailureAt = &now
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if attempt, err = repo.Get("account:a"); err != nil {
+				t.Fatal(err)
+			}
+			if attempt.Failures != 2 || attempt.LastFailureAt == nil {
+				t.Errorf("attempt = %+v", attempt)
+			}
+			if other, _ := repo.Get("ip:127.0.0.1"); other.Failures != 0 {
+				t.Errorf("不同的键互不影响: %+v", other)
+			}
+
+			if err := repo.Delete("account:a"); err != nil {
+				t.Fatal(err)
+			}
+			if attempt, _ = repo.Get("account:a"); attempt.Failures != 0 {
+				t.Errorf("删除后 attempt = %+v", attempt)
+			}
+		})
+	}
+}
+
+func TestLoginAttemptRepositoryConcurrentUpdate(t *testing.T) {
+	for name, repo := range loginAttemptRepositories(t) {
+		t.Run(name, func(t *testing.T) {
+			const n = 20
+			var wg sync.WaitGroup
+			for i := 0; i < n; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if _, err := repo.Update("account:a", func(a *model.LoginAttempt) {
+						now := time.Now()
+						a.Failures++
+						a.LastFailureAt = &now
+					}); err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			attempt, err := repo.Get("account:a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempt.Failures != n {
+				t.Errorf("Failures = %d，并发更新不应丢失计数", attempt.Failures)
+			}
+		})
+	}
+}
+
+func TestMemoryLoginAttemptRepositorySweep(t *testing.T) {
+	repo := NewMemoryLoginAttemptRepository(time.Minute)
+	old := time.Now().Add(-time.Hour)
+	next := time.Now().Add(time.Minute)
+	repo.Update("account:expired", func(a *model.LoginAttempt) {
+		a.Failures = 1
+		a.LastFailureAt = &old
+	})
+	repo.Update("account:pending", func(a *model.LoginAttempt) { a.NextAllowedAt = &next })
+
+	// 任意一次写入都会清理过期记录，退避期内的记录保留
+	repo.Update("ip:127.0.0.1", func(a *model.LoginAttempt) {})
+	if attempt, _ := repo.Get("account:expired"); attempt.Failures != 0 {
+		t.Errorf("过期记录未清理: %+v", attempt)
+	}
+	if attempt, _ := repo.Get("account:pending"); attempt.NextAllowedAt == nil {
+		t.Error("退避期内的记录不应被清理")
+	}
+}
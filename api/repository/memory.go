@@ -0,0 +1,801 @@
+package repository
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// MemoryStore 用户、课程和选课记录的内存存储，用于测试。没有数据时返回值与 GORM 一致：
+// 查询模型得到空切片，按字段投影得到 nil。
+// MemoryAuthRepository、MemoryCourseRepository 和 MemoryEnrollmentRepository 共享同一个存储，
+// 课程列表才能像数据库查询一样关联教师姓名和选课人数。存取的都是副本，不会与调用方共享切片。
+// 数据不加密，按证件号码查找时直接比较明文。
+type MemoryStore struct {
+	mu           sync.RWMutex
+	users        map[int64]model.User
+	courses      map[int64]model.Course
+	enrollments  map[enrollmentKey]model.Enrollment
+	nextUserID   int64
+	nextCourseID int64
+}
+
+type enrollmentKey struct {
+	studentID int64
+	courseID  int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[int64]model.User),
+		courses:     make(map[int64]model.Course),
+		enrollments: make(map[enrollmentKey]model.Enrollment),
+	}
+}
+
+// course 未删除的课程，调用方需持有锁
+func (s *MemoryStore) course(id int64) (model.Course, bool) {
+	c, ok := s.courses[id]
+	if !ok || c.DeletedAt.Valid {
+		return model.Course{}, false
+	}
+	return c, true
+}
+
+// enrolledCounts 各课程的选课人数，调用方需持有锁
+func (s *MemoryStore) enrolledCounts() map[int64]int64 {
+	counts := make(map[int64]int64)
+	for key := range s.enrollments {
+		counts[key.courseID]++
+	}
+	return counts
+}
+
+// courseRow 与 courseListQuery 关联出的一行相同，包含投影表中的全部字段，调用方需持有锁
+func (s *MemoryStore) courseRow(c model.Course, counts map[int64]int64, viewerID int64) map[string]interface{} {
+	var teacherName interface{}
+	for _, u := range s.users {
+		if u.PublicID == c.TeacherID {
+			teacherName = u.Name
+			break
+		}
+	}
+	_, enrolled := s.enrollments[enrollmentKey{studentID: viewerID, courseID: c.ID}]
+	return map[string]interface{}{
+		"id":              c.ID,
+		"code":            c.Code,
+		"name":            c.Name,
+		"teacher_id":      c.TeacherID,
+		"teacher_name":    teacherName,
+		"remark":          c.Remark,
+		"student_maxnum":  int64(c.StudentMaxNum),
+		"enrolled_count":  counts[c.ID],
+		"remaining_seats": int64(c.StudentMaxNum) - counts[c.ID],
+		"is_enrolled":     enrolled,
+		"hours":           int64(c.Hours),
+		"credits":         c.Credits,
+		"category":        c.Category,
+		"term":            c.Term,
+		"status":          c.Status,
+		"start_date":      c.StartDate,
+		"min_year_level":  int64(c.MinYearLevel),
+		"max_year_level":  int64(c.MaxYearLevel),
+	}
+}
+
+func copyCourse(c model.Course) model.Course {
+	c.Tags = append([]model.CourseTag(nil), c.Tags...)
+	c.Audiences = append([]model.CourseAudience(nil), c.Audiences...)
+	c.Students = nil
+	return c
+}
+
+// memoryPage 按 findPage 的规则对内存中的完整行排序、分页并选择字段。
+// 排序值相同时按ID排序，结果是确定的。
+func memoryPage(rows []map[string]interface{}, projection *model.Projection, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	if _, ok := projection.Expr(sortBy); !ok {
+		return nil, &model.UnknownFieldError{Field: sortBy, Allowed: projection.Allowed()}
+	}
+	if len(fields) == 0 {
+		fields = projection.Allowed()
+	}
+	if err := projection.Validate(fields); err != nil {
+		return nil, err
+	}
+	total := int64(len(rows))
+
+	ascending := sortOrder == "ASC"
+	if pagination.IsCursor() && pagination.Cursor.Backward {
+		ascending = !ascending
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		c := compareRows(rows[i], rows[j], sortBy)
+		if ascending {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	if !pagination.IsCursor() {
+		start := min(pagination.Offset(), len(rows))
+		end := min(start+pagination.Limit(), len(rows))
+		return &model.PageResult{Rows: selectFields(rows[start:end], fields), Total: total}, nil
+	}
+
+	cursor := pagination.Cursor
+	if !cursor.IsStart() {
+		bound := map[string]interface{}{"id": cursor.ID}
+		if sortBy != "id" {
+			value, err := parseCursorValue(sortBy, cursor.Value)
+			if err != nil {
+				return nil, err
+			}
+			bound[sortBy] = value
+		}
+		var after []map[string]interface{}
+		for _, row := range rows {
+			c := compareRows(row, bound, sortBy)
+			if (ascending && c > 0) || (!ascending && c < 0) {
+				after = append(after, row)
+			}
+		}
+		rows = after
+	}
+	if len(rows) > pagination.Limit()+1 {
+		rows = rows[:pagination.Limit()+1]
+	}
+
+	rows, next, prev := keysetBounds(selectFields(rows, append(append([]string(nil), fields...), "id", sortBy)), pagination, sortBy, sortOrder)
+	return &model.PageResult{Rows: rows, Total: total, Next: next, Prev: prev}, nil
+}
+
+// compareRows 按 (sortBy, id) 组合键比较两行
+func compareRows(a, b map[string]interface{}, sortBy string) int {
+	if sortBy != "id" {
+		if c := compareValues(a[sortBy], b[sortBy]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(toInt64(a["id"]), toInt64(b["id"]))
+}
+
+// compareValues 数值按大小、时间按先后比较，NULL 排在最前，其余按字符串比较
+func compareValues(a, b interface{}) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if x, ok := a.(time.Time); ok {
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	x, xok := toFloat64(a)
+	y, yok := toFloat64(b)
+	if xok && yok {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int, int32, int64, uint, uint32, uint64:
+		return float64(toInt64(val)), true
+	}
+	return 0, false
+}
+
+// selectFields 只保留 fields 中的字段，返回新的行。没有数据时与 GORM 一样返回 nil
+func selectFields(rows []map[string]interface{}, fields []string) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, row := range rows {
+		selected := make(map[string]interface{}, len(fields))
+		for _, name := range fields {
+			selected[name] = row[name]
+		}
+		result = append(result, selected)
+	}
+	return result
+}
+
+// MemoryAuthRepository AuthRepository 的内存实现
+type MemoryAuthRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryAuthRepository(store *MemoryStore) *MemoryAuthRepository {
+	return &MemoryAuthRepository{store: store}
+}
+
+func (r *MemoryAuthRepository) FindByIDCard(idCard string) (*model.User, error) {
+	return r.find(func(u model.User) bool { return u.IDCard == idCard })
+}
+
+func (r *MemoryAuthRepository) FindByPublicID(publicID string) (*model.User, error) {
+	return r.find(func(u model.User) bool { return u.PublicID == publicID })
+}
+
+func (r *MemoryAuthRepository) FindByID(id int64) (*model.User, error) {
+	return r.find(func(u model.User) bool { return u.ID == id })
+}
+
+func (r *MemoryAuthRepository) find(match func(u model.User) bool) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, u := range r.store.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// CreateUser 与数据库一样生成ID和 PublicID，PublicID 或证件号码重复时返回 gorm.ErrDuplicatedKey
+func (r *MemoryAuthRepository) CreateUser(user *model.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user.PublicID == "" {
+		user.PublicID = pkg.NewPublicID()
+	}
+	if err := r.checkUnique(*user); err != nil {
+		return err
+	}
+	if user.ID == 0 {
+		r.store.nextUserID++
+		user.ID = r.store.nextUserID
+	} else if _, ok := r.store.users[user.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	r.store.nextUserID = max(r.store.nextUserID, user.ID)
+	r.store.users[user.ID] = *user
+	return nil
+}
+
+var userSchemaCache sync.Map
+
+// UpdateUser 与数据库一样只复制 columns 中的列，用户不存在时返回 gorm.ErrRecordNotFound
+func (r *MemoryAuthRepository) UpdateUser(user *model.User, columns ...string) error {
+	if len(columns) == 0 {
+		return ErrNoColumns
+	}
+	s, err := schema.Parse(&model.User{}, &userSchemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, ok := r.store.users[user.ID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	src, dst := reflect.ValueOf(user).Elem(), reflect.ValueOf(&stored).Elem()
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("users 表没有列 %s", column)
+		}
+		dst.FieldByIndex(field.StructField.Index).Set(src.FieldByIndex(field.StructField.Index))
+	}
+	if err := r.checkUnique(stored); err != nil {
+		return err
+	}
+	r.store.users[user.ID] = stored
+	return nil
+}
+
+func (r *MemoryAuthRepository) ConsumeTOTPCounter(userID, counter int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, ok := r.store.users[userID]
+	if !ok || stored.TOTPLastCounter >= counter {
+		return false, nil
+	}
+	stored.TOTPLastCounter = counter
+	r.store.users[userID] = stored
+	return true, nil
+}
+
+func (r *MemoryAuthRepository) ReplaceRecoveryCodes(userID int64, old, new string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	stored, ok := r.store.users[userID]
+	if !ok || stored.RecoveryCodes != old {
+		return false, nil
+	}
+	stored.RecoveryCodes = new
+	r.store.users[userID] = stored
+	return true, nil
+}
+
+func (r *MemoryAuthRepository) checkUnique(user model.User) error {
+	for _, u := range r.store.users {
+		if u.ID == user.ID {
+			continue
+		}
+		if u.PublicID == user.PublicID || (user.IDCard != "" && u.IDCard == user.IDCard) {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	return nil
+}
+
+// MemoryCourseRepository CourseRepository 的内存实现，删除课程为软删除
+type MemoryCourseRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryCourseRepository(store *MemoryStore) *MemoryCourseRepository {
+	return &MemoryCourseRepository{store: store}
+}
+
+func (r *MemoryCourseRepository) Create(course *model.Course) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if course.ID == 0 {
+		r.store.nextCourseID++
+		course.ID = r.store.nextCourseID
+	} else if _, ok := r.store.courses[course.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	r.store.nextCourseID = max(r.store.nextCourseID, course.ID)
+
+	now := time.Now()
+	course.CreatedAt, course.UpdatedAt = now, now
+	if course.Status == "" {
+		course.Status = model.CourseStatusOpen
+	}
+	for i := range course.Tags {
+		course.Tags[i].CourseID = course.ID
+	}
+	for i := range course.Audiences {
+		course.Audiences[i].CourseID = course.ID
+		if course.Audiences[i].Requirement == "" {
+			course.Audiences[i].Requirement = model.RequirementElective
+		}
+	}
+	r.store.courses[course.ID] = copyCourse(*course)
+	return nil
+}
+
+func (r *MemoryCourseRepository) GetByID(id int64) (*model.Course, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	c, ok := r.store.course(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c = copyCourse(c)
+	return &c, nil
+}
+
+func (r *MemoryCourseRepository) GetRows(ids []int64, viewerID int64, fields []string) (map[int64]map[string]interface{}, error) {
+	if err := model.CourseProjection.Validate(fields); err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		fields = model.CourseProjection.Allowed()
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	counts := r.store.enrolledCounts()
+	var rows []map[string]interface{}
+	for _, id := range ids {
+		if c, ok := r.store.course(id); ok {
+			rows = append(rows, r.store.courseRow(c, counts, viewerID))
+		}
+	}
+	return rowsByID(selectFields(rows, append(slices.Clone(fields), "id")), fields), nil
+}
+
+func (r *MemoryCourseRepository) GetDetail(id int64, viewerID int64) (map[string]interface{}, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	c, ok := r.store.course(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	row := r.store.courseRow(c, r.store.enrolledCounts(), viewerID)
+	tags := make([]string, 0, len(c.Tags))
+	for _, t := range c.Tags {
+		tags = append(tags, t.Tag)
+	}
+	sort.Strings(tags)
+	row["tags"] = tags
+	return row, nil
+}
+
+// ListAll 与数据库实现一样不加载关联
+func (r *MemoryCourseRepository) ListAll() ([]model.Course, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	courses := make([]model.Course, 0, len(r.store.courses))
+	for id := range r.store.courses {
+		if c, ok := r.store.course(id); ok {
+			c.Tags, c.Audiences, c.Students = nil, nil, nil
+			courses = append(courses, c)
+		}
+	}
+	sort.Slice(courses, func(i, j int) bool { return courses[i].ID < courses[j].ID })
+	return courses, nil
+}
+
+func (r *MemoryCourseRepository) Search(filter model.CourseFilter, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := r.store.enrolledCounts()
+	var rows []map[string]interface{}
+	for id := range r.store.courses {
+		c, ok := r.store.course(id)
+		if !ok || !r.matches(c, filter, counts) {
+			continue
+		}
+		rows = append(rows, r.store.courseRow(c, counts, filter.ViewerID))
+	}
+	return memoryPage(rows, model.CourseProjection, pagination, sortBy, sortOrder, fields)
+}
+
+// matches 与 applyCourseFilter 的条件相同，名称按不区分大小写的包含匹配
+func (r *MemoryCourseRepository) matches(c model.Course, filter model.CourseFilter, counts map[int64]int64) bool {
+	if filter.Name != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(filter.Name)) {
+		return false
+	}
+	if filter.TeacherID != "" && c.TeacherID != filter.TeacherID {
+		return false
+	}
+	if len(filter.TeacherIDs) > 0 && !slices.Contains(filter.TeacherIDs, c.TeacherID) {
+		return false
+	}
+	if filter.Code != "" && c.Code != filter.Code {
+		return false
+	}
+	if filter.Category != "" && c.Category != filter.Category {
+		return false
+	}
+	if filter.Term != "" && c.Term != filter.Term {
+		return false
+	}
+	if filter.StartFrom != nil && c.StartDate.Before(*filter.StartFrom) {
+		return false
+	}
+	if filter.StartTo != nil && c.StartDate.After(*filter.StartTo) {
+		return false
+	}
+	if filter.MinHours != nil && c.Hours < *filter.MinHours {
+		return false
+	}
+	if filter.MaxHours != nil && c.Hours > *filter.MaxHours {
+		return false
+	}
+	if filter.MinCredits != nil && c.Credits < *filter.MinCredits {
+		return false
+	}
+	if filter.MaxCredits != nil && c.Credits > *filter.MaxCredits {
+		return false
+	}
+	if filter.Status != "" && c.Status != filter.Status {
+		return false
+	}
+	if filter.AvailableOnly && int64(c.StudentMaxNum) <= counts[c.ID] {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		found := false
+		for _, t := range c.Tags {
+			if t.Tag == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Update updateData 的键为列名，与数据库实现一样同时更新传入的 course
+func (r *MemoryCourseRepository) Update(course *model.Course, updateData map[string]interface{}) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.course(course.ID)
+	if !ok {
+		return nil
+	}
+	for column, value := range updateData {
+		if err := setCourseColumn(&stored, column, value); err != nil {
+			return err
+		}
+		if err := setCourseColumn(course, column, value); err != nil {
+			return err
+		}
+	}
+	stored.UpdatedAt = time.Now()
+	course.UpdatedAt = stored.UpdatedAt
+	r.store.courses[course.ID] = stored
+	return nil
+}
+
+func setCourseColumn(c *model.Course, column string, value interface{}) error {
+	var ok bool
+	switch column {
+	case "code":
+		c.Code, ok = value.(string)
+	case "name":
+		c.Name, ok = value.(string)
+	case "remark":
+		c.Remark, ok = value.(string)
+	case "category":
+		c.Category, ok = value.(string)
+	case "term":
+		c.Term, ok = value.(string)
+	case "status":
+		c.Status, ok = value.(string)
+	case "student_max_num":
+		c.StudentMaxNum, ok = value.(int)
+	case "hours":
+		c.Hours, ok = value.(int)
+	case "min_year_level":
+		c.MinYearLevel, ok = value.(int)
+	case "max_year_level":
+		c.MaxYearLevel, ok = value.(int)
+	case "credits":
+		c.Credits, ok = value.(float64)
+	case "start_date":
+		c.StartDate, ok = value.(time.Time)
+	default:
+		return fmt.Errorf("未知的课程列: %s", column)
+	}
+	if !ok {
+		return fmt.Errorf("课程列 %s 的值类型不正确: %T", column, value)
+	}
+	return nil
+}
+
+func (r *MemoryCourseRepository) ReplaceTags(courseID int64, tags []string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	c, ok := r.store.course(courseID)
+	if !ok {
+		return nil
+	}
+	c.Tags = nil
+	for _, tag := range tags {
+		c.Tags = append(c.Tags, model.CourseTag{CourseID: courseID, Tag: tag})
+	}
+	r.store.courses[courseID] = c
+	return nil
+}
+
+func (r *MemoryCourseRepository) GetAudiences(courseID int64) ([]model.CourseAudience, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	audiences := append([]model.CourseAudience{}, r.store.courses[courseID].Audiences...)
+	sort.Slice(audiences, func(i, j int) bool { return audiences[i].MajorID < audiences[j].MajorID })
+	return audiences, nil
+}
+
+func (r *MemoryCourseRepository) ReplaceAudiences(courseID int64, audiences []model.CourseAudience) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	c, ok := r.store.course(courseID)
+	if !ok {
+		return nil
+	}
+	c.Audiences = nil
+	for _, a := range audiences {
+		c.Audiences = append(c.Audiences, model.CourseAudience{CourseID: courseID, MajorID: a.MajorID, Requirement: a.Requirement})
+	}
+	r.store.courses[courseID] = c
+	return nil
+}
+
+// Delete 软删除，选课记录保留
+func (r *MemoryCourseRepository) Delete(id int64) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	c, ok := r.store.course(id)
+	if !ok {
+		return nil
+	}
+	c.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.store.courses[id] = c
+	return nil
+}
+
+func (r *MemoryCourseRepository) GetEnrollmentCount(courseID int64) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.store.enrolledCounts()[courseID], nil
+}
+
+// MemoryEnrollmentRepository EnrollmentRepository 的内存实现
+type MemoryEnrollmentRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryEnrollmentRepository(store *MemoryStore) *MemoryEnrollmentRepository {
+	return &MemoryEnrollmentRepository{store: store}
+}
+
+func (r *MemoryEnrollmentRepository) GetStudentByPublicID(publicID string) (*model.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	for _, u := range r.store.users {
+		if u.PublicID == publicID && u.Role == "student" {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// GetCourseByID 与数据库实现一样只加载面向的专业
+func (r *MemoryEnrollmentRepository) GetCourseByID(courseID int) (*model.Course, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	c, ok := r.store.course(int64(courseID))
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c = copyCourse(c)
+	c.Tags = nil
+	return &c, nil
+}
+
+func (r *MemoryEnrollmentRepository) GetEnrollment(studentID, courseID int64) (*model.Enrollment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	e, ok := r.store.enrollments[enrollmentKey{studentID: studentID, courseID: courseID}]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &e, nil
+}
+
+// CreateEnrollment 课程或学生不存在时与外键约束一样拒绝
+func (r *MemoryEnrollmentRepository) CreateEnrollment(enrollment *model.Enrollment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	return r.createEnrollment(enrollment)
+}
+
+// CreateEnrollmentWithinCapacity 在同一次加锁中统计人数并写入
+func (r *MemoryEnrollmentRepository) CreateEnrollmentWithinCapacity(enrollment *model.Enrollment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	course, ok := r.store.course(enrollment.CourseID)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	if r.store.enrolledCounts()[course.ID] >= int64(course.StudentMaxNum) {
+		return ErrCourseFull
+	}
+	return r.createEnrollment(enrollment)
+}
+
+func (r *MemoryEnrollmentRepository) createEnrollment(enrollment *model.Enrollment) error {
+	key := enrollmentKey{studentID: enrollment.StudentID, courseID: enrollment.CourseID}
+	if _, ok := r.store.enrollments[key]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if _, ok := r.store.courses[enrollment.CourseID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if _, ok := r.store.users[enrollment.StudentID]; !ok {
+		return gorm.ErrForeignKeyViolated
+	}
+	if enrollment.Status == "" {
+		enrollment.Status = model.EnrollmentStatusEnrolled
+	}
+	r.store.enrollments[key] = *enrollment
+	return nil
+}
+
+func (r *MemoryEnrollmentRepository) DeleteEnrollment(enrollment *model.Enrollment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	delete(r.store.enrollments, enrollmentKey{studentID: enrollment.StudentID, courseID: enrollment.CourseID})
+	return nil
+}
+
+func (r *MemoryEnrollmentRepository) CountEnrollmentsByCourse(courseID int64) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return r.store.enrolledCounts()[courseID], nil
+}
+
+func (r *MemoryEnrollmentRepository) GetStudentEnrollments(studentID int64) ([]model.Enrollment, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	enrollments := make([]model.Enrollment, 0)
+	for key, e := range r.store.enrollments {
+		if key.studentID == studentID {
+			enrollments = append(enrollments, e)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].CourseID < enrollments[j].CourseID })
+	return enrollments, nil
+}
+
+func (r *MemoryEnrollmentRepository) GetStudentCourses(studentID int64, enrollmentIDs []int64, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	counts := r.store.enrolledCounts()
+	seen := make(map[int64]bool, len(enrollmentIDs))
+	var rows []map[string]interface{}
+	for _, id := range enrollmentIDs {
+		c, ok := r.store.course(id)
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		rows = append(rows, r.store.courseRow(c, counts, studentID))
+	}
+	return memoryPage(rows, model.CourseProjection, pagination, sortBy, sortOrder, fields)
+}
+
+// UpdateEnrollment 与 Save 相同，记录不存在时新建
+func (r *MemoryEnrollmentRepository) UpdateEnrollment(enrollment *model.Enrollment) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.enrollments[enrollmentKey{studentID: enrollment.StudentID, courseID: enrollment.CourseID}] = *enrollment
+	return nil
+}
+
+// GetStudentRecords 学生全部的修读记录，按学期排序，不含已删除的课程
+func (r *MemoryEnrollmentRepository) GetStudentRecords(studentID int64) ([]model.CourseRecord, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	records := make([]model.CourseRecord, 0)
+	for key, e := range r.store.enrollments {
+		if key.studentID != studentID {
+			continue
+		}
+		c, ok := r.store.course(key.courseID)
+		if !ok {
+			continue
+		}
+		records = append(records, model.CourseRecord{
+			CourseID: c.ID,
+			Code:     c.Code,
+			Name:     c.Name,
+			Credits:  c.Credits,
+			Category: c.Category,
+			Term:     c.Term,
+			Status:   e.Status,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Term != records[j].Term {
+			return records[i].Term < records[j].Term
+		}
+		return records[i].CourseID < records[j].CourseID
+	})
+	return records, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"gorm.io/gorm"
+)
+
+// OrganizationRepository 院系、专业和行政班
+type OrganizationRepository interface {
+	CreateDepartment(department *model.Department) error
+	GetDepartment(id int64) (*model.Department, error)
+	ListDepartments() ([]model.Department, error)
+	CreateMajor(major *model.Major) error
+	GetMajor(id int64) (*model.Major, error)
+	ListMajors(departmentID int64) ([]model.Major, error)
+	CreateCohort(cohort *model.Cohort) error
+	GetCohort(id int64) (*model.Cohort, error)
+	ListCohorts(majorID int64) ([]model.Cohort, error)
+}
+
+type GormOrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewGormOrganizationRepository(db *gorm.DB) *GormOrganizationRepository {
+	return &GormOrganizationRepository{db: db}
+}
+
+func (r *GormOrganizationRepository) CreateDepartment(department *model.Department) error {
+	return r.db.Create(department).Error
+}
+
+func (r *GormOrganizationRepository) GetDepartment(id int64) (*model.Department, error) {
+	var department model.Department
+	if err := r.db.First(&department, id).Error; err != nil {
+		return nil, err
+	}
+	return &department, nil
+}
+
+func (r *GormOrganizationRepository) ListDepartments() ([]model.Department, error) {
+	var departments []model.Department
+	err := r.db.Order("code").Find(&departments).Error
+	return departments, err
+}
+
+func (r *GormOrganizationRepository) CreateMajor(major *model.Major) error {
+	return r.db.Create(major).Error
+}
+
+func (r *GormOrganizationRepository) GetMajor(id int64) (*model.Major, error) {
+	var major model.Major
+	if err := r.db.First(&major, id).Error; err != nil {
+		return nil, err
+	}
+	return &major, nil
+}
+
+func (r *GormOrganizationRepository) ListMajors(departmentID int64) ([]model.Major, error) {
+	var majors []model.Major
+	err := r.db.Where("department_id = ?", departmentID).Order("code").Find(&majors).Error
+	return majors, err
+}
+
+func (r *GormOrganizationRepository) CreateCohort(cohort *model.Cohort) error {
+	return r.db.Create(cohort).Error
+}
+
+func (r *GormOrganizationRepository) GetCohort(id int64) (*model.Cohort, error) {
+	var cohort model.Cohort
+	if err := r.db.First(&cohort, id).Error; err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+func (r *GormOrganizationRepository) ListCohorts(majorID int64) ([]model.Cohort, error) {
+	var cohorts []model.Cohort
+	err := r.db.Where("major_id = ?", majorID).Order("year DESC, class_name").Find(&cohorts).Error
+	return cohorts, err
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"gorm.io/gorm"
+)
+
+// findPage 统计总数并按页码或游标查询一页数据。
+// query 只应包含过滤条件；sortBy 和 fields 为投影表中的对外字段名，结果以对外字段名为键。
+func findPage(query *gorm.DB, projection *model.Projection, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PageResult, error) {
+	sortExpr, ok := projection.Expr(sortBy)
+	if !ok {
+		return nil, &model.UnknownFieldError{Field: sortBy, Allowed: projection.Allowed()}
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if !pagination.IsCursor() {
+		selects, err := projection.Select(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		var rows []map[string]interface{}
+		err = query.Select(selects).
+			Offset(pagination.Offset()).
+			Limit(pagination.Limit()).
+			Order(sortExpr + " " + sortOrder).
+			Find(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		return &model.PageResult{Rows: rows, Total: total}, nil
+	}
+
+	// 游标需要排序字段和ID，字段投影中缺少时补上
+	selects, err := projection.Select(fields, "id", sortBy)
+	if err != nil {
+		return nil, err
+	}
+	idExpr, _ := projection.Expr("id")
+
+	rows, next, prev, err := findKeysetPage(query.Select(selects), pagination, sortBy, sortExpr, idExpr, sortOrder)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PageResult{Rows: rows, Total: total, Next: next, Prev: prev}, nil
+}
+
+// findKeysetPage 按 (排序字段, id) 组合键定位，多取一行用于判断是否还有更多数据
+func findKeysetPage(query *gorm.DB, pagination model.Pagination, sortBy, sortExpr, idExpr, sortOrder string) ([]map[string]interface{}, *model.Cursor, *model.Cursor, error) {
+	cursor := pagination.Cursor
+
+	// 向前翻页时反向查询，取回后再倒序
+	ascending := sortOrder == "ASC"
+	if cursor.Backward {
+		ascending = !ascending
+	}
+	cmp, order := ">", "ASC"
+	if !ascending {
+		cmp, order = "<", "DESC"
+	}
+
+	if !cursor.IsStart() {
+		if sortBy == "id" {
+			query = query.Where(fmt.Sprintf("%s %s ?", idExpr, cmp), cursor.ID)
+		} else {
+			value, err := parseCursorValue(sortBy, cursor.Value)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", sortExpr, cmp, sortExpr, idExpr, cmp),
+				value, value, cursor.ID)
+		}
+	}
+
+	var rows []map[string]interface{}
+	err := query.Limit(pagination.Limit() + 1).
+		Order(sortExpr + " " + order).
+		Order(idExpr + " " + order).
+		Find(&rows).Error
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	rows, next, prev := keysetBounds(rows, pagination, sortBy, sortOrder)
+	return rows, next, prev, nil
+}
+
+// keysetBounds rows 为按查询方向多取一行的结果，截去多取的行、恢复为 sortOrder 的顺序，
+// 并计算前后页的游标
+func keysetBounds(rows []map[string]interface{}, pagination model.Pagination, sortBy, sortOrder string) ([]map[string]interface{}, *model.Cursor, *model.Cursor) {
+	cursor := pagination.Cursor
+	hasMore := len(rows) > pagination.Limit()
+	if hasMore {
+		rows = rows[:pagination.Limit()]
+	}
+	if cursor.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	var next, prev *model.Cursor
+	first, last := rows[0], rows[len(rows)-1]
+	if cursor.Backward {
+		next = keysetCursor(last, sortBy, sortOrder, false)
+		if hasMore {
+			prev = keysetCursor(first, sortBy, sortOrder, true)
+		}
+	} else {
+		if hasMore {
+			next = keysetCursor(last, sortBy, sortOrder, false)
+		}
+		if !cursor.IsStart() {
+			prev = keysetCursor(first, sortBy, sortOrder, true)
+		}
+	}
+	return rows, next, prev
+}
+
+func keysetCursor(row map[string]interface{}, sortBy, sortOrder string, backward bool) *model.Cursor {
+	cursor := &model.Cursor{
+		SortBy:   sortBy,
+		Order:    sortOrder,
+		ID:       toInt64(row["id"]),
+		Backward: backward,
+	}
+	if sortBy != "id" {
+		cursor.Value = formatCursorValue(row[sortBy])
+	}
+	return cursor
+}
+
+func formatCursorValue(v interface{}) string {
+	switch val := v.(type) {
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		return string(val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// parseCursorValue 按排序字段的类型还原边界值，使数据库按数值或时间比较
+func parseCursorValue(sortBy, s string) (interface{}, error) {
+	switch model.SortValueTypes[sortBy] {
+	case model.SortValueInt:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, pkg.ErrInvalidCursor
+		}
+		return i, nil
+	case model.SortValueTime:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, pkg.ErrInvalidCursor
+		}
+		return t, nil
+	default:
+		return s, nil
+	}
+}
+
+func toInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case int32:
+		return int64(val)
+	case int:
+		return int64(val)
+	case uint64:
+		return int64(val)
+	case uint32:
+		return int64(val)
+	case uint:
+		return int64(val)
+	case float64:
+		return int64(val)
+	case []byte:
+		i, _ := strconv.ParseInt(string(val), 10, 64)
+		return i
+	case string:
+		i, _ := strconv.ParseInt(val, 10, 64)
+		return i
+	}
+	return 0
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"gorm.io/gorm"
+)
+
+type PasswordHistoryRepository interface {
+	// Recent 返回用户最近的 limit 条记录，新的在前
+	Recent(userID int64, limit int) ([]model.PasswordHistory, error)
+	// Add 追加一条记录，并只保留最近的 keep 条
+	Add(entry *model.PasswordHistory, keep int) error
+}
+
+type GormPasswordHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPasswordHistoryRepository(db *gorm.DB) *GormPasswordHistoryRepository {
+	return &GormPasswordHistoryRepository{db: db}
+}
+
+func (r *GormPasswordHistoryRepository) Recent(userID int64, limit int) ([]model.PasswordHistory, error) {
+	var entries []model.PasswordHistory
+	err := r.db.Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
+
+func (r *GormPasswordHistoryRepository) Add(entry *model.PasswordHistory, keep int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(entry).Error; err != nil {
+			return err
+		}
+
+		var ids []int64
+		err := tx.Model(&model.PasswordHistory{}).
+			Where("user_id = ?", entry.UserID).
+			Order("id DESC").
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) <= keep {
+			return err
+		}
+		return tx.Where("id IN ?", ids[keep:]).Delete(&model.PasswordHistory{}).Error
+	})
+}
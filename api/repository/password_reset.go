@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"gorm.io/gorm"
+)
+
+// ErrResetTokenUsed 令牌已被并发请求使用
+var ErrResetTokenUsed = errors.New("重置令牌已被使用")
+
+type PasswordResetRepository interface {
+	// Create 保存新令牌，同时作废该用户之前未使用的令牌
+	Create(token *model.PasswordResetToken) error
+	FindByHash(hash string) (*model.PasswordResetToken, error)
+	// LatestCreatedAt 返回用户最近一次申请重置的时间，没有记录时返回零值
+	LatestCreatedAt(userID int64) (time.Time, error)
+	// Consume 将令牌标记为已使用并在同一事务中保存用户的新密码和令牌版本，令牌已被使用时返回 ErrResetTokenUsed
+	Consume(token *model.PasswordResetToken, user *model.User) error
+}
+
+type GormPasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewGormPasswordResetRepository(db *gorm.DB) *GormPasswordResetRepository {
+	return &GormPasswordResetRepository{db: db}
+}
+
+func (r *GormPasswordResetRepository) Create(token *model.PasswordResetToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND used_at IS NULL", token.UserID).Delete(&model.PasswordResetToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(token).Error
+	})
+}
+
+func (r *GormPasswordResetRepository) FindByHash(hash string) (*model.PasswordResetToken, error) {
+	var token model.PasswordResetToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *GormPasswordResetRepository) LatestCreatedAt(userID int64) (time.Time, error) {
+	var tokens []model.PasswordResetToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(1).Find(&tokens).Error
+	if err != nil || len(tokens) == 0 {
+		return time.Time{}, err
+	}
+	return tokens[0].CreatedAt, nil
+}
+
+func (r *GormPasswordResetRepository) Consume(token *model.PasswordResetToken, user *model.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrResetTokenUsed
+		}
+		token.UsedAt = &now
+		return tx.Model(user).Select("password", "token_version").Updates(user).Error
+	})
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"gorm.io/gorm"
+)
+
+// ProgramRepository 专业培养方案
+type ProgramRepository interface {
+	GetByMajor(majorID int64) (*model.Program, error)
+	// Save 保存培养方案，专业已有方案时整体替换
+	Save(program *model.Program) error
+}
+
+type GormProgramRepository struct {
+	db *gorm.DB
+}
+
+func NewGormProgramRepository(db *gorm.DB) *GormProgramRepository {
+	return &GormProgramRepository{db: db}
+}
+
+func (r *GormProgramRepository) GetByMajor(majorID int64) (*model.Program, error) {
+	var program model.Program
+	err := r.db.
+		Preload("RequiredCourses").
+		Preload("ElectiveGroups").
+		Preload("ElectiveGroups.Courses").
+		Preload("CategoryCredits").
+		Where("major_id = ?", majorID).
+		First(&program).Error
+	if err != nil {
+		return nil, err
+	}
+	return &program, nil
+}
+
+func (r *GormProgramRepository) Save(program *model.Program) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.Program
+		err := tx.Where("major_id = ?", program.MajorID).First(&existing).Error
+		switch err {
+		case nil:
+			if err := deleteProgramItems(tx, existing.ID); err != nil {
+				return err
+			}
+			program.ID = existing.ID
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"name":          program.Name,
+				"total_credits": program.TotalCredits,
+			}).Error; err != nil {
+				return err
+			}
+		case gorm.ErrRecordNotFound:
+			if err := tx.Omit("RequiredCourses", "ElectiveGroups", "CategoryCredits").Create(program).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		for i := range program.RequiredCourses {
+			program.RequiredCourses[i].ProgramID = program.ID
+		}
+		for i := range program.CategoryCredits {
+			program.CategoryCredits[i].ProgramID = program.ID
+		}
+		for i := range program.ElectiveGroups {
+			program.ElectiveGroups[i].ID = 0
+			program.ElectiveGroups[i].ProgramID = program.ID
+		}
+		if len(program.RequiredCourses) > 0 {
+			if err := tx.Create(&program.RequiredCourses).Error; err != nil {
+				return err
+			}
+		}
+		if len(program.CategoryCredits) > 0 {
+			if err := tx.Create(&program.CategoryCredits).Error; err != nil {
+				return err
+			}
+		}
+		// 选修课组连同组内课程一起创建
+		if len(program.ElectiveGroups) > 0 {
+			if err := tx.Create(&program.ElectiveGroups).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func deleteProgramItems(tx *gorm.DB, programID int64) error {
+	groupIDs := tx.Model(&model.ElectiveGroup{}).Select("id").Where("program_id = ?", programID)
+	if err := tx.Where("group_id IN (?)", groupIDs).Delete(&model.ElectiveGroupCourse{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("program_id = ?", programID).Delete(&model.ElectiveGroup{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("program_id = ?", programID).Delete(&model.ProgramCourse{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("program_id = ?", programID).Delete(&model.CategoryRequirement{}).Error
+}
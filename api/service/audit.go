@@ -0,0 +1,53 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// 审计事件类型
+const (
+	AuditLoginSucceeded         = "login.succeeded"
+	AuditLoginFailed            = "login.failed"
+	AuditLoginThrottled         = "login.throttled"
+	AuditAccountLocked          = "account.locked"
+	AuditAccountUnlock          = "account.unlocked"
+	AuditMFAEnabled             = "mfa.enabled"
+	AuditMFADisabled            = "mfa.disabled"
+	AuditMFAFailed              = "mfa.failed"
+	AuditRecoveryUsed           = "mfa.recovery_code_used"
+	AuditPasswordChanged        = "password.changed"
+	AuditPasswordResetRequested = "password.reset_requested"
+	AuditPasswordReset          = "password.reset"
+)
+
+// AuditEvent 安全相关的审计事件，不记录证件号等明文个人信息
+type AuditEvent struct {
+	Type    string    `json:"type"`
+	Actor   string    `json:"actor,omitempty"`   // 操作者的 PublicID
+	Subject string    `json:"subject,omitempty"` // 被操作的用户 PublicID 或限流键
+	IP      string    `json:"ip,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// AuditLogger 审计事件的输出
+type AuditLogger interface {
+	Record(event AuditEvent)
+}
+
+// LogAuditLogger 以 JSON 行写入标准日志
+type LogAuditLogger struct{}
+
+func (LogAuditLogger) Record(event AuditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("audit: 序列化审计事件失败: %v", err)
+		return
+	}
+	log.Printf("audit %s", data)
+}
@@ -2,53 +2,86 @@ package service
 
 import (
 	"errors"
-
-	"github.com/dlclark/regexp2"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/repository"
 	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
 )
 
 var (
 	ErrUserAlreadyExists  = errors.New("用户已存在")
-	ErrInvalidPassword    = errors.New("密码必须包含至少一个大写字母、一个小写字母和一个数字")
+	ErrInvalidPassword    = errors.New("密码不符合要求")
 	ErrInvalidCredentials = errors.New("用户不存在或密码错误")
+	ErrInvalidDocument    = errors.New("证件号码格式不正确")
+	ErrUserNotFound       = errors.New("用户不存在")
+	ErrSessionRevoked     = errors.New("登录已失效，请重新登录")
 )
 
-var regex = regexp2.MustCompile(`^(?=.*[a-z])(?=.*[A-Z])(?=.*\d).+$`, 0)
+// 非居民身份证的证件号码格式
+var documentPatterns = map[string]*regexp.Regexp{
+	model.DocumentTypePassport:      regexp.MustCompile(`^[A-Z0-9]{5,17}$`),
+	model.DocumentTypeHKMacauPermit: regexp.MustCompile(`^[HM]\d{8}(\d{2})?$`),
+	model.DocumentTypeTaiwanPermit:  regexp.MustCompile(`^\d{8}(\d{2})?$`),
+}
 
 type AuthService struct {
-	repo repository.AuthRepository
+	repo      repository.AuthRepository
+	tokens    *pkg.TokenSigner
+	throttle  *LoginThrottle
+	passwords *PasswordValidator
+	mfa       MFAPolicy
+	audit     AuditLogger
 }
 
-func NewAuthService(repo repository.AuthRepository) *AuthService {
-	return &AuthService{repo: repo}
+func NewAuthService(repo repository.AuthRepository, tokens *pkg.TokenSigner, throttle *LoginThrottle, passwords *PasswordValidator, mfa MFAPolicy, audit AuditLogger) *AuthService {
+	return &AuthService{repo: repo, tokens: tokens, throttle: throttle, passwords: passwords, mfa: mfa, audit: audit}
 }
 
 type RegisterInput struct {
-	IDCard   string `json:"id_card"`
-	Name     string `json:"name"`
-	Password string `json:"password"`
-	Role     string `json:"role"` // student or teacher
+	IDCard       string `json:"id_card"`
+	DocumentType string `json:"document_type"` // 为空时按居民身份证处理
+	Name         string `json:"name"`
+	Email        string `json:"email"` // 可选，用于找回密码
+	Password     string `json:"password"`
+	Role         string `json:"role"` // student or teacher
 }
 
 func (s *AuthService) Register(input RegisterInput) (*model.User, error) {
+	if input.DocumentType == "" {
+		input.DocumentType = model.DocumentTypeIDCard
+	}
+
+	// 校验并规范化证件号码
+	idCard, err := normalizeDocument(input.DocumentType, input.IDCard)
+	if err != nil {
+		return nil, err
+	}
+	input.IDCard = idCard
+
 	// 检查用户是否已存在
 	if _, err := s.repo.FindByIDCard(input.IDCard); err == nil {
 		return nil, ErrUserAlreadyExists
 	}
 
-	// 密码复杂度验证
-	if !isValidPassword(input.Password) {
-		return nil, ErrInvalidPassword
+	user := &model.User{
+		IDCard:       input.IDCard,
+		DocumentType: input.DocumentType,
+		Name:         input.Name,
+		Email:        input.Email,
+		Role:         input.Role,
 	}
 
-	user := &model.User{
-		IDCard:   input.IDCard,
-		Name:     input.Name,
-		Password: input.Password,
-		Role:     input.Role,
+	// 密码策略校验
+	if err := s.passwords.Validate(input.Password, user); err != nil {
+		return nil, err
+	}
+	if user.Password, err = pkg.HashPassword(input.Password); err != nil {
+		return nil, err
 	}
 
 	if err := s.repo.CreateUser(user); err != nil {
@@ -61,26 +94,182 @@ func (s *AuthService) Register(input RegisterInput) (*model.User, error) {
 type LoginInput struct {
 	IDCard   string `json:"id_card"`
 	Password string `json:"password"`
+	IP       string `json:"-"`
 }
 
-func (s *AuthService) Login(input LoginInput) (string, error) {
-	user, err := s.repo.FindByIDCard(input.IDCard)
-	if err != nil || user.Password != input.Password {
-		return "", ErrInvalidCredentials
+// Login 校验证件号和密码。用户启用了两步验证或角色要求两步验证时，返回挑战令牌而不是正式令牌
+func (s *AuthService) Login(input LoginInput) (*LoginResult, error) {
+	idCard := normalizeLoginID(input.IDCard)
+	accountKey, err := accountThrottleKey(idCard)
+	if err != nil {
+		return nil, err
 	}
+	ipKey := "ip:" + input.IP
 
-	token, err := pkg.GenerateToken(user.IDCard, user.Role)
+	// IP被锁定或账号处于退避、锁定期时直接拒绝，不校验密码
+	if err := s.throttle.Check(ipKey); err != nil {
+		s.audit.Record(AuditEvent{Type: AuditLoginThrottled, Subject: ipKey, IP: input.IP, Detail: err.Error()})
+		return nil, err
+	}
+	if err := s.throttle.Begin(accountKey); err != nil {
+		s.audit.Record(AuditEvent{Type: AuditLoginThrottled, Subject: accountKey, IP: input.IP, Detail: err.Error()})
+		return nil, err
+	}
+
+	// 账号不存在时同样比较一次哈希，响应时间不暴露账号是否存在
+	user, err := s.repo.FindByIDCard(idCard)
 	if err != nil {
-		return "", err
+		pkg.CheckPassword(dummyPasswordHash(), input.Password)
+		s.recordLoginFailure(accountKey, ipKey, input.IP)
+		return nil, ErrInvalidCredentials
+	}
+	if !pkg.CheckPassword(user.Password, input.Password) {
+		s.recordLoginFailure(accountKey, ipKey, input.IP)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := s.throttle.Reset(accountKey); err != nil {
+		return nil, err
+	}
+
+	if result, err := s.challenge(user); result != nil || err != nil {
+		return result, err
+	}
+
+	token, err := s.tokens.GenerateToken(user.PublicID, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	s.audit.Record(AuditEvent{Type: AuditLoginSucceeded, Subject: user.PublicID, IP: input.IP})
+	return &LoginResult{Token: token}, nil
 }
 
-func isValidPassword(password string) bool {
-	match, err := regex.MatchString(password)
+// ConfirmIdentity 修改邮箱等敏感操作前再次校验当前密码，启用了两步验证的用户还需提交验证码。
+// 密码错误次数按用户单独限流，避免借已登录的会话暴力尝试密码。
+func (s *AuthService) ConfirmIdentity(user *model.User, password, code, ip string) error {
+	if password == "" {
+		return ErrCurrentPasswordRequired
+	}
+	key := "confirm:" + user.PublicID
+	if err := s.throttle.Begin(key); err != nil {
+		s.audit.Record(AuditEvent{Type: AuditLoginThrottled, Subject: key, IP: ip, Detail: err.Error()})
+		return err
+	}
+	if !pkg.CheckPassword(user.Password, password) {
+		s.audit.Record(AuditEvent{Type: AuditLoginFailed, Subject: key, IP: ip})
+		if _, err := s.throttle.RecordFailure(key, s.throttle.policy.MaxAccountFailures); err != nil {
+			log.Printf("记录密码校验失败出错: %v", err)
+		}
+		return ErrWrongPassword
+	}
+	if err := s.throttle.Reset(key); err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled {
+		return nil
+	}
+	if code == "" {
+		return ErrMFACodeRequired
+	}
+	_, err := s.verifySecondFactor(user, code, ip)
+	return err
+}
+
+func (s *AuthService) recordLoginFailure(accountKey, ipKey, ip string) {
+	s.audit.Record(AuditEvent{Type: AuditLoginFailed, Subject: accountKey, IP: ip})
+
+	accountLocked, accountErr := s.throttle.RecordFailure(accountKey, s.throttle.policy.MaxAccountFailures)
+	ipLocked, ipErr := s.throttle.RecordIPFailure(ipKey)
+	for _, r := range []struct {
+		key    string
+		locked bool
+		err    error
+	}{
+		{accountKey, accountLocked, accountErr},
+		{ipKey, ipLocked, ipErr},
+	} {
+		if r.err != nil {
+			log.Printf("记录登录失败出错: %v", r.err)
+			continue
+		}
+		if r.locked {
+			s.audit.Record(AuditEvent{Type: AuditAccountLocked, Subject: r.key, IP: ip})
+		}
+	}
+}
+
+// dummyPasswordHash 账号不存在时用来比较的哈希，首次使用时生成
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := pkg.HashPassword("course-system-dummy-password")
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// UnlockUser 管理员解除账号的登录锁定
+func (s *AuthService) UnlockUser(adminID, userID string) error {
+	user, err := s.repo.FindByPublicID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	accountKey, err := accountThrottleKey(user.IDCard)
+	if err != nil {
+		return err
+	}
+	if err := s.throttle.Reset(accountKey); err != nil {
+		return err
+	}
+
+	s.audit.Record(AuditEvent{Type: AuditAccountUnlock, Actor: adminID, Subject: user.PublicID})
+	return nil
+}
+
+// ValidateSession 令牌签发后用户修改或重置过密码时，令牌不再有效
+func (s *AuthService) ValidateSession(userID string, tokenVersion int) error {
+	user, err := s.repo.FindByPublicID(userID)
 	if err != nil {
-		return false
+		return ErrSessionRevoked
+	}
+	if user.TokenVersion != tokenVersion {
+		return ErrSessionRevoked
+	}
+	return nil
+}
+
+// accountThrottleKey 账号限流键使用证件号的盲索引，避免明文落库
+func accountThrottleKey(idCard string) (string, error) {
+	hash, err := fieldcrypt.BlindIndex(idCard)
+	if err != nil {
+		return "", err
+	}
+	return "account:" + hash, nil
+}
+
+// normalizeDocument 按证件类型校验号码，返回统一格式
+func normalizeDocument(documentType, number string) (string, error) {
+	if documentType == model.DocumentTypeIDCard {
+		return pkg.NormalizeIDCard(number)
+	}
+
+	pattern, ok := documentPatterns[documentType]
+	if !ok {
+		return "", ErrInvalidDocument
+	}
+	number = strings.ToUpper(strings.TrimSpace(number))
+	if !pattern.MatchString(number) {
+		return "", ErrInvalidDocument
+	}
+	return number, nil
+}
+
+// normalizeLoginID 登录时兼容15位旧身份证号和小写校验位
+func normalizeLoginID(number string) string {
+	if idCard, err := pkg.NormalizeIDCard(number); err == nil {
+		return idCard
 	}
-	return match
+	return strings.ToUpper(strings.TrimSpace(number))
 }
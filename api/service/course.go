@@ -3,42 +3,83 @@ package service
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/search"
+	"gorm.io/gorm"
 )
 
 var (
-	ErrUnauthorized      = errors.New("未认证用户")
-	ErrTeacherNotFound   = errors.New("教师不存在或权限不足")
-	ErrInvalidDateFormat = errors.New("日期格式不正确，请使用YYYY-MM-DD格式")
-	ErrPastStartDate     = errors.New("课程开始日期不能早于今天")
-	ErrCourseNotFound    = errors.New("课程不存在或权限不足")
-	ErrCourseHasStudents = errors.New("课程已有学生选课，不能删除")
-	ErrCourseStarted     = errors.New("课程已开始，不能删除")
-	ErrInvalidStudentNum = errors.New("新人数限制不能小于当前报名人数")
-	ErrInvalidCourseID   = errors.New("无效的课程ID")
+	ErrUnauthorized        = errors.New("未认证用户")
+	ErrTeacherNotFound     = errors.New("教师不存在或权限不足")
+	ErrInvalidDateFormat   = errors.New("日期格式不正确，请使用YYYY-MM-DD格式")
+	ErrPastStartDate       = errors.New("课程开始日期不能早于今天")
+	ErrCourseNotFound      = errors.New("课程不存在或权限不足")
+	ErrCourseHasStudents   = errors.New("课程已有学生选课，不能删除")
+	ErrCourseStarted       = errors.New("课程已开始，不能删除")
+	ErrInvalidStudentNum   = errors.New("新人数限制不能小于当前报名人数")
+	ErrInvalidCourseID     = errors.New("无效的课程ID")
+	ErrInvalidCourseStatus = errors.New("无效的课程状态")
+	ErrInvalidAudience     = errors.New("面向的专业不存在或修读要求不正确")
+	ErrInvalidYearLevel    = errors.New("年级范围不正确")
+	ErrInvalidCategory     = errors.New("无效的课程类别")
+	ErrInvalidCourseSize   = errors.New("人数上限和学时必须大于0")
 )
 
 type CourseService struct {
 	courseRepo repository.CourseRepository
 	userRepo   repository.AuthRepository
+	orgRepo    repository.OrganizationRepository
+	cursors    *pkg.CursorSigner
+
+	searchIndex   atomic.Pointer[search.Index]
+	searchRefresh time.Duration // 距上次重建超过该时间后，下一次检索前从数据库重建索引；为 0 时不重建
+	searchMu      sync.Mutex
+	searchBuiltAt time.Time
+
+	// 有课程的教师 PublicID → 姓名，用于按教师姓名过滤课程，避免每次请求解密全部教师姓名。
+	// 与全文索引一起重建，本实例创建课程或教师修改姓名时同步更新
+	teacherMu    sync.RWMutex
+	teacherNames map[string]string
 }
 
-func NewCourseService(courseRepo repository.CourseRepository, userRepo repository.AuthRepository) *CourseService {
-	return &CourseService{
-		courseRepo: courseRepo,
-		userRepo:   userRepo,
+// NewCourseService cursors 用于对列表和全文检索返回的分页游标签名；
+// searchRefresh 为全文索引的重建间隔，多实例部署时其他实例的修改在该间隔后可以检索到
+func NewCourseService(courseRepo repository.CourseRepository, userRepo repository.AuthRepository, orgRepo repository.OrganizationRepository, cursors *pkg.CursorSigner, searchRefresh time.Duration) *CourseService {
+	s := &CourseService{
+		courseRepo:    courseRepo,
+		userRepo:      userRepo,
+		orgRepo:       orgRepo,
+		cursors:       cursors,
+		searchRefresh: searchRefresh,
 	}
+	s.searchIndex.Store(search.NewIndex(courseSearchBoosts))
+	s.teacherNames = make(map[string]string)
+	return s
 }
 
 type CreateCourseInput struct {
+	Code          string    `json:"code"`
 	Name          string    `json:"name"`
 	Remark        string    `json:"remark"`
 	StudentMaxNum int       `json:"student_maxnum"`
 	Hours         int       `json:"hours"`
+	Credits       float64   `json:"credits"`
+	Category      string    `json:"category"`
+	Term          string    `json:"term"`
+	Tags          []string  `json:"tags"`
 	StartDate     time.Time `json:"start_date"`
+
+	// 选课对象限制，均为空时不限
+	Audiences    []model.CourseAudience `json:"audiences"`
+	MinYearLevel int                    `json:"min_year_level"`
+	MaxYearLevel int                    `json:"max_year_level"`
 }
 
 func (s *CourseService) CreateCourse(teacherID string, input CreateCourseInput) (*model.Course, error) {
@@ -47,7 +88,7 @@ func (s *CourseService) CreateCourse(teacherID string, input CreateCourseInput)
 	}
 
 	// 验证教师是否存在
-	teacher, err := s.userRepo.FindByIDCard(teacherID)
+	teacher, err := s.userRepo.FindByPublicID(teacherID)
 	if err != nil || teacher == nil || teacher.Role != "teacher" {
 		return nil, ErrTeacherNotFound
 	}
@@ -60,23 +101,51 @@ func (s *CourseService) CreateCourse(teacherID string, input CreateCourseInput)
 		return nil, ErrPastStartDate
 	}
 
+	if input.StudentMaxNum <= 0 || input.Hours <= 0 {
+		return nil, ErrInvalidCourseSize
+	}
+	if input.Category != "" && !isValidCourseCategory(input.Category) {
+		return nil, ErrInvalidCategory
+	}
+	if err := validateYearLevels(input.MinYearLevel, input.MaxYearLevel); err != nil {
+		return nil, err
+	}
+	audiences, err := s.normalizeAudiences(input.Audiences)
+	if err != nil {
+		return nil, err
+	}
+
 	course := &model.Course{
+		Code:          normalizeCourseCode(input.Code),
 		Name:          input.Name,
-		TeacherID:     teacher.IDCard,
+		TeacherID:     teacher.PublicID,
 		Remark:        input.Remark,
 		StudentMaxNum: input.StudentMaxNum,
 		Hours:         input.Hours,
+		Credits:       input.Credits,
+		Category:      input.Category,
+		Term:          input.Term,
+		Status:        model.CourseStatusOpen,
 		StartDate:     time.Unix(startDate, 0),
+		MinYearLevel:  input.MinYearLevel,
+		MaxYearLevel:  input.MaxYearLevel,
+		Audiences:     audiences,
+	}
+	for _, tag := range normalizeTags(input.Tags) {
+		course.Tags = append(course.Tags, model.CourseTag{Tag: tag})
 	}
 
 	if err := s.courseRepo.Create(course); err != nil {
 		return nil, err
 	}
+	putSearchDocument(s.searchIndex.Load(), course, teacher.Name)
+	s.setTeacherName(course.TeacherID, teacher.Name)
 
 	return course, nil
 }
 
 type GetCoursesInput struct {
+	Viewer     string // 当前用户，用于标记是否已选课
 	Pagination model.Pagination
 	SortBy     string
 	SortOrder  string
@@ -84,18 +153,59 @@ type GetCoursesInput struct {
 }
 
 func (s *CourseService) GetCourses(input GetCoursesInput) (*model.PaginatedResponse[map[string]interface{}], error) {
-	courses, total, err := s.courseRepo.GetAll(input.Pagination, input.SortBy, input.SortOrder, input.Fields)
+	return s.SearchCourses(model.CourseFilter{}, input)
+}
+
+// SearchCourses 按组合条件搜索课程
+func (s *CourseService) SearchCourses(filter model.CourseFilter, input GetCoursesInput) (*model.PaginatedResponse[map[string]interface{}], error) {
+	if filter.Status != "" && !isValidCourseStatus(filter.Status) {
+		return nil, ErrInvalidCourseStatus
+	}
+
+	if filter.TeacherName != "" {
+		filter.TeacherIDs = s.teacherIDsByName(filter.TeacherName)
+		if len(filter.TeacherIDs) == 0 {
+			return newPaginatedResponse(s.cursors, &model.PageResult{Rows: []map[string]interface{}{}}, input.Pagination)
+		}
+	}
+	filter.ViewerID = s.viewerID(input.Viewer)
+
+	result, err := s.courseRepo.Search(filter, input.Pagination, input.SortBy, input.SortOrder, input.Fields)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.PaginatedResponse[map[string]interface{}]{
-		Data:       courses,
-		Total:      total,
-		Page:       input.Pagination.Page,
-		PageSize:   input.Pagination.PageSize,
-		TotalPages: int((total + int64(input.Pagination.PageSize) - 1) / int64(input.Pagination.PageSize)),
-	}, nil
+	return newPaginatedResponse(s.cursors, result, input.Pagination)
+}
+
+// GetCourse 获取课程详情
+func (s *CourseService) GetCourse(viewer string, courseID int64) (map[string]interface{}, error) {
+	course, err := s.courseRepo.GetDetail(courseID, s.viewerID(viewer))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCourseNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	audiences, err := s.courseRepo.GetAudiences(courseID)
+	if err != nil {
+		return nil, err
+	}
+	course["audiences"] = audiences
+	return course, nil
+}
+
+// viewerID 将令牌中的用户标识转换为用户ID，用户不存在时返回 0
+func (s *CourseService) viewerID(viewer string) int64 {
+	if viewer == "" {
+		return 0
+	}
+	user, err := s.userRepo.FindByPublicID(viewer)
+	if err != nil || user == nil {
+		return 0
+	}
+	return user.ID
 }
 
 func (s *CourseService) DeleteCourse(teacherID string, courseID int64) error {
@@ -123,60 +233,30 @@ func (s *CourseService) DeleteCourse(teacherID string, courseID int64) error {
 		return ErrCourseStarted
 	}
 
-	return s.courseRepo.Delete(courseID)
-}
-
-func (s *CourseService) GetTeacherCourses(teacherID string, input GetCoursesInput) (*model.PaginatedResponse[map[string]interface{}], error) {
-	courses, total, err := s.courseRepo.GetByTeacherID(teacherID, input.Pagination, input.SortBy, input.SortOrder, input.Fields)
-	if err != nil {
-		return nil, err
-	}
-
-	return &model.PaginatedResponse[map[string]interface{}]{
-		Data:       courses,
-		Total:      total,
-		Page:       input.Pagination.Page,
-		PageSize:   input.Pagination.PageSize,
-		TotalPages: int((total + int64(input.Pagination.PageSize) - 1) / int64(input.Pagination.PageSize)),
-	}, nil
-}
-
-func (s *CourseService) GetCoursesByTeacherName(teacherName string, input GetCoursesInput) (*model.PaginatedResponse[map[string]interface{}], error) {
-	courses, total, err := s.courseRepo.GetByTeacherName(teacherName, input.Pagination, input.SortBy, input.SortOrder, input.Fields)
-	if err != nil {
-		return nil, err
-	}
-
-	return &model.PaginatedResponse[map[string]interface{}]{
-		Data:       courses,
-		Total:      total,
-		Page:       input.Pagination.Page,
-		PageSize:   input.Pagination.PageSize,
-		TotalPages: int((total + int64(input.Pagination.PageSize) - 1) / int64(input.Pagination.PageSize)),
-	}, nil
-}
-
-func (s *CourseService) GetCoursesByCourseName(courseName string, input GetCoursesInput) (*model.PaginatedResponse[map[string]interface{}], error) {
-	courses, total, err := s.courseRepo.GetByCourseName(courseName, input.Pagination, input.SortBy, input.SortOrder, input.Fields)
-	if err != nil {
-		return nil, err
+	if err := s.courseRepo.Delete(courseID); err != nil {
+		return err
 	}
+	s.searchIndex.Load().Delete(courseID)
 
-	return &model.PaginatedResponse[map[string]interface{}]{
-		Data:       courses,
-		Total:      total,
-		Page:       input.Pagination.Page,
-		PageSize:   input.Pagination.PageSize,
-		TotalPages: int((total + int64(input.Pagination.PageSize) - 1) / int64(input.Pagination.PageSize)),
-	}, nil
+	return nil
 }
 
 type UpdateCourseInput struct {
+	Code          *string    `json:"code"`
 	Name          *string    `json:"name"`
 	Remark        *string    `json:"remark"`
 	StudentMaxNum *int       `json:"student_maxnum"`
 	Hours         *int       `json:"hours"`
+	Credits       *float64   `json:"credits"`
+	Category      *string    `json:"category"`
+	Term          *string    `json:"term"`
+	Status        *string    `json:"status"`
+	Tags          *[]string  `json:"tags"`
 	StartDate     *time.Time `json:"start_date"`
+
+	Audiences    *[]model.CourseAudience `json:"audiences"`
+	MinYearLevel *int                    `json:"min_year_level"`
+	MaxYearLevel *int                    `json:"max_year_level"`
 }
 
 func (s *CourseService) UpdateCourse(teacherID string, courseID int64, input UpdateCourseInput) (*model.Course, error) {
@@ -191,6 +271,9 @@ func (s *CourseService) UpdateCourse(teacherID string, courseID int64, input Upd
 	}
 
 	updateData := make(map[string]interface{})
+	if input.Code != nil {
+		updateData["code"] = normalizeCourseCode(*input.Code)
+	}
 	if input.Name != nil {
 		updateData["name"] = *input.Name
 	}
@@ -198,6 +281,9 @@ func (s *CourseService) UpdateCourse(teacherID string, courseID int64, input Upd
 		updateData["remark"] = *input.Remark
 	}
 	if input.StudentMaxNum != nil {
+		if *input.StudentMaxNum <= 0 {
+			return nil, ErrInvalidCourseSize
+		}
 		// 检查新人数是否小于当前报名人数
 		count, err := s.courseRepo.GetEnrollmentCount(courseID)
 		if err != nil {
@@ -210,17 +296,145 @@ func (s *CourseService) UpdateCourse(teacherID string, courseID int64, input Upd
 		updateData["student_max_num"] = *input.StudentMaxNum
 	}
 	if input.Hours != nil {
+		if *input.Hours <= 0 {
+			return nil, ErrInvalidCourseSize
+		}
 		updateData["hours"] = *input.Hours
 	}
+	if input.Credits != nil {
+		updateData["credits"] = *input.Credits
+	}
+	if input.Category != nil {
+		if *input.Category != "" && !isValidCourseCategory(*input.Category) {
+			return nil, ErrInvalidCategory
+		}
+		updateData["category"] = *input.Category
+	}
+	if input.Term != nil {
+		updateData["term"] = *input.Term
+	}
+	if input.Status != nil {
+		if !isValidCourseStatus(*input.Status) {
+			return nil, ErrInvalidCourseStatus
+		}
+		updateData["status"] = *input.Status
+	}
 	if input.StartDate != nil {
-		parsedDate := (*input.StartDate).Unix()
-		updateData["start_date"] = parsedDate
+		updateData["start_date"] = *input.StartDate
+	}
+	if input.MinYearLevel != nil || input.MaxYearLevel != nil {
+		minLevel, maxLevel := course.MinYearLevel, course.MaxYearLevel
+		if input.MinYearLevel != nil {
+			minLevel = *input.MinYearLevel
+		}
+		if input.MaxYearLevel != nil {
+			maxLevel = *input.MaxYearLevel
+		}
+		if err := validateYearLevels(minLevel, maxLevel); err != nil {
+			return nil, err
+		}
+		updateData["min_year_level"] = minLevel
+		updateData["max_year_level"] = maxLevel
+	}
+	var audiences []model.CourseAudience
+	if input.Audiences != nil {
+		if audiences, err = s.normalizeAudiences(*input.Audiences); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := s.courseRepo.Update(course, updateData); err != nil {
 		return nil, err
 	}
 
+	if input.Tags != nil {
+		if err := s.courseRepo.ReplaceTags(courseID, normalizeTags(*input.Tags)); err != nil {
+			return nil, err
+		}
+	}
+	if input.Audiences != nil {
+		if err := s.courseRepo.ReplaceAudiences(courseID, audiences); err != nil {
+			return nil, err
+		}
+	}
+
 	// 返回更新后的课程
-	return s.courseRepo.GetByID(courseID)
+	updated, err := s.courseRepo.GetByID(courseID)
+	if err != nil {
+		return nil, err
+	}
+	s.indexCourse(updated)
+
+	return updated, nil
+}
+
+func isValidCourseStatus(status string) bool {
+	switch status {
+	case model.CourseStatusOpen, model.CourseStatusClosed, model.CourseStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func isValidCourseCategory(category string) bool {
+	switch category {
+	case model.CourseCategoryGeneral, model.CourseCategoryFoundation, model.CourseCategoryCore,
+		model.CourseCategoryElective, model.CourseCategoryPractice:
+		return true
+	}
+	return false
+}
+
+// normalizeCourseCode 课程编号统一去除空白并转为大写
+func normalizeCourseCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// normalizeTags 去除空白和重复的标签
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// validateYearLevels 年级范围为 0 时不限，上下限都设置时下限不能大于上限
+func validateYearLevels(minLevel, maxLevel int) error {
+	if minLevel < 0 || maxLevel < 0 || minLevel > 8 || maxLevel > 8 {
+		return ErrInvalidYearLevel
+	}
+	if minLevel > 0 && maxLevel > 0 && minLevel > maxLevel {
+		return ErrInvalidYearLevel
+	}
+	return nil
+}
+
+// normalizeAudiences 校验专业存在，去除重复专业，修读要求默认为选修
+func (s *CourseService) normalizeAudiences(audiences []model.CourseAudience) ([]model.CourseAudience, error) {
+	seen := make(map[int64]bool, len(audiences))
+	var result []model.CourseAudience
+	for _, a := range audiences {
+		if a.Requirement == "" {
+			a.Requirement = model.RequirementElective
+		}
+		if a.Requirement != model.RequirementRequired && a.Requirement != model.RequirementElective {
+			return nil, ErrInvalidAudience
+		}
+		if seen[a.MajorID] {
+			continue
+		}
+		if _, err := s.orgRepo.GetMajor(a.MajorID); err != nil {
+			return nil, ErrInvalidAudience
+		}
+		seen[a.MajorID] = true
+		result = append(result, model.CourseAudience{MajorID: a.MajorID, Requirement: a.Requirement})
+	}
+	return result, nil
 }
@@ -0,0 +1,505 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+func validCourseInput() CreateCourseInput {
+	return CreateCourseInput{
+		Code:          " cs101 ",
+		Name:          "程序设计基础",
+		StudentMaxNum: 30,
+		Hours:         64,
+		Credits:       4,
+		Category:      model.CourseCategoryFoundation,
+		Tags:          []string{"编程", " 编程 ", ""},
+		StartDate:     time.Now().AddDate(0, 1, 0),
+	}
+}
+
+func TestCreateCourse(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	student := f.user(t, "student", "张三")
+
+	tests := []struct {
+		name      string
+		teacherID string
+		modify    func(in *CreateCourseInput)
+		want      error
+	}{
+		{name: "未登录", teacherID: "", want: ErrUnauthorized},
+		{name: "教师不存在", teacherID: "missing", want: ErrTeacherNotFound},
+		{name: "不是教师", teacherID: student.PublicID, want: ErrTeacherNotFound},
+		{name: "开始日期已过", modify: func(in *CreateCourseInput) { in.StartDate = time.Now().AddDate(0, 0, -1) }, want: ErrPastStartDate},
+		{name: "人数上限为0", modify: func(in *CreateCourseInput) { in.StudentMaxNum = 0 }, want: ErrInvalidCourseSize},
+		{name: "学时为负", modify: func(in *CreateCourseInput) { in.Hours = -1 }, want: ErrInvalidCourseSize},
+		{name: "未知类别", modify: func(in *CreateCourseInput) { in.Category = "unknown" }, want: ErrInvalidCategory},
+		{name: "年级超出范围", modify: func(in *CreateCourseInput) { in.MinYearLevel = 9 }, want: ErrInvalidYearLevel},
+		{name: "年级下限大于上限", modify: func(in *CreateCourseInput) { in.MinYearLevel, in.MaxYearLevel = 3, 2 }, want: ErrInvalidYearLevel},
+		{
+			name:   "专业不存在",
+			modify: func(in *CreateCourseInput) { in.Audiences = []model.CourseAudience{{MajorID: 99}} },
+			want:   ErrInvalidAudience,
+		},
+		{
+			name: "修读要求不正确",
+			modify: func(in *CreateCourseInput) {
+				in.Audiences = []model.CourseAudience{{MajorID: 1, Requirement: "optional"}}
+			},
+			want: ErrInvalidAudience,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			teacherID := tt.teacherID
+			if teacherID == "" && tt.modify != nil {
+				teacherID = teacher.PublicID
+			}
+			input := validCourseInput()
+			if tt.modify != nil {
+				tt.modify(&input)
+			}
+			if _, err := f.courseService().CreateCourse(teacherID, input); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		input := validCourseInput()
+		input.Audiences = []model.CourseAudience{{MajorID: 1}, {MajorID: 1, Requirement: model.RequirementRequired}}
+		course, err := f.courseService().CreateCourse(teacher.PublicID, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if course.Code != "CS101" || len(course.Tags) != 1 || course.Status != model.CourseStatusOpen {
+			t.Fatalf("课程未规范化: %+v", course)
+		}
+		if len(course.Audiences) != 1 || course.Audiences[0].Requirement != model.RequirementElective {
+			t.Fatalf("面向专业未去重: %+v", course.Audiences)
+		}
+	})
+}
+
+func TestSearchCourses(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	other := f.user(t, "teacher", "李老师")
+	student := f.user(t, "student", "张三")
+	for i := 1; i <= 7; i++ {
+		f.course(t, teacher, func(c *model.Course) {
+			c.Hours = 16 * (i%3 + 1)
+			c.StartDate = time.Now().AddDate(0, 0, i)
+		})
+	}
+	full := f.course(t, other, func(c *model.Course) { c.Name = "Linear Algebra"; c.StudentMaxNum = 1 })
+	f.enroll(t, student, full)
+
+	svc := f.courseService()
+	if err := svc.RebuildSearchIndex(); err != nil {
+		t.Fatal(err)
+	}
+	page := model.Pagination{Page: 1, PageSize: 5}
+
+	t.Run("无效状态", func(t *testing.T) {
+		_, err := svc.SearchCourses(model.CourseFilter{Status: "deleted"}, GetCoursesInput{Pagination: page, SortBy: "id", SortOrder: "ASC"})
+		if !errors.Is(err, ErrInvalidCourseStatus) {
+			t.Fatalf("err = %v", err)
+		}
+	})
+
+	t.Run("未知字段", func(t *testing.T) {
+		var fieldErr *model.UnknownFieldError
+		_, err := svc.GetCourses(GetCoursesInput{Pagination: page, SortBy: "secret", SortOrder: "ASC"})
+		if !errors.As(err, &fieldErr) {
+			t.Fatalf("排序字段: err = %v", err)
+		}
+		_, err = svc.GetCourses(GetCoursesInput{Pagination: page, SortBy: "id", SortOrder: "ASC", Fields: []string{"name", "password"}})
+		if !errors.As(err, &fieldErr) || fieldErr.Field != "password" {
+			t.Fatalf("选择字段: err = %v", err)
+		}
+	})
+
+	t.Run("按页码分页和选择字段", func(t *testing.T) {
+		resp, err := svc.GetCourses(GetCoursesInput{
+			Viewer:     student.PublicID,
+			Pagination: model.Pagination{Page: 2, PageSize: 5},
+			SortBy:     "id",
+			SortOrder:  "DESC",
+			Fields:     []string{"name", "is_enrolled"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != 8 || resp.TotalPages != 2 || len(resp.Data) != 3 {
+			t.Fatalf("total=%d pages=%d rows=%d", resp.Total, resp.TotalPages, len(resp.Data))
+		}
+		if len(resp.Data[0]) != 2 || resp.Data[0]["is_enrolled"] != false {
+			t.Fatalf("字段选择不正确: %v", resp.Data[0])
+		}
+	})
+
+	t.Run("过滤条件", func(t *testing.T) {
+		resp, err := svc.SearchCourses(model.CourseFilter{Name: "linear", TeacherName: "李"},
+			GetCoursesInput{Viewer: student.PublicID, Pagination: page, SortBy: "id", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != 1 || resp.Data[0]["teacher_name"] != "李老师" || resp.Data[0]["is_enrolled"] != true {
+			t.Fatalf("rows = %v", resp.Data)
+		}
+
+		resp, err = svc.SearchCourses(model.CourseFilter{TeacherName: "赵"}, GetCoursesInput{Pagination: page, SortBy: "id", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != 0 || resp.Data == nil || len(resp.Data) != 0 {
+			t.Fatalf("没有匹配的教师时应返回空结果: %+v", resp)
+		}
+
+		resp, err = svc.SearchCourses(model.CourseFilter{AvailableOnly: true, MinHours: ptr(32)},
+			GetCoursesInput{Pagination: page, SortBy: "id", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range resp.Data {
+			if row["id"] == full.ID || row["hours"].(int64) < 32 {
+				t.Fatalf("不满足过滤条件: %v", row)
+			}
+		}
+	})
+
+	t.Run("教师改名", func(t *testing.T) {
+		other.Name = "赵老师"
+		if err := f.users.UpdateUser(other, "name"); err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.ReindexTeacherCourses(other.PublicID); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := svc.SearchCourses(model.CourseFilter{TeacherName: "赵"}, GetCoursesInput{Pagination: page, SortBy: "id", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != 1 || resp.Data[0]["id"] != full.ID {
+			t.Fatalf("rows = %v", resp.Data)
+		}
+	})
+
+	t.Run("游标分页", func(t *testing.T) {
+		// 按学时排序有重复值，依靠ID保证翻页不重不漏
+		var seen []int64
+		input := GetCoursesInput{Pagination: model.Pagination{PageSize: 3, Cursor: &model.Cursor{SortBy: "hours", Order: "ASC"}}, SortBy: "hours", SortOrder: "ASC", Fields: []string{"name"}}
+		var pages []*model.Cursor
+		for {
+			resp, err := svc.GetCourses(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, row := range resp.Data {
+				seen = append(seen, row["id"].(int64))
+			}
+			if resp.NextCursor == "" {
+				break
+			}
+			next := &model.Cursor{}
+			if err := testCursors.Decode(resp.NextCursor, next); err != nil {
+				t.Fatal(err)
+			}
+			pages = append(pages, input.Pagination.Cursor)
+			input.Pagination.Cursor = next
+		}
+		if len(seen) != 8 {
+			t.Fatalf("游标翻页得到 %d 行: %v", len(seen), seen)
+		}
+		for i := 1; i < len(seen); i++ {
+			if seen[i] == seen[i-1] {
+				t.Fatalf("重复的行: %v", seen)
+			}
+		}
+
+		// 从第二页向前翻回到第一页
+		second, err := svc.GetCourses(GetCoursesInput{Pagination: model.Pagination{PageSize: 3, Cursor: pages[1]}, SortBy: "hours", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev := &model.Cursor{}
+		if err := testCursors.Decode(second.PrevCursor, prev); err != nil {
+			t.Fatal(err)
+		}
+		first, err := svc.GetCourses(GetCoursesInput{Pagination: model.Pagination{PageSize: 3, Cursor: prev}, SortBy: "hours", SortOrder: "ASC"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range first.Data {
+			if row["id"] != seen[i] {
+				t.Fatalf("向前翻页结果不一致: %v", first.Data)
+			}
+		}
+		if first.PrevCursor != "" {
+			t.Fatal("首页不应有上一页")
+		}
+	})
+
+	t.Run("游标值与排序字段类型不符", func(t *testing.T) {
+		for _, cursor := range []*model.Cursor{
+			{SortBy: "hours", Order: "ASC", Value: "2026-01-01T00:00:00Z", ID: 1},
+			{SortBy: "start_date", Order: "ASC", Value: "48", ID: 1},
+		} {
+			input := GetCoursesInput{Pagination: model.Pagination{PageSize: 3, Cursor: cursor}, SortBy: cursor.SortBy, SortOrder: "ASC"}
+			if _, err := svc.GetCourses(input); err == nil {
+				t.Fatalf("游标 %+v 应当无效", cursor)
+			}
+		}
+	})
+}
+
+func TestGetCourse(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	course := f.course(t, teacher, func(c *model.Course) {
+		c.Tags = []model.CourseTag{{Tag: "b"}, {Tag: "a"}}
+		c.Audiences = []model.CourseAudience{{MajorID: 2}, {MajorID: 1}}
+	})
+	deleted := f.course(t, teacher)
+	if err := f.courses.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	svc := f.courseService()
+
+	for _, id := range []int64{999, deleted.ID} {
+		if _, err := svc.GetCourse("", id); !errors.Is(err, ErrCourseNotFound) {
+			t.Fatalf("课程 %d: err = %v", id, err)
+		}
+	}
+
+	detail, err := svc.GetCourse("", course.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags := detail["tags"].([]string); len(tags) != 2 || tags[0] != "a" {
+		t.Fatalf("tags = %v", tags)
+	}
+	if audiences := detail["audiences"].([]model.CourseAudience); len(audiences) != 2 || audiences[0].MajorID != 1 {
+		t.Fatalf("audiences = %v", audiences)
+	}
+}
+
+func TestDeleteCourse(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	other := f.user(t, "teacher", "李老师")
+	student := f.user(t, "student", "张三")
+
+	enrolled := f.course(t, teacher)
+	f.enroll(t, student, enrolled)
+	started := f.course(t, teacher, func(c *model.Course) { c.StartDate = time.Now().AddDate(0, 0, -1) })
+	empty := f.course(t, teacher)
+
+	tests := []struct {
+		name      string
+		teacherID string
+		courseID  int64
+		want      error
+	}{
+		{name: "未登录", teacherID: "", courseID: empty.ID, want: ErrUnauthorized},
+		{name: "课程不存在", teacherID: teacher.PublicID, courseID: 999, want: ErrCourseNotFound},
+		{name: "不是授课教师", teacherID: other.PublicID, courseID: empty.ID, want: ErrCourseNotFound},
+		{name: "已有学生选课", teacherID: teacher.PublicID, courseID: enrolled.ID, want: ErrCourseHasStudents},
+		{name: "已开课", teacherID: teacher.PublicID, courseID: started.ID, want: ErrCourseStarted},
+		{name: "成功", teacherID: teacher.PublicID, courseID: empty.ID, want: nil},
+		{name: "重复删除", teacherID: teacher.PublicID, courseID: empty.ID, want: ErrCourseNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := f.courseService().DeleteCourse(tt.teacherID, tt.courseID); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateCourse(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	other := f.user(t, "teacher", "李老师")
+	course := f.course(t, teacher, func(c *model.Course) { c.MinYearLevel = 2 })
+	for _, name := range []string{"张三", "李四"} {
+		f.enroll(t, f.user(t, "student", name), course)
+	}
+
+	tests := []struct {
+		name      string
+		teacherID string
+		courseID  int64
+		input     UpdateCourseInput
+		want      error
+	}{
+		{name: "未登录", courseID: course.ID, want: ErrUnauthorized},
+		{name: "课程不存在", teacherID: teacher.PublicID, courseID: 999, want: ErrCourseNotFound},
+		{name: "不是授课教师", teacherID: other.PublicID, courseID: course.ID, want: ErrCourseNotFound},
+		{name: "人数上限为0", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{StudentMaxNum: ptr(0)}, want: ErrInvalidCourseSize},
+		{name: "人数上限小于已选人数", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{StudentMaxNum: ptr(1)}, want: ErrInvalidStudentNum},
+		{name: "学时为0", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{Hours: ptr(0)}, want: ErrInvalidCourseSize},
+		{name: "未知类别", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{Category: ptr("unknown")}, want: ErrInvalidCategory},
+		{name: "未知状态", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{Status: ptr("deleted")}, want: ErrInvalidCourseStatus},
+		{name: "年级上限小于原有下限", teacherID: teacher.PublicID, courseID: course.ID, input: UpdateCourseInput{MaxYearLevel: ptr(1)}, want: ErrInvalidYearLevel},
+		{
+			name:      "专业不存在",
+			teacherID: teacher.PublicID,
+			courseID:  course.ID,
+			input:     UpdateCourseInput{Audiences: &[]model.CourseAudience{{MajorID: 99}}},
+			want:      ErrInvalidAudience,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.courseService().UpdateCourse(tt.teacherID, tt.courseID, tt.input); !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("校验失败不修改课程", func(t *testing.T) {
+		stored, err := f.courses.GetByID(course.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.StudentMaxNum != 10 || stored.Status != model.CourseStatusOpen || stored.MinYearLevel != 2 {
+			t.Fatalf("课程被修改: %+v", stored)
+		}
+	})
+
+	t.Run("成功", func(t *testing.T) {
+		updated, err := f.courseService().UpdateCourse(teacher.PublicID, course.ID, UpdateCourseInput{
+			Code:          ptr(" ma201 "),
+			StudentMaxNum: ptr(2),
+			Status:        ptr(model.CourseStatusClosed),
+			Tags:          &[]string{"数学", "数学"},
+			Audiences:     &[]model.CourseAudience{{MajorID: 2, Requirement: model.RequirementRequired}},
+			MinYearLevel:  ptr(0),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Code != "MA201" || updated.StudentMaxNum != 2 || updated.Status != model.CourseStatusClosed || updated.MinYearLevel != 0 {
+			t.Fatalf("updated = %+v", updated)
+		}
+		if len(updated.Tags) != 1 || len(updated.Audiences) != 1 || updated.Audiences[0].MajorID != 2 {
+			t.Fatalf("tags = %v, audiences = %v", updated.Tags, updated.Audiences)
+		}
+	})
+}
+
+func TestUpdateCourseStartDate(t *testing.T) {
+	db, err := config.InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: "file::memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.AutoMigrate(&model.Course{}, &model.CourseTag{}, &model.CourseAudience{}, &model.Enrollment{}); err != nil {
+		t.Fatal(err)
+	}
+
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	startDate := time.Date(2030, 9, 1, 8, 0, 0, 0, time.Local)
+	for name, repo := range map[string]repository.CourseRepository{
+		"memory": f.courses,
+		"gorm":   repository.NewGormCourseRepository(db),
+	} {
+		t.Run(name, func(t *testing.T) {
+			course := &model.Course{Name: "数据结构", TeacherID: teacher.PublicID, StudentMaxNum: 10, Hours: 48,
+				Status: model.CourseStatusOpen, StartDate: time.Now().AddDate(0, 0, 7)}
+			if err := repo.Create(course); err != nil {
+				t.Fatal(err)
+			}
+
+			svc := NewCourseService(repo, f.users, f.orgs, testCursors, 0)
+			if _, err := svc.UpdateCourse(teacher.PublicID, course.ID, UpdateCourseInput{StartDate: &startDate}); err != nil {
+				t.Fatal(err)
+			}
+			stored, err := repo.GetByID(course.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !stored.StartDate.Equal(startDate) {
+				t.Fatalf("StartDate = %v, want %v", stored.StartDate, startDate)
+			}
+		})
+	}
+}
+
+func TestFullTextSearchEmptyQuery(t *testing.T) {
+	f := newFixture()
+	if _, err := f.courseService().FullTextSearch("", GetCoursesInput{Pagination: model.Pagination{Page: 1, PageSize: 10}}); !errors.Is(err, ErrEmptySearchQuery) {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestFullTextSearch(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "李老师")
+	first := f.course(t, teacher)
+	f.course(t, teacher, func(c *model.Course) { c.Name = "数据结构实验" })
+	s := f.courseService()
+	if err := s.RebuildSearchIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("按投影选择课程字段", func(t *testing.T) {
+		input := GetCoursesInput{Pagination: model.Pagination{Page: 1, PageSize: 10}, Fields: []string{"name", "teacher_name"}}
+		response, err := s.FullTextSearch("数据结构", input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(response.Data) != 2 {
+			t.Fatalf("data = %+v", response.Data)
+		}
+		want := map[string]interface{}{"name": "数据结构", "teacher_name": "李老师"}
+		if got := response.Data[0].Course; len(got) != len(want) || got["name"] != want["name"] || got["teacher_name"] != want["teacher_name"] {
+			t.Errorf("course = %v，应为 %v", got, want)
+		}
+	})
+
+	t.Run("游标指向的课程已不在结果中", func(t *testing.T) {
+		if err := s.DeleteCourse(teacher.PublicID, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		input := GetCoursesInput{Pagination: model.Pagination{PageSize: 1, Cursor: &model.Cursor{SortBy: "score", Order: "DESC", ID: first.ID}}}
+		_, err := s.FullTextSearch("数据结构", input)
+		assertErrorMessage(t, err, pkg.ErrInvalidCursor.Error())
+	})
+}
+
+func TestFullTextSearchRefresh(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "李老师")
+	s := NewCourseService(f.courses, f.users, f.orgs, testCursors, time.Millisecond)
+	if err := s.RebuildSearchIndex(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他实例创建的课程不会写入本实例的索引，重建后才能检索到
+	f.course(t, teacher)
+	time.Sleep(2 * time.Millisecond)
+	response, err := s.FullTextSearch("数据结构", GetCoursesInput{Pagination: model.Pagination{Page: 1, PageSize: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Total != 1 {
+		t.Fatalf("total = %d，重建后应检索到其他实例创建的课程", response.Total)
+	}
+}
@@ -1,24 +1,32 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+var (
+	ErrCourseClosed    = errors.New("课程已停止选课")
+	ErrCourseCancelled = errors.New("课程已取消")
 )
 
 type EnrollmentService struct {
-	repo *repository.EnrollmentRepository
+	repo    repository.EnrollmentRepository
+	cursors *pkg.CursorSigner
 }
 
-func NewEnrollmentService(repo *repository.EnrollmentRepository) *EnrollmentService {
-	return &EnrollmentService{repo: repo}
+func NewEnrollmentService(repo repository.EnrollmentRepository, cursors *pkg.CursorSigner) *EnrollmentService {
+	return &EnrollmentService{repo: repo, cursors: cursors}
 }
 
-func (s *EnrollmentService) Enroll(studentIDCard string, courseID int) error {
+func (s *EnrollmentService) Enroll(studentID string, courseID int) error {
 	// 检查学生是否存在
-	student, err := s.repo.GetStudentByIDCard(studentIDCard)
+	student, err := s.repo.GetStudentByPublicID(studentID)
 	if err != nil {
 		return fmt.Errorf("学生不存在")
 	}
@@ -35,35 +43,46 @@ func (s *EnrollmentService) Enroll(studentIDCard string, courseID int) error {
 		return fmt.Errorf("课程不存在")
 	}
 
+	// 检查课程是否开放选课
+	switch course.Status {
+	case model.CourseStatusOpen:
+	case model.CourseStatusCancelled:
+		return ErrCourseCancelled
+	default:
+		return ErrCourseClosed
+	}
+
 	// 检查课程是否已开始
 	if course.StartDate.Before(time.Now()) {
 		return fmt.Errorf("课程已开始，不能选课")
 	}
 
-	// 检查课程是否已满
-	count, err := s.repo.CountEnrollmentsByCourse(int64(courseID))
-	if err != nil {
-		return fmt.Errorf("无法获取课程人数")
-	}
-	if count >= int64(course.StudentMaxNum) {
-		return fmt.Errorf("课程人数已满")
+	// 检查专业和年级限制
+	if err := checkAudience(course, student, time.Now()); err != nil {
+		return err
 	}
 
 	enrollment := &model.Enrollment{
 		StudentID: int64(student.ID),
 		CourseID:  int64(courseID),
+		Status:    model.EnrollmentStatusEnrolled,
 	}
 
-	if err := s.repo.CreateEnrollment(enrollment); err != nil {
+	// 检查课程是否已满，与写入选课记录在同一个原子操作中完成
+	err = s.repo.CreateEnrollmentWithinCapacity(enrollment)
+	if errors.Is(err, repository.ErrCourseFull) {
+		return err
+	}
+	if err != nil {
 		return fmt.Errorf("选课失败: %v", err)
 	}
 
 	return nil
 }
 
-func (s *EnrollmentService) GetStudentCourses(studentIDCard string, page, pageSize int, sortBy, sortOrder string, fields []string) (*model.PaginatedResponse[map[string]interface{}], error) {
+func (s *EnrollmentService) GetStudentCourses(studentID string, pagination model.Pagination, sortBy, sortOrder string, fields []string) (*model.PaginatedResponse[map[string]interface{}], error) {
 	// 检查学生是否存在
-	student, err := s.repo.GetStudentByIDCard(studentIDCard)
+	student, err := s.repo.GetStudentByPublicID(studentID)
 	if err != nil {
 		return nil, fmt.Errorf("学生不存在")
 	}
@@ -80,33 +99,19 @@ func (s *EnrollmentService) GetStudentCourses(studentIDCard string, page, pageSi
 		courseIDs = append(courseIDs, e.CourseID)
 	}
 
-	// 设置分页
-	pagination := model.Pagination{
-		Page:     page,
-		PageSize: pageSize,
-	}
-
 	// 获取课程列表
-	courses, total, err := s.repo.GetStudentCourses(courseIDs, pagination, sortBy, sortOrder, fields)
+	result, err := s.repo.GetStudentCourses(student.ID, courseIDs, pagination, sortBy, sortOrder, fields)
 	if err != nil {
 		return nil, fmt.Errorf("查询课程失败")
 	}
 
 	// 构建响应
-	response := &model.PaginatedResponse[map[string]interface{}]{
-		Data:       courses,
-		Total:      total,
-		Page:       pagination.Page,
-		PageSize:   pagination.PageSize,
-		TotalPages: int((total + int64(pagination.PageSize) - 1) / int64(pagination.PageSize)),
-	}
-
-	return response, nil
+	return newPaginatedResponse(s.cursors, result, pagination)
 }
 
-func (s *EnrollmentService) DeleteEnrollment(studentIDCard string, courseID int) error {
+func (s *EnrollmentService) DeleteEnrollment(studentID string, courseID int) error {
 	// 检查学生是否存在
-	student, err := s.repo.GetStudentByIDCard(studentIDCard)
+	student, err := s.repo.GetStudentByPublicID(studentID)
 	if err != nil {
 		return fmt.Errorf("学生不存在")
 	}
@@ -135,3 +140,80 @@ func (s *EnrollmentService) DeleteEnrollment(studentIDCard string, courseID int)
 
 	return nil
 }
+
+// RecordResultInput 登记学生的修读结果，Status 为 enrolled 时撤销已登记的结果
+type RecordResultInput struct {
+	StudentID string   `json:"student_id"`
+	Status    string   `json:"status"`
+	Score     *float64 `json:"score"`
+}
+
+// RecordResult 授课教师在课程开始后登记学生是否通过
+func (s *EnrollmentService) RecordResult(teacherID string, courseID int, input RecordResultInput) (*model.Enrollment, error) {
+	course, err := s.repo.GetCourseByID(courseID)
+	if err != nil || course.TeacherID != teacherID {
+		return nil, fmt.Errorf("课程不存在或权限不足")
+	}
+	if course.StartDate.After(time.Now()) {
+		return nil, fmt.Errorf("课程尚未开始，不能登记成绩")
+	}
+
+	switch input.Status {
+	case model.EnrollmentStatusEnrolled, model.EnrollmentStatusPassed, model.EnrollmentStatusFailed:
+	default:
+		return nil, fmt.Errorf("无效的修读状态")
+	}
+	if input.Score != nil && (*input.Score < 0 || *input.Score > 100) {
+		return nil, fmt.Errorf("成绩必须在0到100之间")
+	}
+
+	student, err := s.repo.GetStudentByPublicID(input.StudentID)
+	if err != nil {
+		return nil, fmt.Errorf("学生不存在")
+	}
+	enrollment, err := s.repo.GetEnrollment(student.ID, course.ID)
+	if err != nil {
+		return nil, fmt.Errorf("该学生未选择此课程")
+	}
+
+	enrollment.Status = input.Status
+	enrollment.Score = input.Score
+	if input.Status == model.EnrollmentStatusEnrolled {
+		enrollment.Score = nil
+	}
+	if err := s.repo.UpdateEnrollment(enrollment); err != nil {
+		return nil, fmt.Errorf("登记成绩失败: %v", err)
+	}
+	return enrollment, nil
+}
+
+// checkAudience 课程限定了专业或年级时，学生必须满足全部限制
+func checkAudience(course *model.Course, student *model.User, now time.Time) error {
+	if len(course.Audiences) > 0 {
+		allowed := false
+		for _, a := range course.Audiences {
+			if student.MajorID != nil && *student.MajorID == a.MajorID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("该课程仅面向指定专业的学生")
+		}
+	}
+
+	if course.MinYearLevel == 0 && course.MaxYearLevel == 0 {
+		return nil
+	}
+	level := model.YearLevel(student.EnrollmentYear, now)
+	if level == 0 {
+		return fmt.Errorf("该课程限定年级，请先联系管理员设置所在行政班")
+	}
+	if course.MinYearLevel > 0 && level < course.MinYearLevel {
+		return fmt.Errorf("该课程仅限%d年级及以上学生选修", course.MinYearLevel)
+	}
+	if course.MaxYearLevel > 0 && level > course.MaxYearLevel {
+		return fmt.Errorf("该课程仅限%d年级及以下学生选修", course.MaxYearLevel)
+	}
+	return nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+// failingEnrollmentRepository 让指定的仓库操作失败，用于覆盖存储出错的分支
+type failingEnrollmentRepository struct {
+	*repository.MemoryEnrollmentRepository
+	fail string
+}
+
+var errStorage = errors.New("存储不可用")
+
+func (r *failingEnrollmentRepository) CreateEnrollmentWithinCapacity(enrollment *model.Enrollment) error {
+	if r.fail == "create" {
+		return errStorage
+	}
+	return r.MemoryEnrollmentRepository.CreateEnrollmentWithinCapacity(enrollment)
+}
+
+func (r *failingEnrollmentRepository) DeleteEnrollment(enrollment *model.Enrollment) error {
+	if r.fail == "delete" {
+		return errStorage
+	}
+	return r.MemoryEnrollmentRepository.DeleteEnrollment(enrollment)
+}
+
+func (r *failingEnrollmentRepository) UpdateEnrollment(enrollment *model.Enrollment) error {
+	if r.fail == "update" {
+		return errStorage
+	}
+	return r.MemoryEnrollmentRepository.UpdateEnrollment(enrollment)
+}
+
+func (r *failingEnrollmentRepository) GetStudentEnrollments(studentID int64) ([]model.Enrollment, error) {
+	if r.fail == "list" {
+		return nil, errStorage
+	}
+	return r.MemoryEnrollmentRepository.GetStudentEnrollments(studentID)
+}
+
+func (f *fixture) enrollmentService(fail string) *EnrollmentService {
+	return NewEnrollmentService(&failingEnrollmentRepository{MemoryEnrollmentRepository: f.enrollments, fail: fail}, testCursors)
+}
+
+func TestEnroll(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	student := f.user(t, "student", "张三", func(u *model.User) {
+		u.MajorID = ptr(int64(1))
+		u.EnrollmentYear = enrollmentYearFor(2)
+	})
+	unassigned := f.user(t, "student", "李四")
+
+	open := f.course(t, teacher)
+	enrolled := f.course(t, teacher)
+	f.enroll(t, student, enrolled)
+	started := f.course(t, teacher, func(c *model.Course) { c.StartDate = time.Now().AddDate(0, 0, -1) })
+	otherMajor := f.course(t, teacher, func(c *model.Course) { c.Audiences = []model.CourseAudience{{MajorID: 2}} })
+	ownMajor := f.course(t, teacher, func(c *model.Course) { c.Audiences = []model.CourseAudience{{MajorID: 2}, {MajorID: 1}} })
+	seniors := f.course(t, teacher, func(c *model.Course) { c.MinYearLevel = 3 })
+	freshmen := f.course(t, teacher, func(c *model.Course) { c.MaxYearLevel = 1 })
+	full := f.course(t, teacher, func(c *model.Course) { c.StudentMaxNum = 1 })
+	f.enroll(t, unassigned, full)
+	closed := f.course(t, teacher, func(c *model.Course) { c.Status = model.CourseStatusClosed })
+	cancelled := f.course(t, teacher, func(c *model.Course) { c.Status = model.CourseStatusCancelled })
+
+	tests := []struct {
+		name      string
+		studentID string
+		courseID  int64
+		fail      string
+		want      string
+	}{
+		{name: "学生不存在", studentID: "missing", courseID: open.ID, want: "学生不存在"},
+		{name: "教师不能选课", studentID: teacher.PublicID, courseID: open.ID, want: "学生不存在"},
+		{name: "重复选课", studentID: student.PublicID, courseID: enrolled.ID, want: "已选过该课程"},
+		{name: "课程不存在", studentID: student.PublicID, courseID: 999, want: "课程不存在"},
+		{name: "停止选课", studentID: student.PublicID, courseID: closed.ID, want: ErrCourseClosed.Error()},
+		{name: "课程已取消", studentID: student.PublicID, courseID: cancelled.ID, want: ErrCourseCancelled.Error()},
+		{name: "课程已开始", studentID: student.PublicID, courseID: started.ID, want: "课程已开始，不能选课"},
+		{name: "专业不符", studentID: student.PublicID, courseID: otherMajor.ID, want: "该课程仅面向指定专业的学生"},
+		{name: "未设置专业", studentID: unassigned.PublicID, courseID: otherMajor.ID, want: "该课程仅面向指定专业的学生"},
+		{name: "未设置年级", studentID: unassigned.PublicID, courseID: seniors.ID, want: "该课程限定年级，请先联系管理员设置所在行政班"},
+		{name: "低于最低年级", studentID: student.PublicID, courseID: seniors.ID, want: "该课程仅限3年级及以上学生选修"},
+		{name: "高于最高年级", studentID: student.PublicID, courseID: freshmen.ID, want: "该课程仅限1年级及以下学生选修"},
+		{name: "人数已满", studentID: student.PublicID, courseID: full.ID, want: "课程人数已满"},
+		{name: "保存失败", studentID: student.PublicID, courseID: open.ID, fail: "create", want: "选课失败: 存储不可用"},
+		{name: "成功", studentID: student.PublicID, courseID: open.ID},
+		{name: "专业符合", studentID: student.PublicID, courseID: ownMajor.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.enrollmentService(tt.fail).Enroll(tt.studentID, int(tt.courseID))
+			assertErrorMessage(t, err, tt.want)
+		})
+	}
+
+	if _, err := f.enrollments.GetEnrollment(student.ID, open.ID); err != nil {
+		t.Fatalf("选课记录未保存: %v", err)
+	}
+}
+
+func TestEnrollConcurrent(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	course := f.course(t, teacher, func(c *model.Course) { c.StudentMaxNum = 3 })
+	s := f.enrollmentService("")
+
+	// 同时选课的学生多于剩余名额，只有前 StudentMaxNum 个成功
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		student := f.user(t, "student", fmt.Sprintf("学生%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Enroll(student.PublicID, int(course.ID))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case !errors.Is(err, repository.ErrCourseFull):
+			t.Errorf("err = %v", err)
+		}
+	}
+	count, _ := f.enrollments.CountEnrollmentsByCourse(course.ID)
+	if succeeded != course.StudentMaxNum || count != int64(course.StudentMaxNum) {
+		t.Fatalf("成功 %d 次，选课人数 %d，上限 %d", succeeded, count, course.StudentMaxNum)
+	}
+}
+
+func TestDeleteEnrollment(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	student := f.user(t, "student", "张三")
+
+	course := f.course(t, teacher)
+	f.enroll(t, student, course)
+	notEnrolled := f.course(t, teacher)
+	started := f.course(t, teacher, func(c *model.Course) { c.StartDate = time.Now().AddDate(0, 0, -1) })
+	f.enroll(t, student, started)
+	deleted := f.course(t, teacher)
+	f.enroll(t, student, deleted)
+	if err := f.courses.Delete(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		studentID string
+		courseID  int64
+		fail      string
+		want      string
+	}{
+		{name: "学生不存在", studentID: "missing", courseID: course.ID, want: "学生不存在"},
+		{name: "未选课", studentID: student.PublicID, courseID: notEnrolled.ID, want: "未选择该课程"},
+		{name: "课程已删除", studentID: student.PublicID, courseID: deleted.ID, want: "课程不存在"},
+		{name: "课程已开始", studentID: student.PublicID, courseID: started.ID, want: "课程已开始，不能退选"},
+		{name: "删除失败", studentID: student.PublicID, courseID: course.ID, fail: "delete", want: "退选失败: 存储不可用"},
+		{name: "成功", studentID: student.PublicID, courseID: course.ID},
+		{name: "重复退选", studentID: student.PublicID, courseID: course.ID, want: "未选择该课程"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := f.enrollmentService(tt.fail).DeleteEnrollment(tt.studentID, int(tt.courseID))
+			assertErrorMessage(t, err, tt.want)
+		})
+	}
+}
+
+func TestRecordResult(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	other := f.user(t, "teacher", "李老师")
+	student := f.user(t, "student", "张三")
+	absent := f.user(t, "student", "李四")
+
+	course := f.course(t, teacher, func(c *model.Course) { c.StartDate = time.Now().AddDate(0, 0, -30) })
+	f.enroll(t, student, course)
+	upcoming := f.course(t, teacher)
+
+	passed := RecordResultInput{StudentID: student.PublicID, Status: model.EnrollmentStatusPassed, Score: ptr(88.5)}
+	tests := []struct {
+		name      string
+		teacherID string
+		courseID  int64
+		input     RecordResultInput
+		fail      string
+		want      string
+	}{
+		{name: "课程不存在", teacherID: teacher.PublicID, courseID: 999, input: passed, want: "课程不存在或权限不足"},
+		{name: "不是授课教师", teacherID: other.PublicID, courseID: course.ID, input: passed, want: "课程不存在或权限不足"},
+		{name: "课程未开始", teacherID: teacher.PublicID, courseID: upcoming.ID, input: passed, want: "课程尚未开始，不能登记成绩"},
+		{
+			name: "无效状态", teacherID: teacher.PublicID, courseID: course.ID,
+			input: RecordResultInput{StudentID: student.PublicID, Status: "graduated"}, want: "无效的修读状态",
+		},
+		{
+			name: "成绩超出范围", teacherID: teacher.PublicID, courseID: course.ID,
+			input: RecordResultInput{StudentID: student.PublicID, Status: model.EnrollmentStatusPassed, Score: ptr(100.5)}, want: "成绩必须在0到100之间",
+		},
+		{
+			name: "学生不存在", teacherID: teacher.PublicID, courseID: course.ID,
+			input: RecordResultInput{StudentID: teacher.PublicID, Status: model.EnrollmentStatusPassed}, want: "学生不存在",
+		},
+		{
+			name: "学生未选课", teacherID: teacher.PublicID, courseID: course.ID,
+			input: RecordResultInput{StudentID: absent.PublicID, Status: model.EnrollmentStatusPassed}, want: "该学生未选择此课程",
+		},
+		{name: "保存失败", teacherID: teacher.PublicID, courseID: course.ID, input: passed, fail: "update", want: "登记成绩失败: 存储不可用"},
+		{name: "成功", teacherID: teacher.PublicID, courseID: course.ID, input: passed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.enrollmentService(tt.fail).RecordResult(tt.teacherID, int(tt.courseID), tt.input)
+			assertErrorMessage(t, err, tt.want)
+		})
+	}
+
+	t.Run("撤销成绩", func(t *testing.T) {
+		input := RecordResultInput{StudentID: student.PublicID, Status: model.EnrollmentStatusEnrolled, Score: ptr(60.0)}
+		enrollment, err := f.enrollmentService("").RecordResult(teacher.PublicID, int(course.ID), input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if enrollment.Status != model.EnrollmentStatusEnrolled || enrollment.Score != nil {
+			t.Fatalf("enrollment = %+v", enrollment)
+		}
+	})
+}
+
+func TestGetStudentCourses(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	student := f.user(t, "student", "张三")
+	for i := 0; i < 6; i++ {
+		course := f.course(t, teacher, func(c *model.Course) { c.StartDate = time.Now().AddDate(0, 0, 10-i) })
+		if i != 3 {
+			f.enroll(t, student, course)
+		}
+	}
+	page := model.Pagination{Page: 1, PageSize: 5}
+
+	tests := []struct {
+		name      string
+		studentID string
+		sortBy    string
+		fail      string
+		want      string
+	}{
+		{name: "学生不存在", studentID: "missing", sortBy: "id", want: "学生不存在"},
+		{name: "读取选课记录失败", studentID: student.PublicID, sortBy: "id", fail: "list", want: "获取选课记录失败"},
+		{name: "查询课程失败", studentID: student.PublicID, sortBy: "secret", want: "查询课程失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.enrollmentService(tt.fail).GetStudentCourses(tt.studentID, page, tt.sortBy, "ASC", nil)
+			assertErrorMessage(t, err, tt.want)
+		})
+	}
+
+	t.Run("成功", func(t *testing.T) {
+		resp, err := f.enrollmentService("").GetStudentCourses(student.PublicID, page, "start_date", "ASC", []string{"name", "start_date", "is_enrolled"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Total != 5 || resp.TotalPages != 1 || len(resp.Data) != 5 {
+			t.Fatalf("total=%d pages=%d rows=%d", resp.Total, resp.TotalPages, len(resp.Data))
+		}
+		for i, row := range resp.Data {
+			if len(row) != 3 || row["is_enrolled"] != true {
+				t.Fatalf("row = %v", row)
+			}
+			if i > 0 && row["start_date"].(time.Time).Before(resp.Data[i-1]["start_date"].(time.Time)) {
+				t.Fatal("未按开课日期排序")
+			}
+		}
+	})
+}
+
+// assertErrorMessage want 为空表示应当成功
+func assertErrorMessage(t *testing.T, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Fatalf("unexpected error: %v", err)
+	case want != "" && err == nil:
+		t.Fatalf("expected error %q", want)
+	case want != "" && err.Error() != want:
+		t.Fatalf("err = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestConcurrentEnroll(t *testing.T) {
+	f := newFixture()
+	teacher := f.user(t, "teacher", "王老师")
+	course := f.course(t, teacher, func(c *model.Course) { c.StudentMaxNum = 100 })
+	var students []*model.User
+	for i := 0; i < 20; i++ {
+		students = append(students, f.user(t, "student", "学生"))
+	}
+
+	svc, courses := f.enrollmentService(""), f.courseService()
+	var wg sync.WaitGroup
+	for _, s := range students {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := svc.Enroll(s.PublicID, int(course.ID)); err != nil {
+				t.Error(err)
+			}
+			if _, err := courses.GetCourses(GetCoursesInput{Viewer: s.PublicID, Pagination: model.Pagination{Page: 1, PageSize: 5}, SortBy: "id", SortOrder: "ASC"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count, _ := f.courses.GetEnrollmentCount(course.ID); count != int64(len(students)) {
+		t.Fatalf("count = %d", count)
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"gorm.io/gorm"
+)
+
+// fixture 基于内存仓库的测试数据，三个仓库共享同一个存储
+type fixture struct {
+	users       *repository.MemoryAuthRepository
+	courses     *repository.MemoryCourseRepository
+	enrollments *repository.MemoryEnrollmentRepository
+	orgs        *stubOrganizationRepository
+}
+
+func newFixture() *fixture {
+	store := repository.NewMemoryStore()
+	return &fixture{
+		users:       repository.NewMemoryAuthRepository(store),
+		courses:     repository.NewMemoryCourseRepository(store),
+		enrollments: repository.NewMemoryEnrollmentRepository(store),
+		orgs:        &stubOrganizationRepository{majors: map[int64]bool{1: true, 2: true}},
+	}
+}
+
+// testCursors 测试使用的分页游标签名
+var testCursors, _ = pkg.NewCursorSigner("course-test-cursor-secret-0123456789")
+
+func (f *fixture) courseService() *CourseService {
+	return NewCourseService(f.courses, f.users, f.orgs, testCursors, 0)
+}
+
+func (f *fixture) user(t *testing.T, role, name string, modify ...func(u *model.User)) *model.User {
+	t.Helper()
+	u := &model.User{Name: name, Role: role}
+	for _, fn := range modify {
+		fn(u)
+	}
+	if err := f.users.CreateUser(u); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return u
+}
+
+// course 创建一门一周后开课、可选10人的课程
+func (f *fixture) course(t *testing.T, teacher *model.User, modify ...func(c *model.Course)) *model.Course {
+	t.Helper()
+	c := &model.Course{
+		Name:          "数据结构",
+		TeacherID:     teacher.PublicID,
+		StudentMaxNum: 10,
+		Hours:         48,
+		Credits:       3,
+		Status:        model.CourseStatusOpen,
+		StartDate:     time.Now().AddDate(0, 0, 7),
+	}
+	for _, fn := range modify {
+		fn(c)
+	}
+	if err := f.courses.Create(c); err != nil {
+		t.Fatalf("创建课程失败: %v", err)
+	}
+	return c
+}
+
+func (f *fixture) enroll(t *testing.T, student *model.User, course *model.Course) {
+	t.Helper()
+	err := f.enrollments.CreateEnrollment(&model.Enrollment{StudentID: student.ID, CourseID: course.ID})
+	if err != nil {
+		t.Fatalf("选课失败: %v", err)
+	}
+}
+
+// stubOrganizationRepository 只实现课程服务用到的 GetMajor
+type stubOrganizationRepository struct {
+	repository.OrganizationRepository
+	majors map[int64]bool
+}
+
+func (r *stubOrganizationRepository) GetMajor(id int64) (*model.Major, error) {
+	if !r.majors[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &model.Major{ID: id}, nil
+}
+
+// enrollmentYearFor 当前处于 level 年级的学生的入学年份
+func enrollmentYearFor(level int) int {
+	now := time.Now()
+	year := now.Year()
+	if now.Month() < time.September {
+		year--
+	}
+	return year - level + 1
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
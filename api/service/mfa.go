@@ -0,0 +1,359 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+var (
+	ErrInvalidMFACode    = errors.New("验证码错误")
+	ErrInvalidChallenge  = errors.New("两步验证令牌无效或已过期")
+	ErrMFAAlreadyEnabled = errors.New("已启用两步验证")
+	ErrMFANotEnabled     = errors.New("未启用两步验证")
+	ErrMFASetupRequired  = errors.New("请先设置两步验证")
+	ErrMFAMandatory      = errors.New("当前角色必须启用两步验证")
+	ErrMFACodeRequired   = errors.New("已启用两步验证，请同时提交验证码")
+)
+
+// mfaColumns 两步验证相关的列，修改两步验证状态时只更新这些列
+var mfaColumns = []string{"totp_secret", "totp_last_counter", "mfa_enabled", "recovery_codes"}
+
+const (
+	recoveryCodeCount    = 10
+	recoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz" // Crockford base32，去掉易混淆的 i、l、o、u
+)
+
+// MFAPolicy 两步验证策略
+type MFAPolicy struct {
+	Issuer        string   // 验证器应用中显示的服务名称
+	RequiredRoles []string // 必须启用两步验证的角色
+}
+
+var DefaultMFAPolicy = MFAPolicy{Issuer: "course-system"}
+
+// Required 判断角色是否必须启用两步验证
+func (p MFAPolicy) Required(role string) bool {
+	for _, r := range p.RequiredRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginResult 登录结果。需要两步验证时只返回挑战令牌，凭挑战令牌和验证码换取正式令牌
+type LoginResult struct {
+	Token            string
+	ChallengeToken   string
+	MFASetupRequired bool // 角色要求两步验证但尚未启用，需先用挑战令牌完成设置
+}
+
+// MFASetup 待确认的 TOTP 密钥，客户端可将 URI 渲染为二维码
+type MFASetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"provisioning_uri"`
+}
+
+// MFALoginResult 完成两步验证后的结果，首次启用时附带恢复码
+type MFALoginResult struct {
+	Token         string
+	RecoveryCodes []string
+}
+
+type MFALoginInput struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"` // TOTP 验证码或恢复码
+	IP             string `json:"-"`
+}
+
+// challenge 密码验证通过后决定是否需要第二步
+func (s *AuthService) challenge(user *model.User) (*LoginResult, error) {
+	if !user.MFAEnabled && !s.mfa.Required(user.Role) {
+		return nil, nil
+	}
+
+	token, err := s.tokens.GenerateMFAToken(user.PublicID, user.Role, user.TokenVersion, challengeNonce(user))
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{ChallengeToken: token, MFASetupRequired: !user.MFAEnabled}, nil
+}
+
+// CompleteMFALogin 校验挑战令牌和验证码，签发正式令牌。
+// 强制启用两步验证的用户首次登录时，用待确认密钥生成的验证码同时完成启用。
+func (s *AuthService) CompleteMFALogin(input MFALoginInput) (*MFALoginResult, error) {
+	user, err := s.challengeUser(input.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+	if !user.MFAEnabled && user.TOTPSecret == "" {
+		return nil, ErrMFASetupRequired
+	}
+
+	enabling := !user.MFAEnabled
+	codes, err := s.verifySecondFactor(user, input.Code, input.IP)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.GenerateToken(user.PublicID, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabling {
+		s.audit.Record(AuditEvent{Type: AuditMFAEnabled, Actor: user.PublicID, Subject: user.PublicID, IP: input.IP})
+	}
+	s.audit.Record(AuditEvent{Type: AuditLoginSucceeded, Subject: user.PublicID, IP: input.IP})
+	return &MFALoginResult{Token: token, RecoveryCodes: codes}, nil
+}
+
+// SetupMFAWithChallenge 强制启用两步验证的用户在登录过程中生成密钥
+func (s *AuthService) SetupMFAWithChallenge(challengeToken string) (*MFASetup, error) {
+	user, err := s.challengeUser(challengeToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.beginMFASetup(user)
+}
+
+// SetupMFA 已登录用户生成待确认的 TOTP 密钥，重复调用会替换之前未确认的密钥
+func (s *AuthService) SetupMFA(userID string) (*MFASetup, error) {
+	user, err := s.repo.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.beginMFASetup(user)
+}
+
+// EnableMFA 用待确认密钥生成的验证码启用两步验证，返回恢复码
+func (s *AuthService) EnableMFA(userID, code, ip string) ([]string, error) {
+	user, err := s.repo.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFASetupRequired
+	}
+
+	codes, err := s.verifySecondFactor(user, code, ip)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(AuditEvent{Type: AuditMFAEnabled, Actor: user.PublicID, Subject: user.PublicID, IP: ip})
+	return codes, nil
+}
+
+// DisableMFA 验证通过后关闭两步验证，策略要求的角色不能关闭
+func (s *AuthService) DisableMFA(userID, code, ip string) error {
+	user, err := s.repo.FindByPublicID(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.MFAEnabled {
+		return ErrMFANotEnabled
+	}
+	if s.mfa.Required(user.Role) {
+		return ErrMFAMandatory
+	}
+
+	if _, err := s.verifySecondFactor(user, code, ip); err != nil {
+		return err
+	}
+
+	user.MFAEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastCounter = 0
+	user.RecoveryCodes = ""
+	if err := s.repo.UpdateUser(user, mfaColumns...); err != nil {
+		return err
+	}
+	s.audit.Record(AuditEvent{Type: AuditMFADisabled, Actor: user.PublicID, Subject: user.PublicID, IP: ip})
+	return nil
+}
+
+// RegenerateRecoveryCodes 验证通过后生成新的恢复码，旧恢复码全部失效
+func (s *AuthService) RegenerateRecoveryCodes(userID, code, ip string) ([]string, error) {
+	user, err := s.repo.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.MFAEnabled {
+		return nil, ErrMFANotEnabled
+	}
+
+	if _, err := s.verifySecondFactor(user, code, ip); err != nil {
+		return nil, err
+	}
+
+	codes, err := newRecoveryCodes(user)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateUser(user, mfaColumns...); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *AuthService) challengeUser(challengeToken string) (*model.User, error) {
+	claims, err := s.tokens.ParseMFAToken(challengeToken)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	user, err := s.repo.FindByPublicID(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	// 修改或重置密码后，以及用挑战令牌完成一次验证后，令牌立即失效
+	if claims.Version != user.TokenVersion || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(challengeNonce(user))) != 1 {
+		return nil, ErrInvalidChallenge
+	}
+	return user, nil
+}
+
+// challengeNonce 用户两步验证状态的摘要。成功验证会推进已使用的时间窗口、移除恢复码或启用两步验证，
+// 摘要随之变化，使签发的挑战令牌只能使用一次。不包含 TOTP 密钥，登录过程中生成密钥不影响挑战令牌
+func challengeNonce(user *model.User) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%t:%s", user.TOTPLastCounter, user.MFAEnabled, user.RecoveryCodes)))
+	return hex.EncodeToString(sum[:16])
+}
+
+func (s *AuthService) beginMFASetup(user *model.User) (*MFASetup, error) {
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastCounter = 0
+	if err := s.repo.UpdateUser(user, mfaColumns...); err != nil {
+		return nil, err
+	}
+
+	return &MFASetup{
+		Secret: secret,
+		URI:    pkg.TOTPProvisioningURI(secret, s.mfa.Issuer, user.MaskedIDCard()),
+	}, nil
+}
+
+// verifySecondFactor 校验 TOTP 验证码或恢复码，失败次数计入限流。
+// 用户尚未启用时，校验通过即启用并返回新生成的恢复码。
+func (s *AuthService) verifySecondFactor(user *model.User, code, ip string) ([]string, error) {
+	key := "mfa:" + user.PublicID
+	if err := s.throttle.Begin(key); err != nil {
+		s.audit.Record(AuditEvent{Type: AuditLoginThrottled, Subject: key, IP: ip, Detail: err.Error()})
+		return nil, err
+	}
+
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	usedRecovery := false
+	consumed := false
+	var err error
+	if counter, ok := pkg.VerifyTOTP(user.TOTPSecret, code, time.Now()); ok && counter > user.TOTPLastCounter {
+		// 条件更新保证同一个时间窗口的验证码只能被一个请求使用
+		if consumed, err = s.repo.ConsumeTOTPCounter(user.ID, counter); consumed {
+			user.TOTPLastCounter = counter
+		}
+	} else if old := user.RecoveryCodes; user.MFAEnabled && consumeRecoveryCode(user, code) {
+		// 恢复码在读取后被其他请求用掉或重新生成时替换失败
+		consumed, err = s.repo.ReplaceRecoveryCodes(user.ID, old, user.RecoveryCodes)
+		usedRecovery = consumed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		s.recordMFAFailure(key, ip)
+		return nil, ErrInvalidMFACode
+	}
+
+	var codes []string
+	if !user.MFAEnabled {
+		user.MFAEnabled = true
+		if codes, err = newRecoveryCodes(user); err != nil {
+			return nil, err
+		}
+		if err := s.repo.UpdateUser(user, "mfa_enabled", "recovery_codes"); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.throttle.Reset(key); err != nil {
+		return nil, err
+	}
+
+	if usedRecovery {
+		s.audit.Record(AuditEvent{Type: AuditRecoveryUsed, Subject: user.PublicID, IP: ip})
+	}
+	return codes, nil
+}
+
+func (s *AuthService) recordMFAFailure(key, ip string) {
+	s.audit.Record(AuditEvent{Type: AuditMFAFailed, Subject: key, IP: ip})
+
+	locked, err := s.throttle.RecordFailure(key, s.throttle.policy.MaxAccountFailures)
+	if err != nil {
+		log.Printf("记录两步验证失败出错: %v", err)
+		return
+	}
+	if locked {
+		s.audit.Record(AuditEvent{Type: AuditAccountLocked, Subject: key, IP: ip})
+	}
+}
+
+// newRecoveryCodes 生成一组恢复码，只保存哈希，明文仅返回这一次
+func newRecoveryCodes(user *model.User) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 10)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		for j, b := range raw {
+			raw[j] = recoveryCodeAlphabet[b&31]
+		}
+		codes[i] = string(raw[:5]) + "-" + string(raw[5:])
+		hashes[i] = hashRecoveryCode(string(raw))
+	}
+	user.RecoveryCodes = strings.Join(hashes, ",")
+	return codes, nil
+}
+
+// consumeRecoveryCode 恢复码匹配时将其移除
+func consumeRecoveryCode(user *model.User, code string) bool {
+	if user.RecoveryCodes == "" || code == "" {
+		return false
+	}
+
+	hash := hashRecoveryCode(code)
+	hashes := strings.Split(user.RecoveryCodes, ",")
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			user.RecoveryCodes = strings.Join(append(hashes[:i], hashes[i+1:]...), ",")
+			return true
+		}
+	}
+	return false
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
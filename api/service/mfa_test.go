@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+// mfaUser 创建已启用两步验证的学生，返回 TOTP 密钥和恢复码
+func (f *fixture) mfaUser(t *testing.T) (*model.User, string, []string) {
+	t.Helper()
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var codes []string
+	user := f.userWithPassword(t, "Old2025Pass", func(u *model.User) {
+		u.TOTPSecret = secret
+		u.MFAEnabled = true
+		if codes, err = newRecoveryCodes(u); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return user, secret, codes
+}
+
+// challengeToken 模拟密码验证通过后签发挑战令牌
+func challengeToken(t *testing.T, s *AuthService, user *model.User) *LoginResult {
+	t.Helper()
+	result, err := s.challenge(user)
+	if err != nil || result == nil {
+		t.Fatalf("challenge() = %v, %v", result, err)
+	}
+	return result
+}
+
+func totpCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := pkg.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCompleteMFALogin(t *testing.T) {
+	f := newFixture()
+	s := f.authService(t, nil)
+	user, secret, _ := f.mfaUser(t)
+	first := challengeToken(t, s, user)
+	if first.ChallengeToken == "" || first.MFASetupRequired {
+		t.Fatalf("challenge = %+v", first)
+	}
+
+	_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: first.ChallengeToken, Code: "000000"})
+	assertErrorMessage(t, err, ErrInvalidMFACode.Error())
+
+	code := totpCode(t, secret)
+	result, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: first.ChallengeToken, Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Token == "" || result.RecoveryCodes != nil {
+		t.Errorf("result = %+v", result)
+	}
+
+	t.Run("挑战令牌只能使用一次", func(t *testing.T) {
+		_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: first.ChallengeToken, Code: code})
+		assertErrorMessage(t, err, ErrInvalidChallenge.Error())
+	})
+
+	t.Run("验证码不能重放", func(t *testing.T) {
+		saved, _ := f.users.FindByID(user.ID)
+		second := challengeToken(t, s, saved)
+		_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: second.ChallengeToken, Code: code})
+		assertErrorMessage(t, err, ErrInvalidMFACode.Error())
+	})
+}
+
+func TestCompleteMFALoginConcurrentReplay(t *testing.T) {
+	f := newFixture()
+	s := f.authService(t, nil)
+	user, secret, _ := f.mfaUser(t)
+	code := totpCode(t, secret)
+
+	// 密码验证后各自拿到挑战令牌，再同时提交同一个验证码
+	const n = 5
+	challenges := make([]string, n)
+	for i := range challenges {
+		challenges[i] = challengeToken(t, s, user).ChallengeToken
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for _, challenge := range challenges {
+		wg.Add(1)
+		go func(challenge string) {
+			defer wg.Done()
+			if _, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge, Code: code}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}(challenge)
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("同一个验证码成功 %d 次，应只成功一次", succeeded)
+	}
+}
+
+func TestCompleteMFALoginWithRecoveryCode(t *testing.T) {
+	f := newFixture()
+	s := f.authService(t, nil)
+	user, _, codes := f.mfaUser(t)
+
+	challenge := challengeToken(t, s, user)
+	if _, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: strings.ToUpper(codes[0])}); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := f.users.FindByID(user.ID)
+	if n := len(strings.Split(saved.RecoveryCodes, ",")); n != recoveryCodeCount-1 {
+		t.Errorf("剩余 %d 个恢复码，应为 %d", n, recoveryCodeCount-1)
+	}
+
+	challenge = challengeToken(t, s, saved)
+	_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: codes[0]})
+	assertErrorMessage(t, err, ErrInvalidMFACode.Error())
+
+	if _, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: codes[1]}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompleteMFALoginAfterPasswordChange(t *testing.T) {
+	f := newFixture()
+	s := f.authService(t, nil)
+	user, secret, _ := f.mfaUser(t)
+	challenge := challengeToken(t, s, user)
+
+	user.TokenVersion++
+	if err := f.users.UpdateUser(user, "token_version"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: totpCode(t, secret)})
+	assertErrorMessage(t, err, ErrInvalidChallenge.Error())
+}
+
+func TestForcedMFAEnrollment(t *testing.T) {
+	f := newFixture()
+	s := f.authService(t, nil)
+	s.mfa = MFAPolicy{Issuer: "test", RequiredRoles: []string{"teacher"}}
+	teacher := f.user(t, "teacher", "李老师")
+
+	challenge := challengeToken(t, s, teacher)
+	if !challenge.MFASetupRequired {
+		t.Fatalf("challenge = %+v，应要求先设置两步验证", challenge)
+	}
+
+	_, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: "000000"})
+	assertErrorMessage(t, err, ErrMFASetupRequired.Error())
+
+	setup, err := s.SetupMFAWithChallenge(challenge.ChallengeToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.CompleteMFALogin(MFALoginInput{ChallengeToken: challenge.ChallengeToken, Code: totpCode(t, setup.Secret)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Token == "" || len(result.RecoveryCodes) != recoveryCodeCount {
+		t.Errorf("result = %+v", result)
+	}
+
+	saved, _ := f.users.FindByID(teacher.ID)
+	if !saved.MFAEnabled || saved.TOTPSecret != setup.Secret {
+		t.Errorf("两步验证未启用: %+v", saved)
+	}
+	if err := s.DisableMFA(saved.PublicID, result.RecoveryCodes[0], ""); !errors.Is(err, ErrMFAMandatory) {
+		t.Errorf("DisableMFA() = %v", err)
+	}
+
+	_, err = s.SetupMFAWithChallenge(challenge.ChallengeToken)
+	assertErrorMessage(t, err, ErrInvalidChallenge.Error())
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+var (
+	ErrDepartmentNotFound     = errors.New("院系不存在")
+	ErrMajorNotFound          = errors.New("专业不存在")
+	ErrCohortNotFound         = errors.New("行政班不存在")
+	ErrOrganizationMismatch   = errors.New("专业不属于该院系，或行政班不属于该专业")
+	ErrStudentOnlyAssignment  = errors.New("只有学生可以设置专业和行政班")
+	ErrInvalidOrganizationArg = errors.New("编码和名称不能为空")
+)
+
+type OrganizationService struct {
+	repo  repository.OrganizationRepository
+	users repository.AuthRepository
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository, users repository.AuthRepository) *OrganizationService {
+	return &OrganizationService{repo: repo, users: users}
+}
+
+type CreateDepartmentInput struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func (s *OrganizationService) CreateDepartment(input CreateDepartmentInput) (*model.Department, error) {
+	department := &model.Department{Code: strings.TrimSpace(input.Code), Name: strings.TrimSpace(input.Name)}
+	if department.Code == "" || department.Name == "" {
+		return nil, ErrInvalidOrganizationArg
+	}
+	if err := s.repo.CreateDepartment(department); err != nil {
+		return nil, err
+	}
+	return department, nil
+}
+
+func (s *OrganizationService) ListDepartments() ([]model.Department, error) {
+	return s.repo.ListDepartments()
+}
+
+type CreateMajorInput struct {
+	DepartmentID int64  `json:"department_id"`
+	Code         string `json:"code"`
+	Name         string `json:"name"`
+}
+
+func (s *OrganizationService) CreateMajor(input CreateMajorInput) (*model.Major, error) {
+	if _, err := s.repo.GetDepartment(input.DepartmentID); err != nil {
+		return nil, ErrDepartmentNotFound
+	}
+	major := &model.Major{DepartmentID: input.DepartmentID, Code: strings.TrimSpace(input.Code), Name: strings.TrimSpace(input.Name)}
+	if major.Code == "" || major.Name == "" {
+		return nil, ErrInvalidOrganizationArg
+	}
+	if err := s.repo.CreateMajor(major); err != nil {
+		return nil, err
+	}
+	return major, nil
+}
+
+func (s *OrganizationService) ListMajors(departmentID int64) ([]model.Major, error) {
+	if _, err := s.repo.GetDepartment(departmentID); err != nil {
+		return nil, ErrDepartmentNotFound
+	}
+	return s.repo.ListMajors(departmentID)
+}
+
+type CreateCohortInput struct {
+	MajorID   int64  `json:"major_id"`
+	Year      int    `json:"year"`
+	ClassName string `json:"class_name"`
+}
+
+func (s *OrganizationService) CreateCohort(input CreateCohortInput) (*model.Cohort, error) {
+	if _, err := s.repo.GetMajor(input.MajorID); err != nil {
+		return nil, ErrMajorNotFound
+	}
+	cohort := &model.Cohort{MajorID: input.MajorID, Year: input.Year, ClassName: strings.TrimSpace(input.ClassName)}
+	if cohort.Year < 1950 || cohort.ClassName == "" {
+		return nil, ErrInvalidOrganizationArg
+	}
+	if err := s.repo.CreateCohort(cohort); err != nil {
+		return nil, err
+	}
+	return cohort, nil
+}
+
+func (s *OrganizationService) ListCohorts(majorID int64) ([]model.Cohort, error) {
+	if _, err := s.repo.GetMajor(majorID); err != nil {
+		return nil, ErrMajorNotFound
+	}
+	return s.repo.ListCohorts(majorID)
+}
+
+// AssignOrganizationInput 为 nil 的字段表示清空。只需给出最具体的一级，
+// 上级会自动补全：给出行政班即确定专业和院系，给出专业即确定院系。
+type AssignOrganizationInput struct {
+	DepartmentID *int64 `json:"department_id"`
+	MajorID      *int64 `json:"major_id"`
+	CohortID     *int64 `json:"cohort_id"`
+}
+
+// AssignOrganization 设置用户的院系、专业和行政班。设置行政班时同步学生的入学年份
+func (s *OrganizationService) AssignOrganization(userID string, input AssignOrganizationInput) (*model.User, error) {
+	user, err := s.users.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if (input.MajorID != nil || input.CohortID != nil) && user.Role != "student" {
+		return nil, ErrStudentOnlyAssignment
+	}
+
+	departmentID, majorID := input.DepartmentID, input.MajorID
+	if input.CohortID != nil {
+		cohort, err := s.repo.GetCohort(*input.CohortID)
+		if err != nil {
+			return nil, ErrCohortNotFound
+		}
+		if majorID != nil && *majorID != cohort.MajorID {
+			return nil, ErrOrganizationMismatch
+		}
+		majorID = &cohort.MajorID
+		user.EnrollmentYear = cohort.Year
+	}
+	if majorID != nil {
+		major, err := s.repo.GetMajor(*majorID)
+		if err != nil {
+			return nil, ErrMajorNotFound
+		}
+		if departmentID != nil && *departmentID != major.DepartmentID {
+			return nil, ErrOrganizationMismatch
+		}
+		departmentID = &major.DepartmentID
+	}
+	if departmentID != nil {
+		if _, err := s.repo.GetDepartment(*departmentID); err != nil {
+			return nil, ErrDepartmentNotFound
+		}
+	}
+
+	user.DepartmentID, user.MajorID, user.CohortID = departmentID, majorID, input.CohortID
+	if err := s.users.UpdateUser(user, "department_id", "major_id", "cohort_id", "enrollment_year"); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+// newPaginatedResponse 根据分页模式构建响应，游标模式下对游标签名编码
+func newPaginatedResponse(cursors *pkg.CursorSigner, result *model.PageResult, pagination model.Pagination) (*model.PaginatedResponse[map[string]interface{}], error) {
+	response := &model.PaginatedResponse[map[string]interface{}]{
+		Data:     result.Rows,
+		Total:    result.Total,
+		PageSize: pagination.PageSize,
+	}
+
+	if !pagination.IsCursor() {
+		response.Page = pagination.Page
+		response.TotalPages = int((result.Total + int64(pagination.PageSize) - 1) / int64(pagination.PageSize))
+		return response, nil
+	}
+
+	var err error
+	if result.Next != nil {
+		if response.NextCursor, err = cursors.Encode(result.Next); err != nil {
+			return nil, err
+		}
+	}
+	if result.Prev != nil {
+		if response.PrevCursor, err = cursors.Encode(result.Prev); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
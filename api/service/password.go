@@ -0,0 +1,207 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/notify"
+)
+
+var (
+	ErrWrongPassword     = errors.New("原密码错误")
+	ErrSamePassword      = errors.New("新密码不能与原密码相同")
+	ErrInvalidResetToken = errors.New("重置链接无效或已过期")
+)
+
+// PasswordResetPolicy 找回密码的配置
+type PasswordResetPolicy struct {
+	TokenTTL       time.Duration // 重置令牌有效期
+	ResendInterval time.Duration // 同一用户两次申请的最小间隔，防止被用来发送垃圾邮件
+	URL            string        // 重置页面地址，含一个 %s 占位符用于填入令牌；为空时邮件中只给出令牌
+}
+
+var DefaultPasswordResetPolicy = PasswordResetPolicy{
+	TokenTTL:       30 * time.Minute,
+	ResendInterval: time.Minute,
+}
+
+type PasswordService struct {
+	users     repository.AuthRepository
+	resets    repository.PasswordResetRepository
+	passwords *PasswordValidator
+	tokens    *pkg.TokenSigner
+	notifier  notify.Notifier
+	policy    PasswordResetPolicy
+	audit     AuditLogger
+	auth      *AuthService
+}
+
+func NewPasswordService(users repository.AuthRepository, resets repository.PasswordResetRepository, passwords *PasswordValidator, tokens *pkg.TokenSigner, notifier notify.Notifier, policy PasswordResetPolicy, audit AuditLogger, auth *AuthService) *PasswordService {
+	return &PasswordService{users: users, resets: resets, passwords: passwords, tokens: tokens, notifier: notifier, policy: policy, audit: audit, auth: auth}
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+	Code        string `json:"code"` // 启用了两步验证时必填，TOTP 验证码或恢复码
+	IP          string `json:"-"`
+}
+
+// ChangePassword 校验原密码后修改密码，已签发的令牌全部失效，返回新令牌。
+// 原密码经 ConfirmIdentity 校验，错误次数计入限流，启用了两步验证的用户还需提交验证码
+func (s *PasswordService) ChangePassword(userID string, input ChangePasswordInput) (string, error) {
+	user, err := s.users.FindByPublicID(userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	if err := s.auth.ConfirmIdentity(user, input.OldPassword, input.Code, input.IP); err != nil {
+		return "", err
+	}
+	if input.NewPassword == input.OldPassword {
+		return "", ErrSamePassword
+	}
+	if err := s.passwords.Validate(input.NewPassword, user); err != nil {
+		return "", err
+	}
+
+	oldHash := user.Password
+	if err := setPassword(user, input.NewPassword); err != nil {
+		return "", err
+	}
+	if err := s.users.UpdateUser(user, "password", "token_version"); err != nil {
+		return "", err
+	}
+	s.rememberPassword(user.ID, oldHash)
+
+	s.audit.Record(AuditEvent{Type: AuditPasswordChanged, Actor: user.PublicID, Subject: user.PublicID, IP: input.IP})
+	return s.tokens.GenerateToken(user.PublicID, user.Role, user.TokenVersion)
+}
+
+type ForgotPasswordInput struct {
+	IDCard string `json:"id_card"`
+	IP     string `json:"-"`
+}
+
+// ForgotPassword 向用户的邮箱发送重置令牌。
+// 用户不存在、未设置邮箱或申请过于频繁时同样返回成功，避免泄露账号是否存在。
+func (s *PasswordService) ForgotPassword(input ForgotPasswordInput) error {
+	user, err := s.users.FindByIDCard(normalizeLoginID(input.IDCard))
+	if err != nil || user.Email == "" {
+		return nil
+	}
+
+	now := time.Now()
+	last, err := s.resets.LatestCreatedAt(user.ID)
+	if err != nil {
+		return err
+	}
+	if now.Sub(last) < s.policy.ResendInterval {
+		return nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err = s.resets.Create(&model.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: now.Add(s.policy.TokenTTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.notifier.Send(s.resetMessage(user, token)); err != nil {
+		// 投递失败不返回给调用方，否则会暴露该账号存在且设置了邮箱
+		log.Printf("发送密码重置通知失败: %v", err)
+	}
+	s.audit.Record(AuditEvent{Type: AuditPasswordResetRequested, Subject: user.PublicID, IP: input.IP})
+	return nil
+}
+
+type ResetPasswordInput struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+	IP          string `json:"-"`
+}
+
+// ResetPassword 使用重置令牌设置新密码，令牌只能使用一次，已签发的登录令牌全部失效
+func (s *PasswordService) ResetPassword(input ResetPasswordInput) error {
+	reset, err := s.resets.FindByHash(hashResetToken(input.Token))
+	if err != nil || !reset.Usable(time.Now()) {
+		return ErrInvalidResetToken
+	}
+
+	user, err := s.users.FindByID(reset.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+	if err := s.passwords.Validate(input.NewPassword, user); err != nil {
+		return err
+	}
+
+	oldHash := user.Password
+	if err := setPassword(user, input.NewPassword); err != nil {
+		return err
+	}
+
+	if err := s.resets.Consume(reset, user); err != nil {
+		if err == repository.ErrResetTokenUsed {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	s.rememberPassword(user.ID, oldHash)
+
+	s.audit.Record(AuditEvent{Type: AuditPasswordReset, Subject: user.PublicID, IP: input.IP})
+	return nil
+}
+
+// setPassword 保存新密码的哈希，并使已签发的令牌失效
+func setPassword(user *model.User, password string) error {
+	hash, err := pkg.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	user.TokenVersion++
+	return nil
+}
+
+// rememberPassword 记录旧密码的哈希用于防止重复使用，失败时不影响已完成的修改
+func (s *PasswordService) rememberPassword(userID int64, oldHash string) {
+	if err := s.passwords.Remember(userID, oldHash); err != nil {
+		log.Printf("记录历史密码失败: %v", err)
+	}
+}
+
+func (s *PasswordService) resetMessage(user *model.User, token string) notify.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s，您好：\n\n我们收到了重置您账号密码的申请。", user.Name)
+	if s.policy.URL != "" {
+		fmt.Fprintf(&b, "请打开以下链接设置新密码：\n\n%s\n\n", fmt.Sprintf(s.policy.URL, token))
+	} else {
+		fmt.Fprintf(&b, "请使用以下重置令牌设置新密码：\n\n%s\n\n", token)
+	}
+	fmt.Fprintf(&b, "%d分钟内有效且只能使用一次。如果不是您本人操作，请忽略本邮件。\n", int(s.policy.TokenTTL.Minutes()))
+
+	return notify.Message{To: user.Email, Subject: "重置密码", Body: b.String()}
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
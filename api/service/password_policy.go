@@ -0,0 +1,182 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/breach"
+)
+
+var (
+	ErrPasswordBreached = errors.New("该密码已出现在公开泄露的密码库中，请更换")
+	ErrPasswordReused   = errors.New("不能使用最近用过的密码")
+)
+
+// PasswordPolicy 密码规则
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length"`
+	MaxLength     int  `json:"max_length"` // 按字符计，0 表示不限制
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	// DisallowPersonalInfo 禁止包含证件号码、出生日期或姓名
+	DisallowPersonalInfo bool `json:"disallow_personal_info"`
+	// HistorySize 不能与最近几次使用过的密码相同（含当前密码），0 表示不检查
+	HistorySize int `json:"history_size"`
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength:            8,
+	MaxLength:            64,
+	RequireUpper:         true,
+	RequireLower:         true,
+	RequireDigit:         true,
+	DisallowPersonalInfo: true,
+	HistorySize:          5,
+}
+
+// PasswordValidator 按策略校验新密码，并记录历史密码
+type PasswordValidator struct {
+	policy   PasswordPolicy
+	breached breach.Checker
+	history  repository.PasswordHistoryRepository
+}
+
+func NewPasswordValidator(policy PasswordPolicy, breached breach.Checker, history repository.PasswordHistoryRepository) *PasswordValidator {
+	return &PasswordValidator{policy: policy, breached: breached, history: history}
+}
+
+// Validate 校验 user 的新密码。注册时 user 尚未保存，不检查历史密码。
+// 不符合规则时返回的错误包装了 ErrInvalidPassword，可用 errors.Is 判断。
+func (v *PasswordValidator) Validate(password string, user *model.User) error {
+	if err := v.policy.check(password, user); err != nil {
+		return fmt.Errorf("%w：%s", ErrInvalidPassword, err)
+	}
+
+	breached, err := v.breached.Breached(password)
+	if err != nil {
+		return err
+	}
+	if breached {
+		return fmt.Errorf("%w：%s", ErrInvalidPassword, ErrPasswordBreached)
+	}
+
+	if user.ID == 0 || v.policy.HistorySize <= 0 {
+		return nil
+	}
+	reused, err := v.reused(password, user)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return fmt.Errorf("%w：%s", ErrInvalidPassword, ErrPasswordReused)
+	}
+	return nil
+}
+
+// Remember 密码修改成功后记录旧密码的哈希，即修改前 user.Password 的值
+func (v *PasswordValidator) Remember(userID int64, oldHash string) error {
+	// 当前密码直接比对，历史表只需保存之前的 HistorySize-1 个
+	keep := v.policy.HistorySize - 1
+	if keep <= 0 || oldHash == "" {
+		return nil
+	}
+	return v.history.Add(&model.PasswordHistory{UserID: userID, Hash: oldHash}, keep)
+}
+
+func (v *PasswordValidator) reused(password string, user *model.User) (bool, error) {
+	if pkg.CheckPassword(user.Password, password) {
+		return true, nil
+	}
+	if v.policy.HistorySize <= 1 {
+		return false, nil
+	}
+
+	entries, err := v.history.Recent(user.ID, v.policy.HistorySize-1)
+	if err != nil {
+		return false, err
+	}
+	for _, e := range entries {
+		if pkg.CheckPassword(e.Hash, password) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// check 校验长度、字符类别和个人信息，返回不带前缀的原因
+func (p PasswordPolicy) check(password string, user *model.User) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("长度不能少于%d位", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("长度不能超过%d位", p.MaxLength)
+	}
+	if len(password) > pkg.MaxPasswordBytes {
+		return fmt.Errorf("长度不能超过%d字节", pkg.MaxPasswordBytes)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	var missing []string
+	if p.RequireUpper && !upper {
+		missing = append(missing, "大写字母")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "小写字母")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "数字")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("必须包含至少一个%s", strings.Join(missing, "、一个"))
+	}
+
+	if p.DisallowPersonalInfo && user != nil {
+		lowered := strings.ToLower(password)
+		for _, fragment := range personalFragments(user) {
+			if strings.Contains(lowered, fragment) {
+				return errors.New("不能包含证件号码、出生日期或姓名")
+			}
+		}
+	}
+	return nil
+}
+
+// personalFragments 密码中不应出现的个人信息片段
+func personalFragments(user *model.User) []string {
+	var fragments []string
+	idCard := strings.ToLower(user.IDCard)
+	if len(idCard) >= 6 {
+		fragments = append(fragments, idCard[len(idCard)-6:])
+	}
+	if user.DocumentType == model.DocumentTypeIDCard && len(idCard) == 18 {
+		fragments = append(fragments, idCard[6:14]) // 出生日期
+	}
+	if name := strings.ToLower(strings.TrimSpace(user.Name)); utf8.RuneCountInString(name) >= 2 {
+		fragments = append(fragments, name)
+	}
+	return fragments
+}
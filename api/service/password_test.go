@@ -0,0 +1,191 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/breach"
+)
+
+// stubPasswordHistory 只在内存中保存历史密码
+type stubPasswordHistory struct {
+	entries []model.PasswordHistory // 新的在前
+}
+
+func (r *stubPasswordHistory) Recent(userID int64, limit int) ([]model.PasswordHistory, error) {
+	var result []model.PasswordHistory
+	for _, e := range r.entries {
+		if e.UserID == userID && len(result) < limit {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *stubPasswordHistory) Add(entry *model.PasswordHistory, keep int) error {
+	r.entries = append([]model.PasswordHistory{*entry}, r.entries...)
+	return nil
+}
+
+// stubPasswordResets 只实现重置密码用到的查找和使用令牌
+type stubPasswordResets struct {
+	repository.PasswordResetRepository
+	users  *repository.MemoryAuthRepository
+	tokens map[string]*model.PasswordResetToken
+}
+
+func (r *stubPasswordResets) FindByHash(hash string) (*model.PasswordResetToken, error) {
+	token, ok := r.tokens[hash]
+	if !ok {
+		return nil, errors.New("令牌不存在")
+	}
+	return token, nil
+}
+
+func (r *stubPasswordResets) Consume(token *model.PasswordResetToken, user *model.User) error {
+	if token.UsedAt != nil {
+		return repository.ErrResetTokenUsed
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	return r.users.UpdateUser(user, "password", "token_version")
+}
+
+type discardAudit struct{}
+
+func (discardAudit) Record(AuditEvent) {}
+
+func testTokenSigner(t *testing.T) *pkg.TokenSigner {
+	t.Helper()
+	tokens, err := pkg.NewTokenSigner("test", pkg.NewHMACKey("test", []byte("password-test-secret-0123456789ab")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens
+}
+
+// authService 使用内存中的登录失败记录。不退避，便于连续提交错误和正确的密码或验证码，失败次数仍会导致锁定
+func (f *fixture) authService(t *testing.T, passwords *PasswordValidator) *AuthService {
+	t.Helper()
+	policy := DefaultLoginThrottlePolicy
+	policy.BaseDelay = 0
+	throttle := NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(time.Hour), policy)
+	return NewAuthService(f.users, testTokenSigner(t), throttle, passwords, DefaultMFAPolicy, discardAudit{})
+}
+
+func (f *fixture) passwordService(t *testing.T, history *stubPasswordHistory, resets *stubPasswordResets) *PasswordService {
+	t.Helper()
+	validator := NewPasswordValidator(DefaultPasswordPolicy, breach.NopChecker{}, history)
+	return NewPasswordService(f.users, resets, validator, testTokenSigner(t), nil, DefaultPasswordResetPolicy, discardAudit{}, f.authService(t, validator))
+}
+
+// userWithPassword 创建保存了 password 哈希的学生
+func (f *fixture) userWithPassword(t *testing.T, password string, modify ...func(u *model.User)) *model.User {
+	t.Helper()
+	hash, err := pkg.HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.user(t, "student", "张三", append([]func(u *model.User){func(u *model.User) { u.Password = hash }}, modify...)...)
+}
+
+func TestChangePassword(t *testing.T) {
+	f := newFixture()
+	history := &stubPasswordHistory{}
+	s := f.passwordService(t, history, nil)
+	user := f.userWithPassword(t, "Old2025Pass")
+	oldHash := user.Password
+
+	t.Run("原密码错误", func(t *testing.T) {
+		_, err := s.ChangePassword(user.PublicID, ChangePasswordInput{OldPassword: oldHash, NewPassword: "New2025Pass"})
+		assertErrorMessage(t, err, ErrWrongPassword.Error())
+	})
+
+	t.Run("保存新密码的哈希", func(t *testing.T) {
+		if _, err := s.ChangePassword(user.PublicID, ChangePasswordInput{OldPassword: "Old2025Pass", NewPassword: "New2025Pass"}); err != nil {
+			t.Fatal(err)
+		}
+		saved, _ := f.users.FindByID(user.ID)
+		if saved.Password == "New2025Pass" || !pkg.CheckPassword(saved.Password, "New2025Pass") {
+			t.Errorf("Password = %q，应为新密码的哈希", saved.Password)
+		}
+		if saved.TokenVersion != user.TokenVersion+1 {
+			t.Errorf("TokenVersion = %d", saved.TokenVersion)
+		}
+		if len(history.entries) != 1 || history.entries[0].Hash != oldHash {
+			t.Errorf("历史密码应直接保存修改前的哈希: %+v", history.entries)
+		}
+	})
+
+	t.Run("不能改回最近用过的密码", func(t *testing.T) {
+		_, err := s.ChangePassword(user.PublicID, ChangePasswordInput{OldPassword: "New2025Pass", NewPassword: "Old2025Pass"})
+		if !errors.Is(err, ErrInvalidPassword) || !strings.Contains(err.Error(), ErrPasswordReused.Error()) {
+			t.Fatalf("err = %v", err)
+		}
+	})
+
+	t.Run("原密码连续错误后锁定", func(t *testing.T) {
+		for i := 0; i < DefaultLoginThrottlePolicy.MaxAccountFailures; i++ {
+			s.ChangePassword(user.PublicID, ChangePasswordInput{OldPassword: "Guess2025Pass", NewPassword: "Other2025Pass"})
+		}
+		_, err := s.ChangePassword(user.PublicID, ChangePasswordInput{OldPassword: "New2025Pass", NewPassword: "Other2025Pass"})
+		var blocked *LoginBlockedError
+		if !errors.As(err, &blocked) || !blocked.Locked {
+			t.Fatalf("err = %v，应当已锁定", err)
+		}
+	})
+}
+
+func TestChangePasswordWithMFA(t *testing.T) {
+	f := newFixture()
+	s := f.passwordService(t, &stubPasswordHistory{}, nil)
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := f.userWithPassword(t, "Old2025Pass", func(u *model.User) {
+		u.TOTPSecret = secret
+		u.MFAEnabled = true
+	})
+
+	input := ChangePasswordInput{OldPassword: "Old2025Pass", NewPassword: "New2025Pass"}
+	_, err = s.ChangePassword(user.PublicID, input)
+	assertErrorMessage(t, err, ErrMFACodeRequired.Error())
+
+	if input.Code, err = pkg.TOTPCode(secret, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ChangePassword(user.PublicID, input); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	f := newFixture()
+	history := &stubPasswordHistory{}
+	user := f.userWithPassword(t, "Old2025Pass")
+	oldHash := user.Password
+	resets := &stubPasswordResets{users: f.users, tokens: map[string]*model.PasswordResetToken{
+		hashResetToken("token"): {UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)},
+	}}
+	s := f.passwordService(t, history, resets)
+
+	if err := s.ResetPassword(ResetPasswordInput{Token: "token", NewPassword: "New2025Pass"}); err != nil {
+		t.Fatal(err)
+	}
+	saved, _ := f.users.FindByID(user.ID)
+	if saved.Password == "New2025Pass" || !pkg.CheckPassword(saved.Password, "New2025Pass") {
+		t.Errorf("Password = %q，应为新密码的哈希", saved.Password)
+	}
+	if len(history.entries) != 1 || history.entries[0].Hash != oldHash {
+		t.Errorf("历史密码应直接保存重置前的哈希: %+v", history.entries)
+	}
+
+	err := s.ResetPassword(ResetPasswordInput{Token: "token", NewPassword: "Other2025Pass"})
+	assertErrorMessage(t, err, ErrInvalidResetToken.Error())
+}
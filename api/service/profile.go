@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+var (
+	ErrInvalidName      = errors.New("姓名不能为空且不能超过50个字符")
+	ErrInvalidEmail     = errors.New("邮箱格式不正确")
+	ErrInvalidPhone     = errors.New("手机号码格式不正确")
+	ErrInvalidLanguage  = errors.New("不支持的语言")
+	ErrInvalidAvatarURL = errors.New("头像地址必须是 http 或 https 链接")
+	ErrTeacherOnlyField = errors.New("只有教师可以填写简介和答疑时间")
+
+	ErrCurrentPasswordRequired = errors.New("请输入当前密码")
+)
+
+// SupportedLanguages 界面语言
+var SupportedLanguages = []string{"zh-CN", "en-US"}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	phonePattern = regexp.MustCompile(`^1[3-9]\d{9}$`) // 中国大陆手机号
+)
+
+type ProfileService struct {
+	users   repository.AuthRepository
+	orgs    repository.OrganizationRepository
+	courses *CourseService
+	auth    *AuthService
+}
+
+func NewProfileService(users repository.AuthRepository, orgs repository.OrganizationRepository, courses *CourseService, auth *AuthService) *ProfileService {
+	return &ProfileService{users: users, orgs: orgs, courses: courses, auth: auth}
+}
+
+// GetProfile 获取当前用户的资料
+func (s *ProfileService) GetProfile(userID string) (*model.User, error) {
+	user, err := s.users.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateProfileInput 为 nil 的字段不修改，空字符串表示清空可选字段。
+// 院系、专业和入学年份由管理员维护，不能自行修改。
+// 邮箱用于找回密码，修改时需要提交当前密码，启用了两步验证的用户还需提交验证码。
+type UpdateProfileInput struct {
+	Name        *string `json:"name"`
+	Email       *string `json:"email"`
+	Phone       *string `json:"phone"`
+	Language    *string `json:"language"`
+	AvatarURL   *string `json:"avatar_url"`
+	Bio         *string `json:"bio"`
+	OfficeHours *string `json:"office_hours"`
+
+	CurrentPassword string `json:"current_password"`
+	Code            string `json:"code"` // TOTP 验证码或恢复码
+	IP              string `json:"-"`
+}
+
+// UpdateProfile 校验并修改当前用户的资料
+func (s *ProfileService) UpdateProfile(userID string, input UpdateProfileInput) (*model.User, error) {
+	user, err := s.users.FindByPublicID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	oldName := user.Name
+
+	// 会话被盗用时不能借修改邮箱接管账号
+	if input.Email != nil {
+		email, err := normalizeEmail(*input.Email)
+		if err != nil {
+			return nil, err
+		}
+		if email != user.Email {
+			if err := s.auth.ConfirmIdentity(user, input.CurrentPassword, input.Code, input.IP); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	columns, err := applyProfileInput(user, input)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return user, nil
+	}
+	if err := s.users.UpdateUser(user, columns...); err != nil {
+		return nil, err
+	}
+
+	// 教师改名后更新其课程在全文索引中的教师姓名
+	if user.Role == "teacher" && user.Name != oldName {
+		if err := s.courses.ReindexTeacherCourses(user.PublicID); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// TeacherProfile 教师公开主页
+type TeacherProfile struct {
+	Teacher    *model.User
+	Department *model.Department // 未分配院系时为 nil
+	Courses    *model.PaginatedResponse[map[string]interface{}]
+}
+
+// GetTeacherProfile 获取教师公开资料及其讲授的课程
+func (s *ProfileService) GetTeacherProfile(teacherID string, input GetCoursesInput) (*TeacherProfile, error) {
+	teacher, err := s.users.FindByPublicID(teacherID)
+	if err != nil || teacher.Role != "teacher" {
+		return nil, ErrTeacherNotFound
+	}
+
+	courses, err := s.courses.SearchCourses(model.CourseFilter{TeacherID: teacher.PublicID}, input)
+	if err != nil {
+		return nil, err
+	}
+	profile := &TeacherProfile{Teacher: teacher, Courses: courses}
+	if teacher.DepartmentID != nil {
+		if profile.Department, err = s.orgs.GetDepartment(*teacher.DepartmentID); err != nil {
+			profile.Department = nil
+		}
+	}
+	return profile, nil
+}
+
+// applyProfileInput 校验并修改 user，返回请求中出现的字段对应的列
+func applyProfileInput(user *model.User, input UpdateProfileInput) ([]string, error) {
+	var columns []string
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if name == "" || utf8.RuneCountInString(name) > 50 {
+			return nil, ErrInvalidName
+		}
+		user.Name = name
+		columns = append(columns, "name")
+	}
+	if input.Email != nil {
+		email, err := normalizeEmail(*input.Email)
+		if err != nil {
+			return nil, err
+		}
+		user.Email = email
+		columns = append(columns, "email")
+	}
+	if input.Phone != nil {
+		phone, err := normalizePhone(*input.Phone)
+		if err != nil {
+			return nil, err
+		}
+		user.Phone = phone
+		columns = append(columns, "phone")
+	}
+	if input.Language != nil {
+		if !isSupportedLanguage(*input.Language) {
+			return nil, ErrInvalidLanguage
+		}
+		user.Language = *input.Language
+		columns = append(columns, "language")
+	}
+	if input.AvatarURL != nil {
+		avatar := strings.TrimSpace(*input.AvatarURL)
+		if avatar != "" && !isHTTPURL(avatar) {
+			return nil, ErrInvalidAvatarURL
+		}
+		user.AvatarURL = avatar
+		columns = append(columns, "avatar_url")
+	}
+	if input.Bio != nil || input.OfficeHours != nil {
+		if user.Role != "teacher" {
+			return nil, ErrTeacherOnlyField
+		}
+		if input.Bio != nil {
+			user.Bio = strings.TrimSpace(*input.Bio)
+			columns = append(columns, "bio")
+		}
+		if input.OfficeHours != nil {
+			user.OfficeHours = strings.TrimSpace(*input.OfficeHours)
+			columns = append(columns, "office_hours")
+		}
+	}
+	return columns, nil
+}
+
+// normalizeEmail 去掉首尾空格并转为小写，空字符串表示清空
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email != "" && (len(email) > 254 || !emailPattern.MatchString(email)) {
+		return "", ErrInvalidEmail
+	}
+	return email, nil
+}
+
+// normalizePhone 去掉空格、连字符和 +86 前缀
+func normalizePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "").Replace(phone)
+	phone = strings.TrimPrefix(strings.TrimPrefix(phone, "+86"), "0086")
+	if phone != "" && !phonePattern.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+func isSupportedLanguage(lang string) bool {
+	for _, l := range SupportedLanguages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
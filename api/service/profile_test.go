@@ -0,0 +1,85 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+)
+
+func (f *fixture) profileService(t *testing.T) *ProfileService {
+	t.Helper()
+	return NewProfileService(f.users, f.orgs, f.courseService(), f.authService(t, nil))
+}
+
+func TestUpdateProfileEmail(t *testing.T) {
+	f := newFixture()
+	s := f.profileService(t)
+	user := f.userWithPassword(t, "Pass2025word", func(u *model.User) { u.Email = "old@example.com" })
+
+	tests := []struct {
+		name  string
+		input UpdateProfileInput
+		want  string
+	}{
+		{name: "未提交当前密码", input: UpdateProfileInput{Email: ptr("new@example.com")}, want: ErrCurrentPasswordRequired.Error()},
+		{name: "当前密码错误", input: UpdateProfileInput{Email: ptr("new@example.com"), CurrentPassword: "Wrong2025word"}, want: ErrWrongPassword.Error()},
+		{name: "邮箱格式先于密码校验", input: UpdateProfileInput{Email: ptr("new")}, want: ErrInvalidEmail.Error()},
+		{name: "邮箱未变化时不需要密码", input: UpdateProfileInput{Email: ptr(" OLD@example.com "), Name: ptr("李四")}},
+		{name: "清空邮箱也需要密码", input: UpdateProfileInput{Email: ptr("")}, want: ErrCurrentPasswordRequired.Error()},
+		{name: "修改邮箱", input: UpdateProfileInput{Email: ptr("new@example.com"), CurrentPassword: "Pass2025word"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := f.users.FindByID(user.ID)
+			_, err := s.UpdateProfile(user.PublicID, tt.input)
+			assertErrorMessage(t, err, tt.want)
+			saved, _ := f.users.FindByID(user.ID)
+			if tt.want != "" && saved.Email != before.Email {
+				t.Errorf("校验失败后邮箱被修改为 %q", saved.Email)
+			}
+		})
+	}
+	if saved, _ := f.users.FindByID(user.ID); saved.Email != "new@example.com" || saved.Name != "李四" {
+		t.Errorf("Email = %q, Name = %q", saved.Email, saved.Name)
+	}
+}
+
+func TestUpdateProfileEmailWithMFA(t *testing.T) {
+	f := newFixture()
+	s := f.profileService(t)
+	secret, err := pkg.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := f.userWithPassword(t, "Pass2025word", func(u *model.User) {
+		u.Email = "old@example.com"
+		u.TOTPSecret = secret
+		u.MFAEnabled = true
+	})
+	input := UpdateProfileInput{Email: ptr("new@example.com"), CurrentPassword: "Pass2025word"}
+
+	_, err = s.UpdateProfile(user.PublicID, input)
+	assertErrorMessage(t, err, ErrMFACodeRequired.Error())
+
+	input.Code = "000000"
+	if code, _ := pkg.TOTPCode(secret, time.Now()); code == input.Code {
+		input.Code = "000001"
+	}
+	if _, err = s.UpdateProfile(user.PublicID, input); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("err = %v", err)
+	}
+
+	input.Code, err = pkg.TOTPCode(secret, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateProfile(user.PublicID, input); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := f.users.FindByID(user.ID); saved.Email != "new@example.com" || saved.TOTPLastCounter == 0 {
+		t.Errorf("Email = %q, TOTPLastCounter = %d", saved.Email, saved.TOTPLastCounter)
+	}
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+var (
+	ErrProgramNotFound  = errors.New("该专业尚未制定培养方案")
+	ErrInvalidProgram   = errors.New("培养方案不正确")
+	ErrStudentNotFound  = errors.New("学生不存在")
+	ErrMajorNotAssigned = errors.New("尚未分配专业，无法进行学业审核")
+)
+
+// 学业审核中各项要求的完成情况
+const (
+	AuditSatisfied  = "satisfied"   // 已修读通过的课程已满足要求
+	AuditInProgress = "in_progress" // 加上在修课程后可以满足要求
+	AuditMissing    = "missing"     // 加上在修课程仍不满足
+)
+
+type ProgramService struct {
+	programs    repository.ProgramRepository
+	orgs        repository.OrganizationRepository
+	enrollments repository.EnrollmentRepository
+}
+
+func NewProgramService(programs repository.ProgramRepository, orgs repository.OrganizationRepository, enrollments repository.EnrollmentRepository) *ProgramService {
+	return &ProgramService{programs: programs, orgs: orgs, enrollments: enrollments}
+}
+
+type ElectiveGroupInput struct {
+	Name       string   `json:"name"`
+	MinCredits float64  `json:"min_credits"`
+	Courses    []string `json:"courses"` // 课程编号
+}
+
+// SaveProgramInput 培养方案，课程均以课程编号表示
+type SaveProgramInput struct {
+	Name            string               `json:"name"`
+	TotalCredits    float64              `json:"total_credits"`
+	RequiredCourses []string             `json:"required_courses"`
+	ElectiveGroups  []ElectiveGroupInput `json:"elective_groups"`
+	CategoryCredits map[string]float64   `json:"category_credits"` // 课程类别 => 最低学分
+}
+
+// GetProgram 获取专业的培养方案
+func (s *ProgramService) GetProgram(majorID int64) (*model.Program, error) {
+	program, err := s.programs.GetByMajor(majorID)
+	if err != nil {
+		return nil, ErrProgramNotFound
+	}
+	return program, nil
+}
+
+// SaveProgram 设置专业的培养方案，已有方案时整体替换
+func (s *ProgramService) SaveProgram(majorID int64, input SaveProgramInput) (*model.Program, error) {
+	if _, err := s.orgs.GetMajor(majorID); err != nil {
+		return nil, ErrMajorNotFound
+	}
+
+	program, err := buildProgram(majorID, input)
+	if err != nil {
+		return nil, fmt.Errorf("%w：%s", ErrInvalidProgram, err)
+	}
+	if err := s.programs.Save(program); err != nil {
+		return nil, err
+	}
+	return s.programs.GetByMajor(majorID)
+}
+
+// CreditProgress 学分要求的完成情况
+type CreditProgress struct {
+	Required   float64 `json:"required"`
+	Completed  float64 `json:"completed"`   // 已通过课程的学分
+	InProgress float64 `json:"in_progress"` // 在修课程的学分
+	Status     string  `json:"status"`
+}
+
+type RequiredCourseAudit struct {
+	CourseCode string `json:"course_code"`
+	Name       string `json:"name,omitempty"` // 未修读过时为空
+	Status     string `json:"status"`
+}
+
+type ElectiveGroupAudit struct {
+	Name string `json:"name"`
+	CreditProgress
+	Courses []string `json:"courses"` // 已计入的课程编号
+}
+
+type CategoryAudit struct {
+	Category string `json:"category"`
+	CreditProgress
+}
+
+// DegreeAudit 学生修读情况与培养方案的对照结果
+type DegreeAudit struct {
+	ProgramID       int64                 `json:"program_id"`
+	ProgramName     string                `json:"program_name"`
+	Status          string                `json:"status"` // 所有要求中最差的一项
+	TotalCredits    CreditProgress        `json:"total_credits"`
+	RequiredCourses []RequiredCourseAudit `json:"required_courses"`
+	ElectiveGroups  []ElectiveGroupAudit  `json:"elective_groups"`
+	Categories      []CategoryAudit       `json:"categories"`
+	Courses         []model.CourseRecord  `json:"courses"` // 参与审核的修读记录，每门课程一条
+}
+
+// Audit 按学生所在专业的培养方案，审核已通过和在修的课程
+func (s *ProgramService) Audit(studentID string) (*DegreeAudit, error) {
+	student, err := s.enrollments.GetStudentByPublicID(studentID)
+	if err != nil {
+		return nil, ErrStudentNotFound
+	}
+	if student.MajorID == nil {
+		return nil, ErrMajorNotAssigned
+	}
+	program, err := s.programs.GetByMajor(*student.MajorID)
+	if err != nil {
+		return nil, ErrProgramNotFound
+	}
+	records, err := s.enrollments.GetStudentRecords(student.ID)
+	if err != nil {
+		return nil, err
+	}
+	return auditProgram(program, records), nil
+}
+
+// auditProgram 同一门课程修读多次时取最好的一次：通过优先于在修，未通过的不计学分。
+// 一门课程可以同时计入必修、选修课组和所属类别。
+func auditProgram(program *model.Program, records []model.CourseRecord) *DegreeAudit {
+	courses := make(map[string]model.CourseRecord)
+	var order []string
+	for _, r := range records {
+		key := courseKey(r)
+		existing, ok := courses[key]
+		if !ok {
+			order = append(order, key)
+		}
+		if !ok || recordRank(r.Status) > recordRank(existing.Status) {
+			courses[key] = r
+		}
+	}
+
+	audit := &DegreeAudit{
+		ProgramID:       program.ID,
+		ProgramName:     program.Name,
+		Status:          AuditSatisfied,
+		RequiredCourses: []RequiredCourseAudit{},
+		ElectiveGroups:  []ElectiveGroupAudit{},
+		Categories:      []CategoryAudit{},
+		Courses:         []model.CourseRecord{},
+	}
+	worst := func(status string) {
+		if auditRank(status) < auditRank(audit.Status) {
+			audit.Status = status
+		}
+	}
+
+	audit.TotalCredits = CreditProgress{Required: program.TotalCredits}
+	for _, key := range order {
+		r := courses[key]
+		audit.Courses = append(audit.Courses, r)
+		audit.TotalCredits.add(r)
+	}
+	audit.TotalCredits.finish()
+	worst(audit.TotalCredits.Status)
+
+	for _, rc := range program.RequiredCourses {
+		item := RequiredCourseAudit{CourseCode: rc.CourseCode, Status: AuditMissing}
+		if r, ok := courses[rc.CourseCode]; ok {
+			item.Name = r.Name
+			switch r.Status {
+			case model.EnrollmentStatusPassed:
+				item.Status = AuditSatisfied
+			case model.EnrollmentStatusEnrolled:
+				item.Status = AuditInProgress
+			}
+		}
+		audit.RequiredCourses = append(audit.RequiredCourses, item)
+		worst(item.Status)
+	}
+
+	for _, g := range program.ElectiveGroups {
+		item := ElectiveGroupAudit{Name: g.Name, CreditProgress: CreditProgress{Required: g.MinCredits}, Courses: []string{}}
+		for _, gc := range g.Courses {
+			if r, ok := courses[gc.CourseCode]; ok && item.add(r) {
+				item.Courses = append(item.Courses, gc.CourseCode)
+			}
+		}
+		item.finish()
+		audit.ElectiveGroups = append(audit.ElectiveGroups, item)
+		worst(item.Status)
+	}
+
+	for _, cr := range program.CategoryCredits {
+		item := CategoryAudit{Category: cr.Category, CreditProgress: CreditProgress{Required: cr.MinCredits}}
+		for _, key := range order {
+			if r := courses[key]; r.Category == cr.Category {
+				item.add(r)
+			}
+		}
+		item.finish()
+		audit.Categories = append(audit.Categories, item)
+		worst(item.Status)
+	}
+	return audit
+}
+
+// add 计入一条修读记录，未通过的课程不计入时返回 false
+func (p *CreditProgress) add(r model.CourseRecord) bool {
+	switch r.Status {
+	case model.EnrollmentStatusPassed:
+		p.Completed += r.Credits
+	case model.EnrollmentStatusEnrolled:
+		p.InProgress += r.Credits
+	default:
+		return false
+	}
+	return true
+}
+
+// finish 学分保留一位小数后判断完成情况，避免浮点误差
+func (p *CreditProgress) finish() {
+	p.Completed = math.Round(p.Completed*10) / 10
+	p.InProgress = math.Round(p.InProgress*10) / 10
+	switch {
+	case p.Completed >= p.Required:
+		p.Status = AuditSatisfied
+	case p.Completed+p.InProgress >= p.Required:
+		p.Status = AuditInProgress
+	default:
+		p.Status = AuditMissing
+	}
+}
+
+// courseKey 没有课程编号的课程无法对应培养方案中的课程，只按开课记录计入总学分和类别学分
+func courseKey(r model.CourseRecord) string {
+	if r.Code == "" {
+		return fmt.Sprintf("#%d", r.CourseID)
+	}
+	return r.Code
+}
+
+func recordRank(status string) int {
+	switch status {
+	case model.EnrollmentStatusPassed:
+		return 2
+	case model.EnrollmentStatusEnrolled:
+		return 1
+	}
+	return 0
+}
+
+func auditRank(status string) int {
+	switch status {
+	case AuditSatisfied:
+		return 2
+	case AuditInProgress:
+		return 1
+	}
+	return 0
+}
+
+func buildProgram(majorID int64, input SaveProgramInput) (*model.Program, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, errors.New("名称不能为空")
+	}
+	if input.TotalCredits < 0 {
+		return nil, errors.New("总学分不能为负数")
+	}
+	program := &model.Program{MajorID: majorID, Name: name, TotalCredits: input.TotalCredits}
+
+	codes, err := normalizeCourseCodes(input.RequiredCourses)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		program.RequiredCourses = append(program.RequiredCourses, model.ProgramCourse{CourseCode: code})
+	}
+
+	for _, g := range input.ElectiveGroups {
+		groupName := strings.TrimSpace(g.Name)
+		if groupName == "" {
+			return nil, errors.New("选修课组名称不能为空")
+		}
+		if g.MinCredits <= 0 {
+			return nil, fmt.Errorf("选修课组「%s」的最低学分必须大于0", groupName)
+		}
+		codes, err := normalizeCourseCodes(g.Courses)
+		if err != nil {
+			return nil, err
+		}
+		if len(codes) == 0 {
+			return nil, fmt.Errorf("选修课组「%s」至少包含一门课程", groupName)
+		}
+		group := model.ElectiveGroup{Name: groupName, MinCredits: g.MinCredits}
+		for _, code := range codes {
+			group.Courses = append(group.Courses, model.ElectiveGroupCourse{CourseCode: code})
+		}
+		program.ElectiveGroups = append(program.ElectiveGroups, group)
+	}
+
+	categories := make([]string, 0, len(input.CategoryCredits))
+	for category := range input.CategoryCredits {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		credits := input.CategoryCredits[category]
+		if !isValidCourseCategory(category) {
+			return nil, fmt.Errorf("无效的课程类别 %s", category)
+		}
+		if credits <= 0 {
+			return nil, fmt.Errorf("类别 %s 的最低学分必须大于0", category)
+		}
+		program.CategoryCredits = append(program.CategoryCredits, model.CategoryRequirement{Category: category, MinCredits: credits})
+	}
+	return program, nil
+}
+
+// normalizeCourseCodes 规范化并去重课程编号
+func normalizeCourseCodes(codes []string) ([]string, error) {
+	seen := make(map[string]bool, len(codes))
+	var result []string
+	for _, code := range codes {
+		code = normalizeCourseCode(code)
+		if code == "" {
+			return nil, errors.New("课程编号不能为空")
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		result = append(result, code)
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/search"
+)
+
+var ErrEmptySearchQuery = errors.New("搜索关键词不能为空")
+
+// 全文检索字段及权重
+var courseSearchBoosts = map[string]float64{
+	"name":         3,
+	"teacher_name": 2,
+	"remark":       1,
+}
+
+// RebuildSearchIndex 从数据库重建全文索引，服务启动时以及距上次重建超过 searchRefresh 时调用。
+// 重建期间本实例写入旧索引的修改可能被新索引覆盖，在下一次重建时恢复。
+func (s *CourseService) RebuildSearchIndex() error {
+	courses, err := s.courseRepo.ListAll()
+	if err != nil {
+		return err
+	}
+
+	idx := search.NewIndex(courseSearchBoosts)
+	teacherNames := make(map[string]string)
+	for i := range courses {
+		course := &courses[i]
+		name, ok := teacherNames[course.TeacherID]
+		if !ok {
+			name = s.teacherName(course.TeacherID)
+			teacherNames[course.TeacherID] = name
+		}
+		putSearchDocument(idx, course, name)
+	}
+	s.searchIndex.Store(idx)
+
+	s.teacherMu.Lock()
+	s.teacherNames = teacherNames
+	s.teacherMu.Unlock()
+
+	s.searchMu.Lock()
+	s.searchBuiltAt = time.Now()
+	s.searchMu.Unlock()
+	return nil
+}
+
+// currentSearchIndex 返回检索使用的索引。索引只保存在本实例内存中，看不到其他实例的修改，
+// 到了重建时间由一个请求从数据库重建，其余请求继续使用旧索引；重建失败时记录日志并使用旧索引。
+func (s *CourseService) currentSearchIndex() *search.Index {
+	if s.searchRefresh > 0 {
+		s.searchMu.Lock()
+		due := time.Since(s.searchBuiltAt) >= s.searchRefresh
+		if due {
+			s.searchBuiltAt = time.Now()
+		}
+		s.searchMu.Unlock()
+
+		if due {
+			if err := s.RebuildSearchIndex(); err != nil {
+				log.Printf("重建全文索引失败，继续使用旧索引: %v", err)
+			}
+		}
+	}
+	return s.searchIndex.Load()
+}
+
+// ReindexTeacherCourses 教师姓名变化后更新其课程的索引
+func (s *CourseService) ReindexTeacherCourses(teacherID string) error {
+	courses, err := s.courseRepo.ListAll()
+	if err != nil {
+		return err
+	}
+
+	name := s.teacherName(teacherID)
+	s.setTeacherName(teacherID, name)
+	for i := range courses {
+		if courses[i].TeacherID == teacherID {
+			putSearchDocument(s.searchIndex.Load(), &courses[i], name)
+		}
+	}
+	return nil
+}
+
+// FullTextSearch 在课程名、课程说明和教师姓名中检索，结果按相关度排序。
+// 只使用 input 中的 Viewer、Pagination 和 Fields，课程字段与课程列表一样按 CourseProjection 选择。
+func (s *CourseService) FullTextSearch(q string, input GetCoursesInput) (*model.PaginatedResponse[model.CourseSearchHit], error) {
+	if q == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	pagination := input.Pagination
+
+	hits := s.currentSearchIndex().Search(q, 0)
+	total := int64(len(hits))
+
+	// 结果按相关度排序，游标记录边界结果的ID
+	start := min(pagination.Offset(), len(hits))
+	end := min(start+pagination.Limit(), len(hits))
+	if pagination.IsCursor() {
+		start, end = 0, min(pagination.Limit(), len(hits))
+		if !pagination.Cursor.IsStart() {
+			found := false
+			for i, hit := range hits {
+				if hit.ID != pagination.Cursor.ID {
+					continue
+				}
+				if pagination.Cursor.Backward {
+					start, end = max(i-pagination.Limit(), 0), i
+				} else {
+					start, end = i+1, min(i+1+pagination.Limit(), len(hits))
+				}
+				found = true
+				break
+			}
+			// 游标指向的课程已不在结果中（被删除或修改），无法确定位置
+			if !found {
+				return nil, pkg.ErrInvalidCursor
+			}
+		}
+	}
+	hasPrev, hasNext := start > 0, end < len(hits)
+	hits = hits[start:end]
+
+	ids := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	rows, err := s.courseRepo.GetRows(ids, s.viewerID(input.Viewer), input.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]model.CourseSearchHit, 0, len(hits))
+	for _, hit := range hits {
+		course, ok := rows[hit.ID]
+		if !ok {
+			// 索引与数据库短暂不一致时跳过
+			continue
+		}
+		data = append(data, model.CourseSearchHit{
+			Course:     course,
+			Score:      hit.Score,
+			Highlights: hit.Highlights,
+		})
+	}
+
+	response := &model.PaginatedResponse[model.CourseSearchHit]{
+		Data:     data,
+		Total:    total,
+		PageSize: pagination.PageSize,
+	}
+	if !pagination.IsCursor() {
+		response.Page = pagination.Page
+		response.TotalPages = int((total + int64(pagination.PageSize) - 1) / int64(pagination.PageSize))
+		return response, nil
+	}
+
+	if len(hits) > 0 {
+		if hasNext {
+			next := &model.Cursor{SortBy: "score", Order: "DESC", ID: hits[len(hits)-1].ID}
+			if response.NextCursor, err = s.cursors.Encode(next); err != nil {
+				return nil, err
+			}
+		}
+		if hasPrev {
+			prev := &model.Cursor{SortBy: "score", Order: "DESC", ID: hits[0].ID, Backward: true}
+			if response.PrevCursor, err = s.cursors.Encode(prev); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return response, nil
+}
+
+func (s *CourseService) indexCourse(course *model.Course) {
+	putSearchDocument(s.searchIndex.Load(), course, s.teacherName(course.TeacherID))
+}
+
+// teacherIDsByName 返回姓名包含 name（不区分大小写）的教师 PublicID，只在有课程的教师中查找
+func (s *CourseService) teacherIDsByName(name string) []string {
+	s.currentSearchIndex()
+	name = strings.ToLower(name)
+
+	s.teacherMu.RLock()
+	defer s.teacherMu.RUnlock()
+	var ids []string
+	for id, teacherName := range s.teacherNames {
+		if strings.Contains(strings.ToLower(teacherName), name) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *CourseService) setTeacherName(teacherID, name string) {
+	s.teacherMu.Lock()
+	s.teacherNames[teacherID] = name
+	s.teacherMu.Unlock()
+}
+
+func putSearchDocument(idx *search.Index, course *model.Course, teacherName string) {
+	idx.Put(course.ID, map[string]string{
+		"name":         course.Name,
+		"teacher_name": teacherName,
+		"remark":       course.Remark,
+	})
+}
+
+func (s *CourseService) teacherName(teacherID string) string {
+	teacher, err := s.userRepo.FindByPublicID(teacherID)
+	if err != nil || teacher == nil {
+		return ""
+	}
+	return teacher.Name
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+// LoginThrottlePolicy 登录限流策略
+type LoginThrottlePolicy struct {
+	MaxAccountFailures int           // 同一账号连续失败达到该次数后锁定
+	MaxIPFailures      int           // 同一IP连续失败达到该次数后锁定，IP不做逐次退避
+	BaseDelay          time.Duration // 账号第一次失败后的等待时间，之后每次失败翻倍
+	MaxDelay           time.Duration // 等待时间上限
+	LockoutDuration    time.Duration // 锁定时长
+	ResetAfter         time.Duration // 距上次失败超过该时间后重新计数
+}
+
+var DefaultLoginThrottlePolicy = LoginThrottlePolicy{
+	MaxAccountFailures: 5,
+	MaxIPFailures:      50,
+	BaseDelay:          time.Second,
+	MaxDelay:           time.Minute,
+	LockoutDuration:    15 * time.Minute,
+	ResetAfter:         time.Hour,
+}
+
+// LoginBlockedError 登录被限流或锁定
+type LoginBlockedError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	wait := int(math.Ceil(e.RetryAfter.Seconds()))
+	if e.Locked {
+		return fmt.Sprintf("登录失败次数过多，已被临时锁定，请在%d秒后重试", wait)
+	}
+	return fmt.Sprintf("登录过于频繁，请在%d秒后重试", wait)
+}
+
+// LoginThrottle 记录登录失败并按指数退避限制重试。账号等按用户区分的键逐次退避，IP只累计次数
+type LoginThrottle struct {
+	repo   repository.LoginAttemptRepository
+	policy LoginThrottlePolicy
+	now    func() time.Time
+}
+
+func NewLoginThrottle(repo repository.LoginAttemptRepository, policy LoginThrottlePolicy) *LoginThrottle {
+	return &LoginThrottle{repo: repo, policy: policy, now: time.Now}
+}
+
+// Begin 原子地检查 key 是否处于退避或锁定期，未被限制时预先占用本次失败后的退避时间。
+// 并发提交的多次尝试只有一个能通过，其余在退避期内被拒绝，不会绕过指数退避。
+func (t *LoginThrottle) Begin(key string) error {
+	now := t.now()
+	var blocked *LoginBlockedError
+	_, err := t.repo.Update(key, func(a *model.LoginAttempt) {
+		if until := a.BlockedUntil(now); !until.IsZero() {
+			blocked = &LoginBlockedError{Locked: a.IsLocked(now), RetryAfter: until.Sub(now)}
+			return
+		}
+		next := now.Add(t.backoff(t.failures(a, now) + 1))
+		a.NextAllowedAt = &next
+	})
+	if err != nil {
+		return err
+	}
+	if blocked != nil {
+		return blocked
+	}
+	return nil
+}
+
+// Check 任一键处于退避或锁定期时返回 *LoginBlockedError，只读不占用退避时间
+func (t *LoginThrottle) Check(keys ...string) error {
+	now := t.now()
+	for _, key := range keys {
+		attempt, err := t.repo.Get(key)
+		if err != nil {
+			return err
+		}
+		if until := attempt.BlockedUntil(now); !until.IsZero() {
+			return &LoginBlockedError{Locked: attempt.IsLocked(now), RetryAfter: until.Sub(now)}
+		}
+	}
+	return nil
+}
+
+// RecordFailure 记录一次失败并按失败次数退避，返回本次失败是否触发了锁定
+func (t *LoginThrottle) RecordFailure(key string, maxFailures int) (bool, error) {
+	return t.recordFailure(key, maxFailures, true)
+}
+
+// RecordIPFailure 记录来自某个IP的一次失败，只累计次数，达到 MaxIPFailures 后锁定。
+// 同一IP后面可能有很多正常用户，不做逐次退避。
+func (t *LoginThrottle) RecordIPFailure(key string) (bool, error) {
+	return t.recordFailure(key, t.policy.MaxIPFailures, false)
+}
+
+func (t *LoginThrottle) recordFailure(key string, maxFailures int, backoff bool) (bool, error) {
+	now := t.now()
+	locked := false
+	_, err := t.repo.Update(key, func(a *model.LoginAttempt) {
+		a.Failures = t.failures(a, now) + 1
+		a.LastFailureAt = &now
+
+		if backoff {
+			next := now.Add(t.backoff(a.Failures))
+			a.NextAllowedAt = &next
+		}
+		if a.Failures >= maxFailures && !a.IsLocked(now) {
+			until := now.Add(t.policy.LockoutDuration)
+			a.LockedUntil = &until
+			a.Failures = 0
+			locked = true
+		}
+	})
+	return locked, err
+}
+
+// failures 距上次失败超过 ResetAfter 且未锁定时重新计数
+func (t *LoginThrottle) failures(a *model.LoginAttempt, now time.Time) int {
+	if a.LastFailureAt != nil && now.Sub(*a.LastFailureAt) > t.policy.ResetAfter && !a.IsLocked(now) {
+		return 0
+	}
+	return a.Failures
+}
+
+// Reset 清除失败记录，登录成功或管理员解锁时调用
+func (t *LoginThrottle) Reset(key string) error {
+	return t.repo.Delete(key)
+}
+
+func (t *LoginThrottle) backoff(failures int) time.Duration {
+	delay := t.policy.BaseDelay
+	for i := 1; i < failures && delay < t.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, t.policy.MaxDelay)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+)
+
+// newTestThrottle 返回使用内存记录和可调时钟的限流器
+func newTestThrottle() (*LoginThrottle, *time.Time) {
+	now := time.Now()
+	throttle := NewLoginThrottle(repository.NewMemoryLoginAttemptRepository(time.Hour), DefaultLoginThrottlePolicy)
+	throttle.now = func() time.Time { return now }
+	return throttle, &now
+}
+
+func assertBlocked(t *testing.T, err error, locked bool, retryAfter time.Duration) {
+	t.Helper()
+	var blocked *LoginBlockedError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("err = %v，应为 *LoginBlockedError", err)
+	}
+	if blocked.Locked != locked || blocked.RetryAfter != retryAfter {
+		t.Errorf("err = %+v，应为 Locked=%t RetryAfter=%s", blocked, locked, retryAfter)
+	}
+}
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle, now := newTestThrottle()
+	policy := DefaultLoginThrottlePolicy
+
+	for i, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if err := throttle.Begin("account:a"); err != nil {
+			t.Fatalf("第%d次尝试: %v", i+1, err)
+		}
+		if _, err := throttle.RecordFailure("account:a", policy.MaxAccountFailures); err != nil {
+			t.Fatal(err)
+		}
+		assertBlocked(t, throttle.Check("account:a"), false, want)
+		*now = now.Add(want)
+	}
+
+	t.Run("达到次数后锁定", func(t *testing.T) {
+		var locked bool
+		for i := 0; i < 2; i++ {
+			var err error
+			if locked, err = throttle.RecordFailure("account:a", policy.MaxAccountFailures); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !locked {
+			t.Fatal("第5次失败应触发锁定")
+		}
+		assertBlocked(t, throttle.Begin("account:a"), true, policy.LockoutDuration)
+	})
+
+	t.Run("成功后清除", func(t *testing.T) {
+		if err := throttle.Reset("account:a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := throttle.Check("account:a"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestLoginThrottleResetAfter(t *testing.T) {
+	throttle, now := newTestThrottle()
+	policy := DefaultLoginThrottlePolicy
+	for i := 0; i < policy.MaxAccountFailures-1; i++ {
+		throttle.RecordFailure("account:a", policy.MaxAccountFailures)
+	}
+
+	*now = now.Add(policy.ResetAfter + time.Second)
+	locked, err := throttle.RecordFailure("account:a", policy.MaxAccountFailures)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if locked {
+		t.Error("超过 ResetAfter 后应重新计数")
+	}
+	assertBlocked(t, throttle.Check("account:a"), false, policy.BaseDelay)
+}
+
+func TestLoginThrottleIP(t *testing.T) {
+	throttle, _ := newTestThrottle()
+	policy := DefaultLoginThrottlePolicy
+
+	for i := 1; i < policy.MaxIPFailures; i++ {
+		if locked, err := throttle.RecordIPFailure("ip:127.0.0.1"); err != nil || locked {
+			t.Fatalf("第%d次失败: locked=%t err=%v", i, locked, err)
+		}
+		if err := throttle.Check("ip:127.0.0.1"); err != nil {
+			t.Fatalf("IP失败%d次后不应退避: %v", i, err)
+		}
+	}
+
+	locked, err := throttle.RecordIPFailure("ip:127.0.0.1")
+	if err != nil || !locked {
+		t.Fatalf("locked=%t err=%v，应触发锁定", locked, err)
+	}
+	assertBlocked(t, throttle.Check("ip:127.0.0.1"), true, policy.LockoutDuration)
+}
+
+func TestLoginThrottleBeginConcurrent(t *testing.T) {
+	throttle, _ := newTestThrottle()
+
+	// 同时发起的尝试只有一个能通过，其余在本次失败的退避期内被拒绝
+	const n = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if throttle.Begin("account:a") == nil {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 1 {
+		t.Fatalf("%d 次尝试通过，应只通过一次", allowed)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/config"
+)
+
+// 检查数据库中的不一致数据，例如引用了不存在的课程或学生的选课记录。
+// 加上 -repair 时删除能自动修复的行，其余问题需要人工处理。发现问题时以状态码 1 退出。
+func main() {
+	repair := flag.Bool("repair", false, "删除能自动修复的不一致数据")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Printf("加载配置失败: %v", err)
+		os.Exit(1)
+	}
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		log.Printf("连接数据库失败: %v", err)
+		os.Exit(1)
+	}
+
+	var issues []repository.IntegrityIssue
+	if *repair {
+		issues, err = repository.RepairIntegrity(db)
+	} else {
+		issues, err = repository.CheckIntegrity(db)
+	}
+	if err != nil {
+		log.Printf("检查失败: %v", err)
+		os.Exit(1)
+	}
+	if len(issues) == 0 {
+		fmt.Println("未发现不一致的数据")
+		return
+	}
+
+	remaining := 0
+	for _, issue := range issues {
+		state := "需人工处理"
+		switch {
+		case *repair && issue.Repairable:
+			state = fmt.Sprintf("已删除 %d 行", issue.Repaired)
+		case issue.Repairable:
+			state = "可用 -repair 删除"
+		}
+		if !*repair || !issue.Repairable {
+			remaining++
+		}
+		fmt.Printf("%-28s %6d  %s（%s）\n", issue.Name, issue.Count, issue.Description, state)
+	}
+	if remaining > 0 {
+		os.Exit(1)
+	}
+}
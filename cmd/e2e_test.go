@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/health"
+	"github.com/liuyifan1996/course-selection-system/seed"
+)
+
+var update = flag.Bool("update", false, "用实际响应更新 testdata 中的 golden 文件")
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+// 测试后端：sqlite 全部使用 GORM 仓库；memory 的用户、课程和选课使用内存仓库，其余仍使用 SQLite
+var backends = []string{"sqlite", "memory"}
+
+// testConfig 使用独立的 SQLite 内存数据库，每次调用得到一个空库
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg := config.Default()
+	cfg.JWT.Secret = strings.Repeat("e2e-test-secret-", 3)
+	cfg.Cursor.Secret = strings.Repeat("e2e-test-cursor-", 3)
+	cfg.PII.Keys = "test:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	cfg.PII.IndexKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	cfg.Database.Driver = config.DriverSQLite
+	cfg.Database.DSN = ":memory:"
+
+	cfg.Password.BreachedFile = "../config/breached_passwords.txt"
+	cfg.Notifier.Type = "log"
+	// 种子数据直接写入数据库，相当于其他实例的修改，每次检索前都重建全文索引
+	cfg.Search.RefreshInterval = time.Nanosecond
+	return &cfg
+}
+
+// testServer 完整的路由和对应的数据库
+type testServer struct {
+	t   *testing.T
+	app *App
+}
+
+func newTestServer(t *testing.T, backend string) *testServer {
+	t.Helper()
+	cfg := testConfig(t)
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deps := Dependencies{DB: db}
+	if backend == "memory" {
+		repos := NewGormRepositories(db, cfg.LoginThrottle)
+		store := repository.NewMemoryStore()
+		repos.Auth = repository.NewMemoryAuthRepository(store)
+		repos.Course = repository.NewMemoryCourseRepository(store)
+		repos.Enrollment = repository.NewMemoryEnrollmentRepository(store)
+		deps.Repositories = repos
+	}
+
+	probes := health.NewChecker(time.Second)
+	app, err := Setup(cfg, probes, deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	probes.MarkRunning()
+	t.Cleanup(func() { app.Close() })
+	return &testServer{t: t, app: app}
+}
+
+// do 发送请求，token 不为空时作为 Bearer 令牌，body 不为 nil 时编码为 JSON
+func (s *testServer) do(method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.app.Router.ServeHTTP(rec, req)
+	return rec
+}
+
+// login 登录并返回令牌
+func (s *testServer) login(idCard, password string) string {
+	s.t.Helper()
+	rec := s.do(http.MethodPost, "/login", "", map[string]string{"id_card": idCard, "password": password})
+	if rec.Code != http.StatusOK {
+		s.t.Fatalf("登录失败: %d %s", rec.Code, rec.Body)
+	}
+	return decodeBody(s.t, rec)["token"].(string)
+}
+
+// seed 向测试服务器的数据库写入生成的数据。memory 后端的用户和课程不在数据库中，只能用于 sqlite 后端
+func (s *testServer) seed(opts seed.Options) *seed.Result {
+	s.t.Helper()
+	result, err := seed.Run(s.app.DB, opts)
+	if err != nil {
+		s.t.Fatalf("生成数据失败: %v", err)
+	}
+	return result
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("响应不是 JSON 对象: %s", rec.Body)
+	}
+	return body
+}
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
+	timePattern  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}`)
+)
+
+// goldenNormalizer 将每次运行都不同的值替换为占位符。同一个 UUID 在整个场景中使用相同的编号，
+// 可以从 golden 文件看出响应之间的引用关系。
+type goldenNormalizer struct {
+	uuids map[string]string
+}
+
+func newGoldenNormalizer() *goldenNormalizer {
+	return &goldenNormalizer{uuids: make(map[string]string)}
+}
+
+func (n *goldenNormalizer) normalize(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = n.normalize(item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = n.normalize(item)
+		}
+	case string:
+		switch {
+		case uuidPattern.MatchString(val):
+			if _, ok := n.uuids[val]; !ok {
+				n.uuids[val] = fmt.Sprintf("<uuid-%d>", len(n.uuids)+1)
+			}
+			return n.uuids[val]
+		case tokenPattern.MatchString(val):
+			return "<token>"
+		case timePattern.MatchString(val):
+			return "<time>"
+		}
+	}
+	return v
+}
+
+// assertGolden 比较状态码和规范化后的响应体与 testdata/<name>.golden，
+// 使用 go test ./cmd -run TestScenario -update 重新生成
+func (n *goldenNormalizer) assertGolden(t *testing.T, name string, rec *httptest.ResponseRecorder) {
+	t.Helper()
+	var body interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: 响应不是 JSON: %s", name, rec.Body)
+	}
+	var pretty bytes.Buffer
+	enc := json.NewEncoder(&pretty)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(n.normalize(body)); err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf("HTTP %d\n%s", rec.Code, pretty.String())
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败（首次运行请加 -update）: %v", path, err)
+	}
+	if got != string(want) {
+		t.Errorf("%s 与 golden 文件不一致\n--- got\n%s--- want\n%s", name, got, want)
+	}
+}
+
+// TestScenario 注册 → 登录 → 创建课程 → 选课 → 退课 → 删除课程。
+// 两个后端使用同一组 golden 文件，响应必须完全一致。
+func TestScenario(t *testing.T) {
+	const (
+		teacherIDCard = "110105198001011238"
+		studentIDCard = "440304200309152345"
+		password      = "Course2025Pass"
+	)
+
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			s := newTestServer(t, backend)
+			g := newGoldenNormalizer()
+
+			g.assertGolden(t, "01_register_teacher", s.do(http.MethodPost, "/register", "", map[string]string{
+				"id_card": teacherIDCard, "name": "王建国", "role": "teacher", "password": password,
+			}))
+			g.assertGolden(t, "02_register_student", s.do(http.MethodPost, "/register", "", map[string]string{
+				"id_card": studentIDCard, "name": "李小明", "role": "student", "password": password,
+			}))
+			g.assertGolden(t, "03_login", s.do(http.MethodPost, "/login", "", map[string]string{
+				"id_card": teacherIDCard, "password": password,
+			}))
+			teacher := s.login(teacherIDCard, password)
+			student := s.login(studentIDCard, password)
+
+			g.assertGolden(t, "04_unauthenticated", s.do(http.MethodGet, "/courses", "", nil))
+			course := map[string]interface{}{
+				"code":           " cs101 ",
+				"name":           "程序设计基础",
+				"remark":         "面向零基础",
+				"student_maxnum": 30,
+				"hours":          64,
+				"credits":        4,
+				"category":       "foundation",
+				"term":           "2026-2027-1",
+				"tags":           []string{"编程"},
+				"start_date":     time.Now().AddDate(0, 1, 0).Format(time.RFC3339),
+			}
+			g.assertGolden(t, "05_student_create_course", s.do(http.MethodPost, "/courses/create", student, course))
+			g.assertGolden(t, "06_create_course", s.do(http.MethodPost, "/courses/create", teacher, course))
+
+			g.assertGolden(t, "07_enroll", s.do(http.MethodPost, "/courses/1/enroll", student, nil))
+			g.assertGolden(t, "08_enroll_again", s.do(http.MethodPost, "/courses/1/enroll", student, nil))
+			g.assertGolden(t, "09_student_courses", s.do(http.MethodGet, "/student-courses?page=1&page_size=10&fields=id,code,name,teacher_name,enrolled_count,is_enrolled", student, nil))
+			g.assertGolden(t, "10_course_detail", s.do(http.MethodGet, "/courses/1", student, nil))
+			g.assertGolden(t, "11_delete_with_students", s.do(http.MethodDelete, "/courses/1", teacher, nil))
+
+			g.assertGolden(t, "12_drop", s.do(http.MethodDelete, "/courses/1/enroll", student, nil))
+			g.assertGolden(t, "13_drop_again", s.do(http.MethodDelete, "/courses/1/enroll", student, nil))
+			g.assertGolden(t, "14_delete_course", s.do(http.MethodDelete, "/courses/1", teacher, nil))
+			g.assertGolden(t, "15_deleted_course", s.do(http.MethodGet, "/courses/1", student, nil))
+			g.assertGolden(t, "16_courses_after_delete", s.do(http.MethodGet, "/courses?page=1&page_size=10", student, nil))
+
+			// 登录失败会触发限流，放在最后
+			g.assertGolden(t, "17_login_wrong_password", s.do(http.MethodPost, "/login", "", map[string]string{
+				"id_card": studentIDCard, "password": "Wrong2025Pass",
+			}))
+		})
+	}
+}
+
+// TestSeed 生成的数据可以直接通过接口使用，相同的种子生成相同的数据，重复执行不产生重复数据
+func TestSeed(t *testing.T) {
+	opts := seed.Options{Seed: 7, Teachers: 4, Students: 30, Courses: 6, Sessions: 2, Enrollments: 80, Password: "Course2025Pass"}
+	s := newTestServer(t, "sqlite")
+	first := s.seed(opts)
+	if len(first.Teachers) != 4 || len(first.Students) != 30 || len(first.Courses) != 12 {
+		t.Fatalf("生成 %d 名教师、%d 名学生、%d 个开课班级", len(first.Teachers), len(first.Students), len(first.Courses))
+	}
+	if first.Enrollments == 0 || first.Created.Enrollments != first.Enrollments {
+		t.Fatalf("生成 %d 条选课记录，写入 %d 条", first.Enrollments, first.Created.Enrollments)
+	}
+	for _, u := range append(first.Teachers, first.Students...) {
+		if normalized, err := pkg.NormalizeIDCard(u.IDCard); err != nil || normalized != u.IDCard {
+			t.Errorf("%s 的身份证号 %s 无效: %v", u.Name, u.IDCard, err)
+		}
+	}
+
+	again := s.seed(opts)
+	if again.Created != (seed.Counts{}) {
+		t.Errorf("重复执行写入了新数据: %+v", again.Created)
+	}
+	for i := range first.Students {
+		if again.Students[i].ID != first.Students[i].ID {
+			t.Fatalf("重复执行后第 %d 名学生的ID从 %d 变为 %d", i, first.Students[i].ID, again.Students[i].ID)
+		}
+	}
+
+	other := newTestServer(t, "sqlite").seed(opts)
+	for i := range first.Students {
+		a, b := first.Students[i], other.Students[i]
+		if a.PublicID != b.PublicID || a.IDCard != b.IDCard || a.Name != b.Name || a.EnrollmentYear != b.EnrollmentYear {
+			t.Fatalf("相同种子生成的第 %d 名学生不同: %s %s / %s %s", i, a.IDCard, a.Name, b.IDCard, b.Name)
+		}
+	}
+	for i := range first.Courses {
+		a, b := first.Courses[i], other.Courses[i]
+		if a.Code != b.Code || a.Name != b.Name || a.Term != b.Term || !a.StartDate.Equal(b.StartDate) {
+			t.Fatalf("相同种子生成的第 %d 个开课班级不同: %s %s / %s %s", i, a.Code, a.Term, b.Code, b.Term)
+		}
+	}
+	if other.Enrollments != first.Enrollments {
+		t.Errorf("相同种子生成的选课记录数不同: %d / %d", first.Enrollments, other.Enrollments)
+	}
+
+	// 生成的账号可以登录，课程和选课记录可以通过接口查询
+	student := first.Students[0]
+	token := s.login(student.IDCard, opts.Password)
+	var enrolled int64
+	if err := s.app.DB.Table("enrollments").Where("student_id = ?", student.ID).Count(&enrolled).Error; err != nil {
+		t.Fatal(err)
+	}
+	rec := s.do(http.MethodGet, "/student-courses?page=1&page_size=100", token, nil)
+	if rec.Code != http.StatusOK || int64(decodeBody(t, rec)["total"].(float64)) != enrolled {
+		t.Errorf("学生课程应有 %d 门: %d %s", enrolled, rec.Code, rec.Body)
+	}
+	rec = s.do(http.MethodGet, "/courses?page=1&page_size=1", token, nil)
+	if rec.Code != http.StatusOK || int(decodeBody(t, rec)["total"].(float64)) != len(first.Courses) {
+		t.Errorf("课程列表应有 %d 个开课班级: %d %s", len(first.Courses), rec.Code, rec.Body)
+	}
+	rec = s.do(http.MethodGet, "/courses/fulltext?q="+url.QueryEscape(first.Courses[0].Name)+"&fields=name,teacher_name", token, nil)
+	if hits, _ := decodeBody(t, rec)["data"].([]interface{}); rec.Code != http.StatusOK || len(hits) == 0 {
+		t.Fatalf("全文检索应能找到生成的课程: %d %s", rec.Code, rec.Body)
+	} else if course := hits[0].(map[string]interface{})["course"].(map[string]interface{}); len(course) != 2 || course["teacher_name"] == "" {
+		t.Errorf("检索结果应只包含 name 和 teacher_name: %v", course)
+	}
+}
+
+// TestAccountUpdates 修改邮箱需要当前密码；修改资料和修改密码只更新各自的列，不会互相覆盖
+func TestAccountUpdates(t *testing.T) {
+	const (
+		idCard   = "440304200309152345"
+		password = "Course2025Pass"
+	)
+	for _, backend := range backends {
+		t.Run(backend, func(t *testing.T) {
+			s := newTestServer(t, backend)
+			rec := s.do(http.MethodPost, "/register", "", map[string]string{
+				"id_card": idCard, "name": "李小明", "role": "student", "password": password, "email": "old@example.com",
+			})
+			if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+				t.Fatalf("注册失败: %d %s", rec.Code, rec.Body)
+			}
+			token := s.login(idCard, password)
+
+			rec = s.do(http.MethodPatch, "/me", token, map[string]string{"email": "new@example.com"})
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("未提交当前密码时修改邮箱: %d %s", rec.Code, rec.Body)
+			}
+			rec = s.do(http.MethodPatch, "/me", token, map[string]string{"email": "new@example.com", "current_password": password, "phone": "13800138000"})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("修改邮箱失败: %d %s", rec.Code, rec.Body)
+			}
+
+			rec = s.do(http.MethodPost, "/me/password", token, map[string]string{"old_password": password, "new_password": "Course2026Pass"})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("修改密码失败: %d %s", rec.Code, rec.Body)
+			}
+			token = s.login(idCard, "Course2026Pass")
+			profile := decodeBody(t, s.do(http.MethodGet, "/me", token, nil))
+			if profile["email"] != "new@example.com" || profile["phone"] != "13800138000" || profile["name"] != "李小明" {
+				t.Errorf("资料 = %v", profile)
+			}
+
+			// 密码错误会触发限流，放在最后
+			rec = s.do(http.MethodPatch, "/me", token, map[string]string{"email": "other@example.com", "current_password": password})
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("当前密码错误时修改邮箱: %d %s", rec.Code, rec.Body)
+			}
+			if rec := s.do(http.MethodPost, "/login", "", map[string]string{"id_card": idCard, "password": password}); rec.Code != http.StatusUnauthorized {
+				t.Errorf("旧密码仍可登录: %d %s", rec.Code, rec.Body)
+			}
+		})
+	}
+}
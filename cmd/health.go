@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// databaseCheck 检查连接池能否连通数据库
+func databaseCheck(db *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// schemaCheck 检查数据库已执行全部迁移，例如没有被其他实例回滚
+func schemaCheck(migrator *migrate.Migrator) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("有 %d 个迁移尚未执行", len(pending))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/migrations"
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// migrateSchema 执行未执行的迁移。关闭了启动时迁移的，只检查数据库是否已是最新版本。
+func migrateSchema(db *gorm.DB, cfg config.DatabaseConfig) (*migrate.Migrator, error) {
+	migrator, err := migrate.New(db, migrations.All(), cfg.MigrateLockTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if !cfg.MigrateOnStart {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if len(pending) > 0 {
+			return nil, fmt.Errorf("有 %d 个迁移尚未执行，请先运行 migrate up", len(pending))
+		}
+		return migrator, nil
+	}
+
+	applied, err := migrator.Up(ctx)
+	for _, m := range applied {
+		log.Printf("已执行迁移 %d_%s", m.Version, m.Name)
+	}
+	return migrator, err
+}
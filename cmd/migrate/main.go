@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/migrations"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+)
+
+const usage = `用法: migrate [参数] <命令>
+
+命令:
+  up          执行所有未执行的迁移
+  down [N]    回滚最近执行的 N 个迁移，默认 1 个
+  status      查看各迁移的执行情况
+
+参数:
+`
+
+// 管理数据库迁移。多个实例或多次执行时通过数据库锁保证同一时间只有一处在迁移。
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Printf("加载配置失败: %v", err)
+		os.Exit(1)
+	}
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// 数据迁移需要加密个人信息
+	keyring, err := fieldcrypt.LoadKeyring(fieldcrypt.KeySource{
+		KeyFile:  cfg.PII.KeyFile,
+		Keys:     cfg.PII.Keys,
+		Active:   cfg.PII.ActiveKey,
+		IndexKey: cfg.PII.IndexKey,
+
+		AllowLegacy: cfg.PII.AllowLegacy,
+	})
+	if err != nil {
+		log.Printf("加载密钥失败: %v", err)
+		os.Exit(1)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		log.Printf("连接数据库失败: %v", err)
+		os.Exit(1)
+	}
+	migrator, err := migrate.New(db, migrations.All(), cfg.Database.MigrateLockTimeout)
+	if err != nil {
+		log.Printf("加载迁移失败: %v", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			log.Printf("已执行 %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("迁移失败: %v", err)
+			os.Exit(1)
+		}
+		if len(applied) == 0 {
+			log.Println("数据库已是最新版本")
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				log.Printf("回滚数量必须是正整数: %s", args[1])
+				os.Exit(2)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, m := range reverted {
+			log.Printf("已回滚 %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("回滚失败: %v", err)
+			os.Exit(1)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Printf("查询迁移状态失败: %v", err)
+			os.Exit(1)
+		}
+		for _, s := range statuses {
+			state := "未执行"
+			switch {
+			case s.Unknown:
+				state = "未知版本，执行于 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			case s.AppliedAt != nil:
+				state = "已执行于 " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-24s %s\n", s.Version, s.Name, state)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/liuyifan1996/course-selection-system/api/repository"
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+)
+
+// 使用当前主密钥重新加密所有个人信息。
+// 轮换步骤：在密钥配置中加入新密钥并设为 active，重启服务后执行本命令，
+// 确认完成后再从配置中移除旧密钥。
+func main() {
+	batchSize := flag.Int("batch", 500, "每批处理的行数")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Printf("加载配置失败: %v", err)
+		os.Exit(1)
+	}
+
+	keyring, err := fieldcrypt.LoadKeyring(fieldcrypt.KeySource{
+		KeyFile:  cfg.PII.KeyFile,
+		Keys:     cfg.PII.Keys,
+		Active:   cfg.PII.ActiveKey,
+		IndexKey: cfg.PII.IndexKey,
+
+		AllowLegacy: cfg.PII.AllowLegacy,
+	})
+	if err != nil {
+		log.Printf("加载密钥失败: %v", err)
+		os.Exit(1)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		log.Printf("连接数据库失败: %v", err)
+		os.Exit(1)
+	}
+
+	updated, err := repository.RotateEncryptedColumns(db, keyring, *batchSize)
+	if err != nil {
+		log.Printf("重新加密失败（已更新 %d 行）: %v", updated, err)
+		os.Exit(1)
+	}
+	log.Printf("重新加密完成，共更新 %d 行，当前密钥: %s", updated, keyring.Active)
+}
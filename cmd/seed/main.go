@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/migrations"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+	"github.com/liuyifan1996/course-selection-system/seed"
+)
+
+// 向数据库写入演示数据：院系、专业、行政班、教师、学生、课程和选课记录。
+// 相同的 -seed 和数量总是生成相同的数据，重复执行只补齐缺少的行。需要先执行 migrate up。
+// 生成的账号使用 -password 指定的密码，为防止误对生产库执行，数据库中已有用户时需加 -allow-nonempty。
+func main() {
+	opts := seed.DefaultOptions
+	flag.Uint64Var(&opts.Seed, "seed", opts.Seed, "随机种子")
+	flag.IntVar(&opts.Teachers, "teachers", opts.Teachers, "教师人数")
+	flag.IntVar(&opts.Students, "students", opts.Students, "学生人数")
+	flag.IntVar(&opts.Courses, "courses", opts.Courses, "课程数量，同一门课程各学期开课只算一门")
+	flag.IntVar(&opts.Sessions, "sessions", opts.Sessions, "每门课程开课的学期数，最近一个学期开放选课，之前的学期已结课")
+	flag.IntVar(&opts.Enrollments, "enrollments", opts.Enrollments, "选课记录总数")
+	flag.StringVar(&opts.Password, "password", "", "生成用户的登录密码，必须指定")
+	allowNonEmpty := flag.Bool("allow-nonempty", false, "数据库中已有用户时仍然写入，重复执行生成数据时需要")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Printf("加载配置失败: %v", err)
+		os.Exit(1)
+	}
+	if opts.Password == "" {
+		log.Printf("请通过 -password 指定生成用户的登录密码")
+		os.Exit(1)
+	}
+
+	// 用户的证件号、姓名等需要加密保存
+	keyring, err := fieldcrypt.LoadKeyring(fieldcrypt.KeySource{
+		KeyFile:  cfg.PII.KeyFile,
+		Keys:     cfg.PII.Keys,
+		Active:   cfg.PII.ActiveKey,
+		IndexKey: cfg.PII.IndexKey,
+
+		AllowLegacy: cfg.PII.AllowLegacy,
+	})
+	if err != nil {
+		log.Printf("加载密钥失败: %v", err)
+		os.Exit(1)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	db, err := config.InitDB(cfg.Database)
+	if err != nil {
+		log.Printf("连接数据库失败: %v", err)
+		os.Exit(1)
+	}
+	migrator, err := migrate.New(db, migrations.All(), cfg.Database.MigrateLockTimeout)
+	if err != nil {
+		log.Printf("加载迁移失败: %v", err)
+		os.Exit(1)
+	}
+	pending, err := migrator.Pending(context.Background())
+	if err != nil {
+		log.Printf("查询迁移状态失败: %v", err)
+		os.Exit(1)
+	}
+	if len(pending) > 0 {
+		log.Printf("有 %d 个迁移尚未执行，请先运行 migrate up", len(pending))
+		os.Exit(1)
+	}
+
+	var users int64
+	if err := db.Table("users").Count(&users).Error; err != nil {
+		log.Printf("查询用户数量失败: %v", err)
+		os.Exit(1)
+	}
+	if users > 0 && !*allowNonEmpty {
+		log.Printf("数据库中已有 %d 个用户，确认不是生产库后加 -allow-nonempty 重新执行", users)
+		os.Exit(1)
+	}
+
+	result, err := seed.Run(db, opts)
+	if err != nil {
+		log.Printf("生成数据失败: %v", err)
+		os.Exit(1)
+	}
+
+	c := result.Created
+	fmt.Printf("院系 %d、专业 %d、行政班 %d、用户 %d、课程 %d、选课记录 %d 为新写入\n",
+		c.Departments, c.Majors, c.Cohorts, c.Users, c.Courses, c.Enrollments)
+	fmt.Printf("共 %d 名教师、%d 名学生、%d 个开课班级、%d 条选课记录\n",
+		len(result.Teachers), len(result.Students), len(result.Courses), result.Enrollments)
+	if result.Enrollments < opts.Enrollments {
+		fmt.Printf("受名额、专业和年级限制，选课记录少于要求的 %d 条\n", opts.Enrollments)
+	}
+}
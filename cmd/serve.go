@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg/certreload"
+	"github.com/liuyifan1996/course-selection-system/pkg/health"
+)
+
+// RootHandler 在 Setup 完成前只响应健康检查，其余请求返回 503；
+// 完成后将所有请求交给业务路由。
+type RootHandler struct {
+	probes *health.Checker
+	app    atomic.Pointer[http.Handler]
+}
+
+func NewRootHandler(probes *health.Checker) *RootHandler {
+	return &RootHandler{probes: probes}
+}
+
+// SetApp 设置业务路由并将服务标记为运行中
+func (h *RootHandler) SetApp(app http.Handler) {
+	h.app.Store(&app)
+	h.probes.MarkRunning()
+}
+
+func (h *RootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if app := h.app.Load(); app != nil {
+		(*app).ServeHTTP(w, r)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/healthz":
+		h.probes.LivenessHandler().ServeHTTP(w, r)
+	case "/readyz":
+		h.probes.ReadinessHandler().ServeHTTP(w, r)
+	default:
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"服务正在启动"}`))
+	}
+}
+
+// Serve 启动 HTTP 服务。ctx 取消后先将 probes 标记为停止，经过 ShutdownDelay 让负载均衡摘除实例，
+// 再停止接收新连接，并在 ShutdownTimeout 内等待进行中的请求完成
+func Serve(ctx context.Context, cfg config.ServerConfig, handler http.Handler, probes *health.Checker) error {
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+
+	useTLS := cfg.TLS.CertFile != ""
+	if useTLS {
+		certs, err := certreload.New(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ReloadInterval)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if useTLS {
+			log.Printf("服务器启动在 %s（HTTPS）", cfg.Addr)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("服务器启动在 %s", cfg.Addr)
+			err = srv.ListenAndServe()
+		}
+		errCh <- err
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	probes.MarkStopping()
+	if cfg.ShutdownDelay > 0 {
+		log.Printf("就绪检查已返回失败，%s 后停止接收新连接", cfg.ShutdownDelay)
+		select {
+		case err := <-errCh:
+			return err
+		case <-time.After(cfg.ShutdownDelay):
+		}
+	}
+
+	log.Printf("正在停止服务器，最多等待 %s", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
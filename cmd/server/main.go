@@ -1,23 +1,54 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/liuyifan1996/course-selection-system/cmd"
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg/health"
 )
 
 func main() {
-	// 设置环境变量
-	os.Setenv("JWT_SECRET_KEY", "3a1f8d7e4c9b2a5f6e8c3d0a7b4e5f2d1c8e3f6a9d2b5c4e7f8a1d3e6c9b2a5")
-	os.Setenv("DSN", "root:root@tcp(127.0.0.1:3306)/cousle_sys?charset=utf8mb4&parseTime=True&loc=Local")
+	cfg, err := config.Load(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Printf("加载配置失败: %v", err)
+		os.Exit(1)
+	}
+
+	// 收到 SIGINT 或 SIGTERM 后停止接收新请求，等待进行中的请求完成
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 先启动服务器响应健康检查，初始化完成后才接收业务请求
+	probes := health.NewChecker(2 * time.Second)
+	root := cmd.NewRootHandler(probes)
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- cmd.Serve(ctx, cfg.Server, root, probes)
+	}()
 
-	r := cmd.Setup()
+	app, err := cmd.Setup(cfg, probes, cmd.Dependencies{})
+	if err != nil {
+		log.Printf("初始化失败: %v", err)
+		os.Exit(1)
+	}
+	root.SetApp(app.Router)
 
-	// 启动服务器
-	log.Println("服务器启动在 :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Printf("服务器启动失败: %v", err)
+	err = <-serveErr
+	if err != nil {
+		log.Printf("服务器异常退出: %v", err)
+	}
+	if err := app.Close(); err != nil {
+		log.Printf("关闭数据库连接失败: %v", err)
+	}
+	if err != nil {
 		os.Exit(1)
 	}
+	log.Println("服务器已停止")
 }
@@ -1,61 +1,189 @@
 package cmd
 
 import (
-	"log"
+	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liuyifan1996/course-selection-system/api/handler"
 	"github.com/liuyifan1996/course-selection-system/api/middleware"
-	"github.com/liuyifan1996/course-selection-system/api/model"
 	"github.com/liuyifan1996/course-selection-system/api/repository"
 	"github.com/liuyifan1996/course-selection-system/api/service"
 	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/breach"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"github.com/liuyifan1996/course-selection-system/pkg/health"
+	"github.com/liuyifan1996/course-selection-system/pkg/notify"
+	"gorm.io/gorm"
 )
 
-func Setup() *gin.Engine {
-	// 初始化数据库
-	db, err := config.InitDB()
+// App 组装好的服务
+type App struct {
+	Router *gin.Engine
+	DB     *gorm.DB
+}
+
+// Close 关闭数据库连接池，应在 HTTP 服务停止后调用
+func (a *App) Close() error {
+	sqlDB, err := a.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Dependencies 由调用方提供的依赖，为 nil 的字段按配置创建。
+// 测试可以传入 SQLite 数据库、内存仓库或记录通知的 Notifier，启动完整的路由。
+type Dependencies struct {
+	DB           *gorm.DB        // 为 nil 时按 cfg.Database 连接，两种情况都会按配置执行迁移
+	Repositories *Repositories   // 为 nil 时全部使用 DB
+	Notifier     notify.Notifier // 为 nil 时按 cfg.Notifier 创建
+}
+
+// Repositories 服务使用的全部仓库
+type Repositories struct {
+	Auth            repository.AuthRepository
+	Course          repository.CourseRepository
+	Enrollment      repository.EnrollmentRepository
+	LoginAttempts   repository.LoginAttemptRepository
+	PasswordResets  repository.PasswordResetRepository
+	PasswordHistory repository.PasswordHistoryRepository
+	Organizations   repository.OrganizationRepository
+	Programs        repository.ProgramRepository
+}
 
+// NewGormRepositories 基于数据库的仓库，登录失败记录按 cfg.Store 保存在内存或数据库中
+func NewGormRepositories(db *gorm.DB, cfg config.LoginThrottleConfig) *Repositories {
+	return &Repositories{
+		Auth:            repository.NewGormAuthRepository(db),
+		Course:          repository.NewGormCourseRepository(db),
+		Enrollment:      repository.NewGormEnrollmentRepository(db),
+		LoginAttempts:   newLoginAttemptRepository(db, cfg.Store, loginThrottlePolicy(cfg)),
+		PasswordResets:  repository.NewGormPasswordResetRepository(db),
+		PasswordHistory: repository.NewGormPasswordHistoryRepository(db),
+		Organizations:   repository.NewGormOrganizationRepository(db),
+		Programs:        repository.NewGormProgramRepository(db),
+	}
+}
+
+// Setup 初始化依赖并注册路由，同时向 probes 注册就绪检查项
+func Setup(cfg *config.Config, probes *health.Checker, deps Dependencies) (*App, error) {
+	tokens, err := newTokenSigner(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("加载 JWT 签名密钥失败: %w", err)
+	}
+	cursors, err := pkg.NewCursorSigner(cfg.Cursor.Secret)
 	if err != nil {
-		log.Printf("Failed to initialize database: %v", err)
-		os.Exit(1)
+		return nil, err
+	}
+
+	// 加载个人信息加密密钥
+	keyring, err := fieldcrypt.LoadKeyring(piiKeySource(cfg.PII))
+	if err != nil {
+		return nil, fmt.Errorf("加载加密密钥失败: %w", err)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	// 初始化数据库
+	db := deps.DB
+	if db == nil {
+		if db, err = config.InitDB(cfg.Database); err != nil {
+			return nil, fmt.Errorf("初始化数据库失败: %w", err)
+		}
 	}
+	app := &App{DB: db}
 
-	// 自动迁移模型
-	if err := db.AutoMigrate(&model.User{}, &model.Course{}, &model.Enrollment{}); err != nil {
-		log.Printf("Failed to migrate database: %v", err)
-		os.Exit(1)
+	// 执行数据库迁移
+	migrator, err := migrateSchema(db, cfg.Database)
+	if err != nil {
+		app.Close()
+		return nil, fmt.Errorf("数据库迁移失败: %w", err)
 	}
 
+	probes.Add("database", databaseCheck(db))
+	probes.Add("migrations", schemaCheck(migrator))
+
 	// 初始化仓库
-	authrepo := repository.NewGormAuthRepository(db)
-	courserepo := repository.NewGormCourseRepository(db)
-	enrollmentrepo := repository.NewEnrollmentRepository(db)
+	repos := deps.Repositories
+	if repos == nil {
+		repos = NewGormRepositories(db, cfg.LoginThrottle)
+	}
+	notifier := deps.Notifier
+	if notifier == nil {
+		notifier = newNotifier(cfg.Notifier)
+	}
 
 	// 初始化服务
-	authService := service.NewAuthService(authrepo)
-	courseService := service.NewCourseService(courserepo, authrepo)
-	enrollmentService := service.NewEnrollmentService(enrollmentrepo)
+	passwordPolicy, err := loadPasswordPolicy(cfg.Password.PolicyFile)
+	if err != nil {
+		app.Close()
+		return nil, fmt.Errorf("加载密码策略失败: %w", err)
+	}
+	breached, err := newBreachChecker(cfg.Password.BreachedFile)
+	if err != nil {
+		app.Close()
+		return nil, fmt.Errorf("加载泄露密码库失败: %w", err)
+	}
+	passwordValidator := service.NewPasswordValidator(passwordPolicy, breached, repos.PasswordHistory)
+	loginThrottle := service.NewLoginThrottle(repos.LoginAttempts, loginThrottlePolicy(cfg.LoginThrottle))
+	authService := service.NewAuthService(repos.Auth, tokens, loginThrottle, passwordValidator, mfaPolicy(cfg.MFA), service.LogAuditLogger{})
+	passwordService := service.NewPasswordService(repos.Auth, repos.PasswordResets, passwordValidator, tokens, notifier, passwordResetPolicy(cfg.Password), service.LogAuditLogger{}, authService)
+	courseService := service.NewCourseService(repos.Course, repos.Auth, repos.Organizations, cursors, cfg.Search.RefreshInterval)
+	enrollmentService := service.NewEnrollmentService(repos.Enrollment, cursors)
+	profileService := service.NewProfileService(repos.Auth, repos.Organizations, courseService, authService)
+	orgService := service.NewOrganizationService(repos.Organizations, repos.Auth)
+	programService := service.NewProgramService(repos.Programs, repos.Organizations, repos.Enrollment)
+
+	// 构建课程全文索引
+	if err := courseService.RebuildSearchIndex(); err != nil {
+		app.Close()
+		return nil, fmt.Errorf("构建全文索引失败: %w", err)
+	}
 
 	// 初始化处理器
 	authHandler := handler.NewAuthHandler(authService)
-	courseHandler := handler.NewCourseHandler(courseService)
-	enrollHandler := handler.NewEnrollmentHandler(enrollmentService)
+	passwordHandler := handler.NewPasswordHandler(passwordService)
+	profileHandler := handler.NewProfileHandler(profileService, cursors)
+	courseHandler := handler.NewCourseHandler(courseService, cursors)
+	enrollHandler := handler.NewEnrollmentHandler(enrollmentService, cursors)
+	adminHandler := handler.NewAdminHandler(authService)
+	orgHandler := handler.NewOrganizationHandler(orgService)
+	programHandler := handler.NewProgramHandler(programService)
+	jwksHandler := handler.NewJWKSHandler(tokens)
 
 	// 设置路由
 	r := gin.Default()
+	// 登录限流、审计日志等依赖客户端 IP，只信任配置的反向代理转发的 X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		app.Close()
+		return nil, fmt.Errorf("配置可信代理失败: %w", err)
+	}
+
+	// 健康检查
+	r.GET("/healthz", gin.WrapH(probes.LivenessHandler()))
+	r.GET("/readyz", gin.WrapH(probes.ReadinessHandler()))
 
 	// 公共路由
 	r.POST("/register", authHandler.Register)
 	r.POST("/login", authHandler.Login)
+	r.POST("/login/mfa", authHandler.CompleteMFALogin)
+	r.POST("/login/mfa/setup", authHandler.SetupMFAWithChallenge)
+	r.POST("/password/forgot", passwordHandler.ForgotPassword)
+	r.POST("/password/reset", passwordHandler.ResetPassword)
+	r.GET("/.well-known/jwks.json", jwksHandler.JWKS)
 
 	// 需要认证的路由
-	auth := r.Group("/").Use(middleware.AuthMiddleware())
+	auth := r.Group("/").Use(middleware.AuthMiddleware(tokens, authService))
 	{
 		// 课程相关
 		auth.POST("/courses/create", courseHandler.CreateCourse)
 		auth.GET("/courses", courseHandler.GetCourses)
+		auth.GET("/courses/search", courseHandler.SearchCourses)
+		auth.GET("/courses/fulltext", courseHandler.FullTextSearch)
+		auth.GET("/courses/:id", courseHandler.GetCourse)
 		auth.DELETE("/courses/:id", courseHandler.DeleteCourse)
 		auth.GET("/courses-teacherid/:id", courseHandler.GetTeacherCourses)
 		auth.GET("/courses-teachername/:teachername", courseHandler.GetCoursesByTeacherName)
@@ -66,7 +194,189 @@ func Setup() *gin.Engine {
 		auth.POST("/courses/:id/enroll", enrollHandler.Enroll)
 		auth.GET("/student-courses", enrollHandler.GetStudentCourses)
 		auth.DELETE("/courses/:id/enroll", enrollHandler.DeleteEnroll)
+		auth.POST("/courses/:id/results", enrollHandler.RecordResult)
+
+		// 个人资料
+		auth.GET("/me", profileHandler.GetProfile)
+		auth.PATCH("/me", profileHandler.UpdateProfile)
+		auth.GET("/teachers/:id", profileHandler.GetTeacherProfile)
+
+		// 院系、专业和行政班
+		auth.GET("/departments", orgHandler.ListDepartments)
+		auth.GET("/departments/:id/majors", orgHandler.ListMajors)
+		auth.GET("/majors/:id/cohorts", orgHandler.ListCohorts)
+		auth.GET("/majors/:id/program", programHandler.GetProgram)
+
+		// 学业审核
+		auth.GET("/me/degree-audit", programHandler.GetMyAudit)
+
+		// 账号安全
+		auth.POST("/me/password", passwordHandler.ChangePassword)
+		auth.POST("/me/mfa/setup", authHandler.SetupMFA)
+		auth.POST("/me/mfa/enable", authHandler.EnableMFA)
+		auth.DELETE("/me/mfa", authHandler.DisableMFA)
+		auth.POST("/me/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+	}
+
+	// 管理员路由
+	admin := r.Group("/admin").Use(middleware.AuthMiddleware(tokens, authService), middleware.RequireRole("admin"))
+	{
+		admin.POST("/users/:id/unlock", adminHandler.UnlockUser)
+		admin.PUT("/users/:id/organization", orgHandler.AssignOrganization)
+		admin.POST("/departments", orgHandler.CreateDepartment)
+		admin.POST("/majors", orgHandler.CreateMajor)
+		admin.POST("/cohorts", orgHandler.CreateCohort)
+		admin.PUT("/majors/:id/program", programHandler.SaveProgram)
+		admin.GET("/users/:id/degree-audit", programHandler.GetStudentAudit)
 	}
 
-	return r
+	app.Router = r
+	return app, nil
+}
+
+// newTokenSigner 加载令牌签名密钥。未指定 ActiveKey 时，优先使用 Secret，其次使用 Keys 中的第一个。
+func newTokenSigner(cfg config.JWTConfig) (*pkg.TokenSigner, error) {
+	var keys []pkg.SigningKey
+	active := cfg.ActiveKey
+	if cfg.Secret != "" {
+		key := pkg.NewHMACKey("", []byte(cfg.Secret))
+		keys = append(keys, key)
+		if active == "" {
+			active = key.ID
+		}
+	}
+	for _, secret := range cfg.PreviousSecrets {
+		keys = append(keys, pkg.NewHMACKey("", []byte(secret)))
+	}
+
+	for _, kc := range cfg.Keys {
+		var key pkg.SigningKey
+		switch {
+		case kc.Secret != "":
+			key = pkg.NewHMACKey(kc.ID, []byte(kc.Secret))
+		case kc.PrivateKeyFile != "", kc.PublicKeyFile != "":
+			parse, path := pkg.ParsePrivateKeyPEM, kc.PrivateKeyFile
+			if path == "" {
+				parse, path = pkg.ParsePublicKeyPEM, kc.PublicKeyFile
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if key, err = parse(kc.ID, data); err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+		}
+		keys = append(keys, key)
+		if active == "" {
+			active = key.ID
+		}
+	}
+	return pkg.NewTokenSigner(active, keys...)
+}
+
+// piiKeySource 个人信息加密密钥的配置
+func piiKeySource(cfg config.PIIConfig) fieldcrypt.KeySource {
+	return fieldcrypt.KeySource{KeyFile: cfg.KeyFile, Keys: cfg.Keys, Active: cfg.ActiveKey, IndexKey: cfg.IndexKey, AllowLegacy: cfg.AllowLegacy}
+}
+
+// newLoginAttemptRepository 多实例部署时使用 db 共享登录失败记录
+func newLoginAttemptRepository(db *gorm.DB, store string, policy service.LoginThrottlePolicy) repository.LoginAttemptRepository {
+	if store == "db" {
+		return repository.NewGormLoginAttemptRepository(db)
+	}
+	return repository.NewMemoryLoginAttemptRepository(policy.ResetAfter)
+}
+
+// loginThrottlePolicy 配置中未设置的字段使用默认策略
+func loginThrottlePolicy(cfg config.LoginThrottleConfig) service.LoginThrottlePolicy {
+	policy := service.DefaultLoginThrottlePolicy
+	if cfg.MaxAccountFailures > 0 {
+		policy.MaxAccountFailures = cfg.MaxAccountFailures
+	}
+	if cfg.MaxIPFailures > 0 {
+		policy.MaxIPFailures = cfg.MaxIPFailures
+	}
+	if cfg.BaseDelay > 0 {
+		policy.BaseDelay = cfg.BaseDelay
+	}
+	if cfg.MaxDelay > 0 {
+		policy.MaxDelay = cfg.MaxDelay
+	}
+	if cfg.LockoutDuration > 0 {
+		policy.LockoutDuration = cfg.LockoutDuration
+	}
+	if cfg.ResetAfter > 0 {
+		policy.ResetAfter = cfg.ResetAfter
+	}
+	return policy
+}
+
+// mfaPolicy 指定必须启用两步验证的角色（如 teacher、admin）
+func mfaPolicy(cfg config.MFAConfig) service.MFAPolicy {
+	policy := service.DefaultMFAPolicy
+	if cfg.Issuer != "" {
+		policy.Issuer = cfg.Issuer
+	}
+	for _, role := range cfg.RequiredRoles {
+		if role = strings.TrimSpace(role); role != "" {
+			policy.RequiredRoles = append(policy.RequiredRoles, role)
+		}
+	}
+	return policy
+}
+
+// newNotifier 按配置选择通知方式：smtp、file 或 log，配置已由 Validate 校验
+func newNotifier(cfg config.NotifierConfig) notify.Notifier {
+	switch cfg.Type {
+	case "smtp":
+		return &notify.SMTPNotifier{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}
+	case "file":
+		return notify.NewFileNotifier(cfg.File)
+	default:
+		return notify.LogNotifier{}
+	}
+}
+
+// passwordResetPolicy 重置页面地址如 https://example.com/reset?token=%s
+func passwordResetPolicy(cfg config.PasswordConfig) service.PasswordResetPolicy {
+	policy := service.DefaultPasswordResetPolicy
+	policy.URL = cfg.ResetURL
+	if cfg.ResetTokenTTL > 0 {
+		policy.TokenTTL = cfg.ResetTokenTTL
+	}
+	if cfg.ResetResendInterval > 0 {
+		policy.ResendInterval = cfg.ResetResendInterval
+	}
+	return policy
+}
+
+// loadPasswordPolicy 从 JSON 文件读取密码策略，未指定的字段使用默认值
+func loadPasswordPolicy(path string) (service.PasswordPolicy, error) {
+	policy := service.DefaultPasswordPolicy
+	if path == "" {
+		return policy, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return policy, err
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return policy, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+func newBreachChecker(path string) (breach.Checker, error) {
+	if path == "" {
+		return breach.NopChecker{}, nil
+	}
+	return breach.NewFileChecker(path)
 }
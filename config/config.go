@@ -0,0 +1,444 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile 未通过 -config 或 CONFIG_FILE 指定时，存在则读取的配置文件
+const DefaultConfigFile = "config/config.yaml"
+
+// Config 服务配置。优先级从低到高：默认值、配置文件、环境变量（含 .env 文件）、命令行参数。
+// 字段的 env 标签为对应的环境变量名；时长使用 Go 的格式，如 15m、1h。
+// 登录限流、两步验证和找回密码中为零值的字段使用 service 包中的默认策略。
+type Config struct {
+	Server        ServerConfig        `yaml:"server"`
+	Database      DatabaseConfig      `yaml:"database"`
+	JWT           JWTConfig           `yaml:"jwt"`
+	Cursor        CursorConfig        `yaml:"cursor"`
+	Search        SearchConfig        `yaml:"search"`
+	PII           PIIConfig           `yaml:"pii"`
+	LoginThrottle LoginThrottleConfig `yaml:"login_throttle"`
+	MFA           MFAConfig           `yaml:"mfa"`
+	Password      PasswordConfig      `yaml:"password"`
+	Notifier      NotifierConfig      `yaml:"notifier"`
+}
+
+type ServerConfig struct {
+	Addr              string        `yaml:"addr" env:"SERVER_ADDR"` // 监听地址，如 :8080
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" env:"SERVER_MAX_HEADER_BYTES"`
+	// ShutdownDelay 收到退出信号后就绪检查先返回失败，经过该时间再停止接收新连接，
+	// 应大于负载均衡检查就绪状态的间隔，否则摘除实例前转发来的请求会连接失败
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY"`
+	// ShutdownTimeout 停止接收新连接后等待进行中请求完成的最长时间
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，环境变量中以逗号分隔。
+	// 只有来自这些地址的请求才读取 X-Forwarded-For 获取客户端 IP，默认不信任任何代理
+	TrustedProxies []string  `yaml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+	TLS            TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig 证书和私钥均配置时启用 HTTPS，文件更新后自动重新加载
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile        string        `yaml:"key_file" env:"TLS_KEY_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"TLS_RELOAD_INTERVAL"` // 检查证书文件是否更新的间隔
+}
+
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" env:"DB_DRIVER"` // mysql、postgres 或 sqlite
+	DSN             string        `yaml:"dsn" env:"DSN"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"` // 0 表示不限制
+	// MigrateOnStart 启动时执行未执行的迁移；关闭时存在未执行的迁移则拒绝启动，需先运行 migrate up
+	MigrateOnStart     bool          `yaml:"migrate_on_start" env:"DB_MIGRATE_ON_START"`
+	MigrateLockTimeout time.Duration `yaml:"migrate_lock_timeout" env:"DB_MIGRATE_LOCK_TIMEOUT"` // 等待其他实例完成迁移的时间
+}
+
+// JWTConfig 令牌签名密钥。Secret 为 HS256 密钥，未指定 ActiveKey 时用于签发新令牌；
+// 轮换时将旧密钥移到 PreviousSecrets，旧令牌在过期前仍然有效。
+// Keys 可配置带 ID 的密钥，包括 RS256 和 EdDSA 的 PEM 私钥或只用于验证的公钥。
+type JWTConfig struct {
+	Secret          string         `yaml:"secret" env:"JWT_SECRET_KEY"`
+	PreviousSecrets []string       `yaml:"previous_secrets" env:"JWT_PREVIOUS_SECRET_KEYS"` // 环境变量中以逗号分隔
+	ActiveKey       string         `yaml:"active_key" env:"JWT_ACTIVE_KEY"`
+	Keys            []JWTKeyConfig `yaml:"keys"`
+}
+
+// JWTKeyConfig Secret、PrivateKeyFile、PublicKeyFile 三者只能配置一个
+type JWTKeyConfig struct {
+	ID             string `yaml:"id"`
+	Secret         string `yaml:"secret"`
+	PrivateKeyFile string `yaml:"private_key_file"`
+	PublicKeyFile  string `yaml:"public_key_file"`
+}
+
+type CursorConfig struct {
+	Secret string `yaml:"secret" env:"CURSOR_SECRET_KEY"` // 分页游标签名密钥，必须配置，不能与 JWT 密钥相同
+}
+
+// SearchConfig 课程全文检索。索引保存在各实例的内存中，本实例的修改立即生效，
+// 其他实例的修改在距上次重建超过 RefreshInterval 后的下一次检索时从数据库重建得到
+type SearchConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"SEARCH_REFRESH_INTERVAL"` // 为 0 时不重建，只适用于单实例部署
+}
+
+// PIIConfig 个人信息加密密钥，格式见 pkg/fieldcrypt
+type PIIConfig struct {
+	KeyFile   string `yaml:"key_file" env:"PII_KEYFILE"`
+	Keys      string `yaml:"keys" env:"PII_KEYS"` // k1:base64,k2:base64
+	ActiveKey string `yaml:"active_key" env:"PII_ACTIVE_KEY"`
+	IndexKey  string `yaml:"index_key" env:"PII_INDEX_KEY"`
+
+	// AllowLegacy 读取时接受明文和未绑定位置的 v1 密文。迁移会自动升级旧数据，
+	// 只在新旧版本混合部署期间临时开启，关闭时读到这类值会报错，防止被替换为明文而不被发现。
+	AllowLegacy bool `yaml:"allow_legacy" env:"PII_ALLOW_LEGACY"`
+}
+
+type LoginThrottleConfig struct {
+	Store              string        `yaml:"store" env:"LOGIN_ATTEMPT_STORE"` // memory 或 db，多实例部署时使用 db
+	MaxAccountFailures int           `yaml:"max_account_failures" env:"LOGIN_MAX_ACCOUNT_FAILURES"`
+	MaxIPFailures      int           `yaml:"max_ip_failures" env:"LOGIN_MAX_IP_FAILURES"`
+	BaseDelay          time.Duration `yaml:"base_delay" env:"LOGIN_BASE_DELAY"`
+	MaxDelay           time.Duration `yaml:"max_delay" env:"LOGIN_MAX_DELAY"`
+	LockoutDuration    time.Duration `yaml:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+	ResetAfter         time.Duration `yaml:"reset_after" env:"LOGIN_RESET_AFTER"`
+}
+
+type MFAConfig struct {
+	Issuer        string   `yaml:"issuer" env:"MFA_ISSUER"`
+	RequiredRoles []string `yaml:"required_roles" env:"MFA_REQUIRED_ROLES"` // 环境变量中以逗号分隔
+}
+
+type PasswordConfig struct {
+	PolicyFile string `yaml:"policy_file" env:"PASSWORD_POLICY_FILE"` // JSON 格式的密码策略
+	// BreachedFile 已泄露密码库的文件或目录，为空时不检查，格式见 pkg/breach
+	BreachedFile        string        `yaml:"breached_file" env:"PASSWORD_BREACHED_FILE"`
+	ResetURL            string        `yaml:"reset_url" env:"PASSWORD_RESET_URL"` // 含一个 %s 占位符
+	ResetTokenTTL       time.Duration `yaml:"reset_token_ttl" env:"PASSWORD_RESET_TOKEN_TTL"`
+	ResetResendInterval time.Duration `yaml:"reset_resend_interval" env:"PASSWORD_RESET_RESEND_INTERVAL"`
+}
+
+type NotifierConfig struct {
+	Type string     `yaml:"type" env:"NOTIFIER"` // file、smtp 或仅用于本地开发的 log，没有默认值
+	File string     `yaml:"file" env:"NOTIFIER_FILE"`
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host" env:"SMTP_HOST"`
+	Port     int    `yaml:"port" env:"SMTP_PORT"`
+	Username string `yaml:"username" env:"SMTP_USERNAME"`
+	Password string `yaml:"password" env:"SMTP_PASSWORD"`
+	From     string `yaml:"from" env:"SMTP_FROM"`
+}
+
+// Default 默认配置，不含任何密钥
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			TLS:               TLSConfig{ReloadInterval: time.Minute},
+		},
+		Database:      DatabaseConfig{Driver: DriverMySQL, MaxIdleConns: 10, MaxOpenConns: 100, MigrateOnStart: true, MigrateLockTimeout: time.Minute},
+		Search:        SearchConfig{RefreshInterval: time.Minute},
+		LoginThrottle: LoginThrottleConfig{Store: "memory"},
+		Notifier:      NotifierConfig{SMTP: SMTPConfig{Port: 587}},
+	}
+}
+
+// weakSecrets 曾经写在代码中或常见的示例密钥，不能用作 JWT 密钥
+var weakSecrets = []string{
+	"3a1f8d7e4c9b2a5f6e8c3d0a7b4e5f2d1c8e3f6a9d2b5c4e7f8a1d3e6c9b2a5",
+	"secret",
+	"changeme",
+	"your-secret-key",
+}
+
+const minSecretLength = 32
+
+// Load 在 fs 上注册配置相关的命令行参数并解析 args，然后按优先级合并配置并校验。
+// 调用方可以在 fs 上注册自己的参数。
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	configFile := fs.String("config", "", "配置文件路径（YAML），默认读取 "+DefaultConfigFile)
+	envFile := fs.String("env-file", ".env", ".env 文件路径，不会覆盖已存在的环境变量")
+	addr := fs.String("addr", "", "监听地址，如 :8080")
+	dsn := fs.String("dsn", "", "数据库连接串")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if err := godotenv.Load(*envFile); err != nil && (set["env-file"] || !errors.Is(err, os.ErrNotExist)) {
+		return nil, fmt.Errorf("读取 %s 失败: %w", *envFile, err)
+	}
+
+	cfg := Default()
+
+	path, required := *configFile, set["config"]
+	if !required {
+		if path, required = os.LookupEnv("CONFIG_FILE"); !required {
+			path = DefaultConfigFile
+		}
+	}
+	if err := cfg.loadFile(path, required); err != nil {
+		return nil, err
+	}
+
+	if err := applyEnv(reflect.ValueOf(&cfg).Elem()); err != nil {
+		return nil, err
+	}
+
+	if set["addr"] {
+		cfg.Server.Addr = *addr
+	}
+	if set["dsn"] {
+		cfg.Database.DSN = *dsn
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// loadFile 读取 YAML 配置文件，文件中未出现的字段保持原值。
+// required 为 false 时文件不存在不报错。
+func (c *Config) loadFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv 按 env 标签用环境变量覆盖配置，未设置或为空的环境变量不覆盖
+func applyEnv(v reflect.Value) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		raw := strings.TrimSpace(os.Getenv(name))
+		if raw == "" {
+			continue
+		}
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("环境变量 %s 格式不正确: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的类型 %s", v.Type())
+	}
+	return nil
+}
+
+// Validate 检查配置是否可以启动服务，返回所有问题
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("未配置监听地址")
+	}
+	for _, d := range []time.Duration{c.Server.ReadHeaderTimeout, c.Server.ReadTimeout, c.Server.WriteTimeout,
+		c.Server.IdleTimeout, c.Server.ShutdownDelay, c.Server.ShutdownTimeout, c.Server.TLS.ReloadInterval} {
+		if d < 0 {
+			add("服务器超时时间不能为负数")
+			break
+		}
+	}
+	if c.Server.MaxHeaderBytes <= 0 {
+		add("请求头大小上限必须大于0")
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		add("启用 TLS 时必须同时配置证书和私钥")
+	}
+
+	switch c.Database.Driver {
+	case DriverMySQL, DriverPostgres, DriverSQLite:
+	default:
+		add("不支持的数据库: %s", c.Database.Driver)
+	}
+	if c.Database.DSN == "" {
+		add("未配置数据库连接串（DSN）")
+	}
+	if c.Database.MaxOpenConns <= 0 {
+		add("数据库最大连接数必须大于0")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("数据库空闲连接数必须在0到最大连接数之间")
+	}
+	if c.Database.MigrateLockTimeout < time.Second {
+		add("迁移锁等待时间不能小于1秒")
+	}
+
+	if c.JWT.Secret == "" && len(c.JWT.Keys) == 0 {
+		add("未配置 JWT 密钥（JWT_SECRET_KEY）")
+	}
+	secrets := append([]string{c.JWT.Secret}, c.JWT.PreviousSecrets...)
+	for _, k := range c.JWT.Keys {
+		n := 0
+		for _, v := range []string{k.Secret, k.PrivateKeyFile, k.PublicKeyFile} {
+			if v != "" {
+				n++
+			}
+		}
+		if n != 1 {
+			add("JWT 密钥 %q 必须且只能配置 secret、private_key_file、public_key_file 中的一个", k.ID)
+		}
+		secrets = append(secrets, k.Secret)
+	}
+	for _, secret := range secrets {
+		switch {
+		case secret == "":
+		case isWeakSecret(secret):
+			add("JWT 密钥是公开的默认值，请重新生成")
+		case len(secret) < minSecretLength:
+			add("JWT 密钥长度不能少于%d个字符", minSecretLength)
+		}
+	}
+
+	switch {
+	case c.Cursor.Secret == "":
+		add("未配置分页游标密钥（CURSOR_SECRET_KEY）")
+	case len(c.Cursor.Secret) < minSecretLength:
+		add("分页游标密钥长度不能少于%d个字符", minSecretLength)
+	case slices.Contains(secrets, c.Cursor.Secret):
+		add("分页游标密钥不能与 JWT 密钥相同")
+	}
+
+	if c.Search.RefreshInterval < 0 {
+		add("全文索引重建间隔不能为负数")
+	}
+
+	if c.PII.KeyFile == "" && c.PII.Keys == "" {
+		add("未配置个人信息加密密钥（PII_KEYFILE 或 PII_KEYS）")
+	}
+
+	if c.LoginThrottle.Store != "memory" && c.LoginThrottle.Store != "db" {
+		add("登录失败记录的存储方式只能是 memory 或 db")
+	}
+
+	switch c.Notifier.Type {
+	case "":
+		add("未配置通知方式（NOTIFIER），可选 file 或 smtp，本地开发可使用 log")
+	case "log":
+	case "file":
+		if c.Notifier.File == "" {
+			add("通知方式为 file 时必须配置 NOTIFIER_FILE")
+		}
+	case "smtp":
+		if c.Notifier.SMTP.Host == "" || c.Notifier.SMTP.From == "" {
+			add("通知方式为 smtp 时必须配置 SMTP_HOST 和 SMTP_FROM")
+		}
+		if c.Notifier.SMTP.Port <= 0 || c.Notifier.SMTP.Port > 65535 {
+			add("SMTP 端口不正确")
+		}
+	default:
+		add("通知方式只能是 log、file 或 smtp")
+	}
+
+	if c.Password.BreachedFile != "" {
+		if _, err := os.Stat(c.Password.BreachedFile); err != nil {
+			add("已泄露密码库不可用: %v", err)
+		}
+	}
+	if c.Password.ResetURL != "" && strings.Count(c.Password.ResetURL, "%s") != 1 {
+		add("密码重置地址必须包含一个 %%s 占位符")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("配置不正确：" + strings.Join(problems, "；"))
+	}
+	return nil
+}
+
+func isWeakSecret(secret string) bool {
+	for _, weak := range weakSecrets {
+		if strings.EqualFold(secret, weak) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,26 +2,85 @@ package config
 
 import (
 	"fmt"
-	"os"
+	"strings"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func InitDB() (*gorm.DB, error) {
+// 支持的数据库
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite" // 纯 Go 实现，用于本地开发和测试
+)
 
-	dsn := os.Getenv("DSN")
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		DisableForeignKeyConstraintWhenMigrating: true,
-	})
+func InitDB(cfg DatabaseConfig) (*gorm.DB, error) {
+	dialector, err := openDialector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect database: %v", err)
 	}
 
-	sqlDB, _ := db.DB()
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect database: %v", err)
+	}
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	// 内存数据库属于单个连接，连接关闭后数据即丢失
+	if cfg.Driver == DriverSQLite && isSQLiteMemory(cfg.DSN) {
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
 
 	return db, nil
 }
+
+func openDialector(cfg DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case DriverMySQL:
+		return mysql.Open(cfg.DSN), nil
+	case DriverPostgres:
+		return postgres.Open(cfg.DSN), nil
+	case DriverSQLite:
+		return sqlite.Open(sqliteDSN(cfg.DSN)), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库: %s", cfg.Driver)
+	}
+}
+
+// sqliteDSN SQLite 默认不检查外键，并在写冲突时立即报错，这里统一开启外键并设置等待时间。
+// DSN 中已指定的 pragma 不会被覆盖。
+func sqliteDSN(dsn string) string {
+	pragmas := []string{"foreign_keys(1)", "busy_timeout(5000)"}
+	if !isSQLiteMemory(dsn) {
+		pragmas = append(pragmas, "journal_mode(WAL)")
+	}
+	for _, p := range pragmas {
+		name := p[:strings.IndexByte(p, '(')]
+		if strings.Contains(dsn, "_pragma="+name) {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "_pragma=" + p
+	}
+	return dsn
+}
+
+func isSQLiteMemory(dsn string) bool {
+	return strings.Contains(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 0001 基线：引入版本化迁移时的全部数据表。
+// 这里的结构体是当时模型的快照，之后修改 api/model 中的模型不会改变基线，表结构的变化应写成新的迁移。
+// 已经由 AutoMigrate 建好表的数据库执行本迁移时只会补齐缺少的表、列和索引。
+
+type baselineUser struct {
+	ID              int64        `gorm:"primaryKey;autoIncrement"`
+	PublicID        string       `gorm:"type:varchar(36);uniqueIndex"`
+	IDCard          string       `gorm:"type:varchar(255)"`
+	IDCardHash      string       `gorm:"type:char(64);uniqueIndex"`
+	DocumentType    string       `gorm:"type:varchar(20);not null;default:'id_card'"`
+	Password        string       `gorm:"type:varchar(60)"`
+	Name            string       `gorm:"type:varchar(512);not null"`
+	Role            baselineRole `gorm:"not null"`
+	Email           string       `gorm:"type:varchar(512)"`
+	TokenVersion    int          `gorm:"not null;default:0"`
+	Phone           string       `gorm:"type:varchar(255)"`
+	EnrollmentYear  int          `gorm:"not null;default:0"`
+	Language        string       `gorm:"type:varchar(10);not null;default:'zh-CN'"`
+	AvatarURL       string       `gorm:"type:varchar(512)"`
+	Bio             string       `gorm:"type:text"`
+	OfficeHours     string       `gorm:"type:varchar(255)"`
+	DepartmentID    *int64       `gorm:"index"`
+	MajorID         *int64       `gorm:"index"`
+	CohortID        *int64       `gorm:"index"`
+	TOTPSecret      string       `gorm:"type:varchar(255)"`
+	TOTPLastCounter int64        `gorm:"not null;default:0"`
+	MFAEnabled      bool         `gorm:"not null;default:false"`
+	RecoveryCodes   string       `gorm:"type:text"`
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+// baselineRole 基线在 MySQL 上使用 enum，其他数据库没有该类型，改用 varchar
+type baselineRole string
+
+func (baselineRole) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "mysql" {
+		return "enum('student','teacher','admin')"
+	}
+	return "varchar(20)"
+}
+
+type baselineCourse struct {
+	ID            int64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	DeletedAt     gorm.DeletedAt `gorm:"index"`
+	Code          string         `gorm:"size:20;index"`
+	Name          string         `gorm:"size:60;not null"`
+	TeacherID     string         `gorm:"size:36;not null;index"`
+	Remark        string         `gorm:"size:200"`
+	StudentMaxNum int            `gorm:"not null"`
+	Hours         int            `gorm:"not null"`
+	Credits       float64        `gorm:"type:decimal(4,1);not null;default:0"`
+	Category      string         `gorm:"size:20"`
+	Term          string         `gorm:"size:20;index"`
+	Status        string         `gorm:"size:20;not null;default:'open'"`
+	StartDate     time.Time      `gorm:"type:date;not null"`
+	MinYearLevel  int            `gorm:"not null;default:0"`
+	MaxYearLevel  int            `gorm:"not null;default:0"`
+}
+
+func (baselineCourse) TableName() string { return "courses" }
+
+type baselineCourseTag struct {
+	CourseID int64  `gorm:"primaryKey"`
+	Tag      string `gorm:"primaryKey;size:30"`
+}
+
+func (baselineCourseTag) TableName() string { return "course_tags" }
+
+type baselineEnrollment struct {
+	CourseID  int64    `gorm:"primarykey"`
+	StudentID int64    `gorm:"primarykey"`
+	Status    string   `gorm:"size:20;not null;default:'enrolled'"`
+	Score     *float64 `gorm:"type:decimal(5,2)"`
+}
+
+func (baselineEnrollment) TableName() string { return "enrollments" }
+
+type baselineLoginAttempt struct {
+	Key           string `gorm:"column:attempt_key;primaryKey;size:100"`
+	Failures      int    `gorm:"not null;default:0"`
+	LastFailureAt *time.Time
+	NextAllowedAt *time.Time
+	LockedUntil   *time.Time
+}
+
+func (baselineLoginAttempt) TableName() string { return "login_attempts" }
+
+type baselinePasswordResetToken struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	UserID    int64     `gorm:"not null;index"`
+	TokenHash string    `gorm:"type:char(64);uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (baselinePasswordResetToken) TableName() string { return "password_reset_tokens" }
+
+type baselinePasswordHistory struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	UserID    int64  `gorm:"not null;index"`
+	Hash      string `gorm:"type:varchar(60);not null"`
+	CreatedAt time.Time
+}
+
+func (baselinePasswordHistory) TableName() string { return "password_histories" }
+
+type baselineDepartment struct {
+	ID   int64  `gorm:"primaryKey;autoIncrement"`
+	Code string `gorm:"size:20;not null;uniqueIndex"`
+	Name string `gorm:"size:100;not null"`
+}
+
+func (baselineDepartment) TableName() string { return "departments" }
+
+type baselineMajor struct {
+	ID           int64  `gorm:"primaryKey;autoIncrement"`
+	DepartmentID int64  `gorm:"not null;index"`
+	Code         string `gorm:"size:20;not null;uniqueIndex"`
+	Name         string `gorm:"size:100;not null"`
+}
+
+func (baselineMajor) TableName() string { return "majors" }
+
+type baselineCohort struct {
+	ID        int64  `gorm:"primaryKey;autoIncrement"`
+	MajorID   int64  `gorm:"not null;uniqueIndex:idx_cohort"`
+	Year      int    `gorm:"not null;uniqueIndex:idx_cohort"`
+	ClassName string `gorm:"size:50;not null;uniqueIndex:idx_cohort"`
+}
+
+func (baselineCohort) TableName() string { return "cohorts" }
+
+type baselineCourseAudience struct {
+	CourseID    int64  `gorm:"primaryKey"`
+	MajorID     int64  `gorm:"primaryKey"`
+	Requirement string `gorm:"size:20;not null;default:'elective'"`
+}
+
+func (baselineCourseAudience) TableName() string { return "course_audiences" }
+
+type baselineProgram struct {
+	ID           int64   `gorm:"primaryKey;autoIncrement"`
+	MajorID      int64   `gorm:"not null;uniqueIndex"`
+	Name         string  `gorm:"size:100;not null"`
+	TotalCredits float64 `gorm:"type:decimal(5,1);not null;default:0"`
+}
+
+func (baselineProgram) TableName() string { return "programs" }
+
+type baselineProgramCourse struct {
+	ProgramID  int64  `gorm:"primaryKey"`
+	CourseCode string `gorm:"primaryKey;size:20"`
+}
+
+func (baselineProgramCourse) TableName() string { return "program_courses" }
+
+type baselineElectiveGroup struct {
+	ID         int64   `gorm:"primaryKey;autoIncrement"`
+	ProgramID  int64   `gorm:"not null;index"`
+	Name       string  `gorm:"size:100;not null"`
+	MinCredits float64 `gorm:"type:decimal(5,1);not null"`
+}
+
+func (baselineElectiveGroup) TableName() string { return "elective_groups" }
+
+type baselineElectiveGroupCourse struct {
+	GroupID    int64  `gorm:"primaryKey"`
+	CourseCode string `gorm:"primaryKey;size:20"`
+}
+
+func (baselineElectiveGroupCourse) TableName() string { return "elective_group_courses" }
+
+type baselineCategoryRequirement struct {
+	ProgramID  int64   `gorm:"primaryKey"`
+	Category   string  `gorm:"primaryKey;size:20"`
+	MinCredits float64 `gorm:"type:decimal(5,1);not null"`
+}
+
+func (baselineCategoryRequirement) TableName() string { return "category_requirements" }
+
+// baselineTables 按建表顺序排列，回滚时倒序删除
+var baselineTables = []interface{}{
+	&baselineUser{}, &baselineCourse{}, &baselineCourseTag{}, &baselineEnrollment{},
+	&baselineLoginAttempt{}, &baselinePasswordResetToken{}, &baselinePasswordHistory{},
+	&baselineDepartment{}, &baselineMajor{}, &baselineCohort{}, &baselineCourseAudience{},
+	&baselineProgram{}, &baselineProgramCourse{}, &baselineElectiveGroup{}, &baselineElectiveGroupCourse{}, &baselineCategoryRequirement{},
+}
+
+func baselineUp(tx *gorm.DB) error {
+	return tx.AutoMigrate(baselineTables...)
+}
+
+func baselineDown(tx *gorm.DB) error {
+	for i := len(baselineTables) - 1; i >= 0; i-- {
+		if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm"
+)
+
+// 0002 迁移引入对外标识和字段加密之前的旧数据：
+// 为没有对外标识的用户补齐 public_id，将 courses.teacher_id 中的身份证号改写为教师的 public_id，
+// 最后加密仍为明文的个人信息。需要先通过 fieldcrypt.SetDefault 加载密钥。
+// 回滚时不恢复旧格式的数据。
+
+// 执行本迁移时 users 表中加密保存的列，以及证件号码的盲索引列。
+// 以后新增加密列不影响本迁移，需在新的迁移中处理
+var (
+	legacyEncryptedColumns = []string{"id_card", "name", "totp_secret", "email", "phone"}
+	legacyBlindIndexes     = map[string]string{"id_card": "id_card_hash"}
+)
+
+func legacyDataUp(tx *gorm.DB) error {
+	var ids []int64
+	if err := tx.Table("users").Where("public_id IS NULL OR public_id = ''").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := tx.Table("users").Where("id = ?", id).Update("public_id", pkg.NewPublicID()).Error; err != nil {
+			return err
+		}
+	}
+
+	err := tx.Exec(`UPDATE courses SET teacher_id = (
+			SELECT users.public_id FROM users WHERE users.id_card = courses.teacher_id AND users.role = 'teacher'
+		) WHERE teacher_id IN (SELECT id_card FROM users WHERE role = 'teacher')`).Error
+	if err != nil {
+		return err
+	}
+
+	kr, err := fieldcrypt.Default()
+	if err != nil {
+		return err
+	}
+	// 本迁移专门处理明文，无论配置如何都接受旧数据
+	legacy := *kr
+	legacy.AllowLegacy = true
+	return encryptLegacyUsers(tx, &legacy)
+}
+
+// encryptLegacyUsers 按批次加密仍为明文的列，已加密的保持不变，并校正盲索引
+func encryptLegacyUsers(tx *gorm.DB, kr *fieldcrypt.Keyring) error {
+	selects := append([]string{"id", "public_id"}, legacyEncryptedColumns...)
+	for _, indexColumn := range legacyBlindIndexes {
+		selects = append(selects, indexColumn)
+	}
+
+	var lastID int64
+	for {
+		var rows []map[string]interface{}
+		err := tx.Table("users").Select(selects).Where("id > ?", lastID).Order("id").Limit(500).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		for _, row := range rows {
+			changes := make(map[string]interface{})
+			for _, column := range legacyEncryptedColumns {
+				value := migrationString(row[column])
+				if value == "" {
+					continue
+				}
+				ctx := fieldcrypt.Context{Table: "users", Column: column, Row: migrationString(row["public_id"])}
+				plaintext, err := kr.Decrypt(value, ctx)
+				if err != nil {
+					return fmt.Errorf("users id=%v %s: %w", row["id"], column, err)
+				}
+				if !fieldcrypt.IsEncrypted(value) {
+					if changes[column], err = kr.Encrypt(plaintext, ctx); err != nil {
+						return fmt.Errorf("users id=%v %s: %w", row["id"], column, err)
+					}
+				}
+				if indexColumn, ok := legacyBlindIndexes[column]; ok {
+					if hash := kr.BlindIndex(plaintext); migrationString(row[indexColumn]) != hash {
+						changes[indexColumn] = hash
+					}
+				}
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			if err := tx.Table("users").Where("id = ?", row["id"]).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+		lastID = migrationInt64(rows[len(rows)-1]["id"])
+	}
+}
+
+func legacyDataDown(tx *gorm.DB) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0003 删除 users.department。院系改为 department_id 关联 departments 后，
+// AutoMigrate 不会删除旧的文本列，旧数据库中一直保留着它。
+
+type legacyUserDepartment struct {
+	Department string `gorm:"type:varchar(100)"`
+}
+
+func (legacyUserDepartment) TableName() string { return "users" }
+
+func dropUserDepartmentUp(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&legacyUserDepartment{}, "Department") {
+		return nil
+	}
+	return tx.Migrator().DropColumn(&legacyUserDepartment{}, "Department")
+}
+
+func dropUserDepartmentDown(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&legacyUserDepartment{}, "Department")
+}
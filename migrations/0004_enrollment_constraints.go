@@ -0,0 +1,98 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 0004 选课记录和课程的外键及 CHECK 约束：
+//   - enrollments.course_id 引用 courses.id，删除课程时删除其选课记录
+//   - enrollments.student_id 引用 users.id，删除用户时删除其选课记录
+//   - courses.teacher_id 引用 users.public_id，仍有课程的教师不能删除
+//   - courses.student_max_num 和 courses.hours 必须为正数
+//
+// 已有数据中存在违反约束的行时迁移失败，需先运行 dbcheck 检查并修复。
+
+type constrainedEnrollment struct {
+	CourseID  int64
+	StudentID int64
+	Course    baselineCourse `gorm:"constraint:fk_enrollments_course,OnDelete:CASCADE"`
+	Student   baselineUser   `gorm:"foreignKey:StudentID;constraint:fk_enrollments_student,OnDelete:CASCADE"`
+}
+
+func (constrainedEnrollment) TableName() string { return "enrollments" }
+
+type constrainedCourse struct {
+	ID            int64
+	TeacherID     string       `gorm:"size:36"`
+	StudentMaxNum int          `gorm:"check:chk_courses_student_max_num,student_max_num > 0"`
+	Hours         int          `gorm:"check:chk_courses_hours,hours > 0"`
+	Teacher       baselineUser `gorm:"foreignKey:TeacherID;references:PublicID;constraint:fk_courses_teacher,OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (constrainedCourse) TableName() string { return "courses" }
+
+var enrollmentConstraints = []struct {
+	model interface{}
+	name  string
+}{
+	{&constrainedEnrollment{}, "fk_enrollments_course"},
+	{&constrainedEnrollment{}, "fk_enrollments_student"},
+	{&constrainedCourse{}, "fk_courses_teacher"},
+	{&constrainedCourse{}, "chk_courses_student_max_num"},
+	{&constrainedCourse{}, "chk_courses_hours"},
+}
+
+// 执行本迁移时会导致添加约束失败的数据，与 dbcheck 中标记为 blocking 的检查项对应。
+// 这里是执行本迁移时的副本，之后修改 repository.integrityChecks 不影响本迁移，反之亦然
+var enrollmentConstraintViolations = []struct {
+	description string
+	table       string
+	where       string
+}{
+	{"选课记录引用了不存在的课程", "enrollments", "NOT EXISTS (SELECT 1 FROM courses WHERE courses.id = enrollments.course_id)"},
+	{"选课记录引用了不存在的学生", "enrollments", "NOT EXISTS (SELECT 1 FROM users WHERE users.id = enrollments.student_id)"},
+	{"课程的授课教师不存在，需要重新指定教师", "courses", "NOT EXISTS (SELECT 1 FROM users WHERE users.public_id = courses.teacher_id)"},
+	{"课程的人数上限或学时不是正数", "courses", "student_max_num <= 0 OR hours <= 0"},
+}
+
+func enrollmentConstraintsUp(tx *gorm.DB) error {
+	var blocking []string
+	for _, v := range enrollmentConstraintViolations {
+		var count int64
+		if err := tx.Table(v.table).Where(v.where).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			blocking = append(blocking, fmt.Sprintf("%s %d 条", v.description, count))
+		}
+	}
+	if len(blocking) > 0 {
+		return fmt.Errorf("存在违反约束的数据（%s），请先运行 dbcheck 检查并修复", strings.Join(blocking, "；"))
+	}
+
+	for _, c := range enrollmentConstraints {
+		if tx.Migrator().HasConstraint(c.model, c.name) {
+			continue
+		}
+		if err := tx.Migrator().CreateConstraint(c.model, c.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func enrollmentConstraintsDown(tx *gorm.DB) error {
+	for i := len(enrollmentConstraints) - 1; i >= 0; i-- {
+		c := enrollmentConstraints[i]
+		if !tx.Migrator().HasConstraint(c.model, c.name) {
+			continue
+		}
+		if err := tx.Migrator().DropConstraint(c.model, c.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0005 users.role 在 MySQL 上由 enum 改为 varchar(20)，各数据库统一用 CHECK 约束限定取值
+
+type portableUserRole struct {
+	Role string `gorm:"type:varchar(20);not null;check:chk_users_role,role IN ('student','teacher','admin')"`
+}
+
+func (portableUserRole) TableName() string { return "users" }
+
+func portableUserRoleUp(tx *gorm.DB) error {
+	if tx.Dialector.Name() == "mysql" {
+		if err := tx.Migrator().AlterColumn(&portableUserRole{}, "Role"); err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasConstraint(&portableUserRole{}, "chk_users_role") {
+		return nil
+	}
+	return tx.Migrator().CreateConstraint(&portableUserRole{}, "chk_users_role")
+}
+
+func portableUserRoleDown(tx *gorm.DB) error {
+	if tx.Migrator().HasConstraint(&portableUserRole{}, "chk_users_role") {
+		if err := tx.Migrator().DropConstraint(&portableUserRole{}, "chk_users_role"); err != nil {
+			return err
+		}
+	}
+	if tx.Dialector.Name() == "mysql" {
+		return tx.Exec("ALTER TABLE users MODIFY COLUMN role enum('student','teacher','admin') NOT NULL").Error
+	}
+	return nil
+}
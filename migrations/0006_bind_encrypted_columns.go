@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm"
+)
+
+// 0006 将 v1 格式的密文和仍为明文的个人信息重新加密为绑定表、列和用户 public_id 的 v2 格式，
+// 此后密文不能在列之间或用户之间互换。需要先通过 fieldcrypt.SetDefault 加载密钥。
+// 回滚时不恢复旧格式的数据。
+
+// 执行本迁移时 users 表中加密保存的列
+var boundUserColumns = []string{"id_card", "name", "totp_secret", "email", "phone"}
+
+func bindEncryptedColumnsUp(tx *gorm.DB) error {
+	kr, err := fieldcrypt.Default()
+	if err != nil {
+		return err
+	}
+	legacy := *kr
+	legacy.AllowLegacy = true
+
+	selects := append([]string{"id", "public_id"}, boundUserColumns...)
+	var lastID int64
+	for {
+		var rows []map[string]interface{}
+		err := tx.Table("users").Select(selects).Where("id > ?", lastID).Order("id").Limit(500).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		for _, row := range rows {
+			publicID := migrationString(row["public_id"])
+			changes := make(map[string]interface{})
+			for _, column := range boundUserColumns {
+				value := migrationString(row[column])
+				if value == "" || fieldcrypt.IsBound(value) {
+					continue
+				}
+				ctx := fieldcrypt.Context{Table: "users", Column: column, Row: publicID}
+				plaintext, err := legacy.Decrypt(value, ctx)
+				if err != nil {
+					return fmt.Errorf("users id=%v %s: %w", row["id"], column, err)
+				}
+				if changes[column], err = kr.Encrypt(plaintext, ctx); err != nil {
+					return fmt.Errorf("users id=%v %s: %w", row["id"], column, err)
+				}
+			}
+			if len(changes) == 0 {
+				continue
+			}
+			if err := tx.Table("users").Where("id = ?", row["id"]).Updates(changes).Error; err != nil {
+				return err
+			}
+		}
+		lastID = migrationInt64(rows[len(rows)-1]["id"])
+	}
+}
+
+func bindEncryptedColumnsDown(tx *gorm.DB) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 0007 将 users.password 中的明文密码替换为 bcrypt 哈希，已经是哈希的行保持不变。
+// 哈希无法还原，回滚时不恢复明文，回滚后的旧版本程序无法用原密码登录。
+
+// 执行本迁移时使用的 bcrypt 计算强度
+const hashPasswordsCost = 10
+
+func hashPasswordsUp(tx *gorm.DB) error {
+	var lastID int64
+	for {
+		var rows []map[string]interface{}
+		err := tx.Table("users").Select("id", "password").Where("id > ?", lastID).Order("id").Limit(500).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		for _, row := range rows {
+			password := migrationString(row["password"])
+			if password == "" || strings.HasPrefix(password, "$2") {
+				continue
+			}
+			hash, err := bcrypt.GenerateFromPassword([]byte(password), hashPasswordsCost)
+			if err != nil {
+				return err
+			}
+			if err := tx.Table("users").Where("id = ?", row["id"]).Update("password", string(hash)).Error; err != nil {
+				return err
+			}
+		}
+		lastID = migrationInt64(rows[len(rows)-1]["id"])
+	}
+}
+
+func hashPasswordsDown(tx *gorm.DB) error {
+	return nil
+}
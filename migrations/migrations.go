@@ -0,0 +1,53 @@
+// Package migrations 数据库表结构和数据的迁移，按版本号顺序执行。
+//
+// 新增迁移时在本目录添加 NNNN_说明.go，并在 All 的末尾登记。已发布的迁移不要再修改。
+package migrations
+
+import (
+	"fmt"
+
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+)
+
+// All 所有迁移，按版本号排列
+func All() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "baseline", Up: baselineUp, Down: baselineDown},
+		{Version: 2, Name: "legacy_data", Up: legacyDataUp, Down: legacyDataDown},
+		{Version: 3, Name: "drop_user_department", Up: dropUserDepartmentUp, Down: dropUserDepartmentDown},
+		{Version: 4, Name: "enrollment_constraints", Up: enrollmentConstraintsUp, Down: enrollmentConstraintsDown},
+		{Version: 5, Name: "portable_user_role", Up: portableUserRoleUp, Down: portableUserRoleDown},
+		{Version: 6, Name: "bind_encrypted_columns", Up: bindEncryptedColumnsUp, Down: bindEncryptedColumnsDown},
+		{Version: 7, Name: "hash_passwords", Up: hashPasswordsUp, Down: hashPasswordsDown},
+	}
+}
+
+// 以下辅助函数供各迁移读取 Find 到 map 中的列值，不同数据库驱动返回的类型不同
+
+func migrationString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	}
+	return ""
+}
+
+func migrationInt64(v interface{}) int64 {
+	switch val := v.(type) {
+	case int64:
+		return val
+	case int32:
+		return int64(val)
+	case int:
+		return int64(val)
+	case uint64:
+		return int64(val)
+	case []byte:
+		var n int64
+		fmt.Sscan(string(val), &n)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,118 @@
+// Package breach 检查密码是否出现在已泄露密码库中。
+//
+// 密码库按 k-anonymity 方式组织：以密码 SHA-1（大写十六进制）的前5位为分组，
+// 组内只保存剩余35位后缀，查询时只需按前缀取出一组再比对后缀，
+// 与 Have I Been Pwned 的 range 接口和离线下载格式一致。
+package breach
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const prefixLen = 5
+
+// Checker 判断密码是否已泄露
+type Checker interface {
+	Breached(password string) (bool, error)
+}
+
+// NopChecker 不做检查，未配置密码库时使用
+type NopChecker struct{}
+
+func (NopChecker) Breached(string) (bool, error) { return false, nil }
+
+// FileChecker 从本地文件查询的密码库
+type FileChecker struct {
+	dir    string              // 目录模式：每个前缀一个文件，内容为 "后缀:次数"
+	hashes map[string]struct{} // 单文件模式：启动时加载全部完整哈希
+}
+
+// NewFileChecker path 为目录时按需读取 <path>/<前缀> 文件，适合完整的大型密码库；
+// 为文件时一次性加载，每行一个完整 SHA-1，可带 ":次数"，空行和 # 开头的行忽略。
+func NewFileChecker(path string) (*FileChecker, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &FileChecker{dir: path}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &FileChecker{hashes: make(map[string]struct{})}
+	err = scanHashes(f, func(hash string) error {
+		if len(hash) != sha1.Size*2 {
+			return fmt.Errorf("%s: 不是有效的 SHA-1: %q", path, hash)
+		}
+		c.hashes[hash] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *FileChecker) Breached(password string) (bool, error) {
+	hash := Hash(password)
+	if c.dir == "" {
+		_, ok := c.hashes[hash]
+		return ok, nil
+	}
+
+	f, err := os.Open(filepath.Join(c.dir, hash[:prefixLen]))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	found := false
+	err = scanHashes(f, func(suffix string) error {
+		if suffix == hash[prefixLen:] {
+			found = true
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return found, nil
+}
+
+// Hash 返回密码 SHA-1 的大写十六进制
+func Hash(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func scanHashes(r io.Reader, fn func(hash string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, _, _ := strings.Cut(line, ":")
+		if err := fn(strings.ToUpper(hash)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,93 @@
+// Package certreload 在证书文件更新后自动加载新证书，无需重启服务。
+package certreload
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader 为 tls.Config.GetCertificate 提供证书。
+// 握手时若距上次检查超过 CheckInterval，就比较文件修改时间，有变化则重新加载；
+// 新证书加载失败时继续使用旧证书。
+type Reloader struct {
+	CertFile      string
+	KeyFile       string
+	CheckInterval time.Duration
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// New 加载证书，文件不存在或不匹配时返回错误
+func New(certFile, keyFile string, checkInterval time.Duration) (*Reloader, error) {
+	r := &Reloader{CertFile: certFile, KeyFile: keyFile, CheckInterval: checkInterval}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload 立即重新加载证书
+func (r *Reloader) Reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	due := time.Since(r.checkedAt) >= r.CheckInterval
+	if due {
+		r.checkedAt = time.Now()
+	}
+	cert, loaded := r.cert, r.modTime
+	r.mu.Unlock()
+
+	if due {
+		if modTime, err := r.latestModTime(); err == nil && modTime.After(loaded) {
+			if err := r.Reload(); err != nil {
+				log.Printf("重新加载证书失败，继续使用旧证书: %v", err)
+			} else {
+				log.Printf("已重新加载证书 %s", r.CertFile)
+				r.mu.Lock()
+				cert = r.cert
+				r.mu.Unlock()
+			}
+		}
+	}
+	return cert, nil
+}
+
+// latestModTime 证书和私钥中较晚的修改时间
+func (r *Reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{r.CertFile, r.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// CursorSigner 对分页游标签名，防止客户端伪造游标跳到任意位置。
+// 密钥只用于游标，不与 JWT 等其他用途共用。
+type CursorSigner struct {
+	key []byte
+}
+
+// NewCursorSigner key 为空时返回错误
+func NewCursorSigner(key string) (*CursorSigner, error) {
+	if key == "" {
+		return nil, errors.New("未配置分页游标密钥")
+	}
+	return &CursorSigner{key: []byte(key)}, nil
+}
+
+// Encode 将游标序列化并签名，返回 URL 安全的字符串
+func (s *CursorSigner) Encode(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload)), nil
+}
+
+// Decode 校验签名并反序列化游标
+func (s *CursorSigner) Decode(cursor string, v any) error {
+	payloadPart, sigPart, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(payloadPart)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(sigPart)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+
+	if !hmac.Equal(sig, s.sign(payload)) {
+		return ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(payload, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
+
+func (s *CursorSigner) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
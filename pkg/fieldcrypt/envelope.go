@@ -0,0 +1,163 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// 密文格式: v2:<主密钥ID>:<base64(被包装的数据密钥)>:<base64(密文)>
+// 被包装的数据密钥和密文都以 GCM nonce 开头。密文以 Context 作为附加认证数据(AAD)，
+// 绑定所在的表、列和行，把密文复制到其他列或其他用户的行时解密失败。
+// v1 是未绑定 Context 的旧格式，和未加密的明文一样只在 Keyring.AllowLegacy 为 true 时接受。
+const (
+	ciphertextPrefix       = "v2:"
+	legacyCiphertextPrefix = "v1:"
+)
+
+// Context 密文所在的位置，作为附加认证数据
+type Context struct {
+	Table  string
+	Column string
+	Row    string // 行的不可变标识，如 users.public_id
+}
+
+func (c Context) aad() []byte {
+	return []byte(c.Table + "." + c.Column + ":" + c.Row)
+}
+
+// Encrypt 使用随机数据密钥加密明文，再用当前主密钥包装数据密钥
+func (kr *Keyring) Encrypt(plaintext string, ctx Context) (string, error) {
+	if ctx.Row == "" {
+		return "", fmt.Errorf("%w: %s.%s", ErrNoRowKey, ctx.Table, ctx.Column)
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+
+	sealed, err := seal(dek, []byte(plaintext), ctx.aad())
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(kr.Keys[kr.Active], dek, nil)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawStdEncoding
+	return ciphertextPrefix + kr.Active + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 的结果，ctx 必须与加密时相同。
+// 未加密的明文和 v1 格式的密文只在 AllowLegacy 为 true 时接受，否则返回 ErrLegacyValue。
+func (kr *Keyring) Decrypt(value string, ctx Context) (string, error) {
+	var prefix string
+	switch {
+	case IsBound(value):
+		prefix = ciphertextPrefix
+	case !kr.AllowLegacy:
+		return "", ErrLegacyValue
+	case strings.HasPrefix(value, legacyCiphertextPrefix):
+		prefix = legacyCiphertextPrefix
+	default:
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", ErrCiphertext
+	}
+	kek, ok := kr.Keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, parts[0])
+	}
+
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrCiphertext
+	}
+	sealed, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrCiphertext
+	}
+
+	dek, err := open(kek, wrapped, nil)
+	if err != nil {
+		return "", err
+	}
+	var aad []byte
+	if prefix == ciphertextPrefix {
+		aad = ctx.aad()
+	}
+	plaintext, err := open(dek, sealed, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// NeedsRotation 判断值是否为明文、旧格式，或不是由当前主密钥加密
+func (kr *Keyring) NeedsRotation(value string) bool {
+	return !IsBound(value) || KeyID(value) != kr.Active
+}
+
+// IsBound 判断值是否为绑定了 Context 的当前格式密文
+func IsBound(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix)
+}
+
+// IsEncrypted 判断值是否为本包生成的密文，包括 v1 格式
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, ciphertextPrefix) || strings.HasPrefix(value, legacyCiphertextPrefix)
+}
+
+// KeyID 返回密文使用的主密钥ID，明文返回空字符串
+func KeyID(value string) string {
+	if !IsEncrypted(value) {
+		return ""
+	}
+	_, rest, _ := strings.Cut(value, ":")
+	kid, _, _ := strings.Cut(rest, ":")
+	return kid
+}
+
+func seal(key, plaintext, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(key, sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrCiphertext
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
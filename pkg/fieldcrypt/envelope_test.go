@@ -0,0 +1,92 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T) *Keyring {
+	t.Helper()
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32)) }
+	kr, err := LoadKeyring(KeySource{Keys: "k1:" + key(1) + ",k2:" + key(2), IndexKey: key(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kr
+}
+
+// encryptV1 按未绑定 Context 的 v1 格式加密，模拟旧版本写入的数据
+func encryptV1(t *testing.T, kr *Keyring, plaintext string) string {
+	t.Helper()
+	dek := bytes.Repeat([]byte{9}, 32)
+	sealed, err := seal(dek, []byte(plaintext), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped, err := seal(kr.Keys[kr.Active], dek, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawStdEncoding
+	return legacyCiphertextPrefix + kr.Active + ":" + enc.EncodeToString(wrapped) + ":" + enc.EncodeToString(sealed)
+}
+
+func TestEncryptBindsContext(t *testing.T) {
+	kr := testKeyring(t)
+	alice := Context{Table: "users", Column: "email", Row: "alice"}
+	value, err := kr.Encrypt("alice@example.com", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsBound(value) || KeyID(value) != "k2" || kr.NeedsRotation(value) {
+		t.Fatalf("value = %s", value)
+	}
+	if got, err := kr.Decrypt(value, alice); err != nil || got != "alice@example.com" {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+
+	for _, other := range []Context{
+		{Table: "users", Column: "email", Row: "bob"},
+		{Table: "users", Column: "phone", Row: "alice"},
+		{Table: "admins", Column: "email", Row: "alice"},
+	} {
+		if _, err := kr.Decrypt(value, other); !errors.Is(err, ErrCiphertext) {
+			t.Errorf("换到 %+v 后 err = %v，应当解密失败", other, err)
+		}
+	}
+
+	if _, err := kr.Encrypt("x", Context{Table: "users", Column: "email"}); !errors.Is(err, ErrNoRowKey) {
+		t.Errorf("缺少行标识时 err = %v", err)
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	kr := testKeyring(t)
+	ctx := Context{Table: "users", Column: "name", Row: "alice"}
+	v1 := encryptV1(t, kr, "张三")
+
+	for _, value := range []string{"张三", v1} {
+		if _, err := kr.Decrypt(value, ctx); !errors.Is(err, ErrLegacyValue) {
+			t.Errorf("未开启 AllowLegacy 时 Decrypt(%.10s) err = %v", value, err)
+		}
+		if !kr.NeedsRotation(value) {
+			t.Errorf("%.10s 应当需要重新加密", value)
+		}
+	}
+
+	kr.AllowLegacy = true
+	for _, value := range []string{"张三", v1} {
+		if got, err := kr.Decrypt(value, ctx); err != nil || got != "张三" {
+			t.Errorf("Decrypt(%.10s) = %q, %v", value, got, err)
+		}
+	}
+	if !IsEncrypted(v1) || IsBound(v1) || KeyID(v1) != "k2" || KeyID("张三") != "" {
+		t.Error("v1 格式识别错误")
+	}
+	if _, err := kr.Decrypt(strings.Replace(v1, "v1:", "v2:", 1), ctx); !errors.Is(err, ErrCiphertext) {
+		t.Errorf("把 v1 密文改为 v2 前缀后 err = %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package fieldcrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoKeyring   = errors.New("未配置字段加密密钥")
+	ErrUnknownKey  = errors.New("未知的加密密钥")
+	ErrCiphertext  = errors.New("密文格式不正确")
+	ErrLegacyValue = errors.New("字段值未加密或使用未绑定位置的旧格式，请先执行迁移或临时开启 allow_legacy")
+	ErrNoRowKey    = errors.New("缺少加密字段所在行的标识")
+)
+
+// Keyring 字段加密使用的密钥环。
+// Keys 为主密钥（KEK），用于包装每个字段值随机生成的数据密钥；Active 为新数据使用的主密钥。
+// IndexKey 用于计算盲索引，轮换主密钥时不变。
+// AllowLegacy 为 true 时解密接受明文和 v1 格式的旧密文，只应在升级旧数据期间开启。
+type Keyring struct {
+	Active      string
+	Keys        map[string][]byte
+	IndexKey    []byte
+	AllowLegacy bool
+}
+
+// keyFile 密钥文件格式，密钥均为 base64 编码的32字节
+type keyFile struct {
+	Active   string            `json:"active"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// KeySource 密钥的来源，KeyFile 不为空时优先从密钥文件加载
+type KeySource struct {
+	KeyFile  string
+	Keys     string // 格式 k1:base64,k2:base64
+	Active   string // 为空时使用 Keys 中的最后一个密钥
+	IndexKey string
+
+	AllowLegacy bool // 见 Keyring.AllowLegacy
+}
+
+// LoadKeyring 从密钥文件或 KeySource 中直接给出的密钥加载密钥环
+func LoadKeyring(src KeySource) (*Keyring, error) {
+	if src.KeyFile != "" {
+		data, err := os.ReadFile(src.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+		}
+		var kf keyFile
+		if err := json.Unmarshal(data, &kf); err != nil {
+			return nil, fmt.Errorf("解析密钥文件失败: %w", err)
+		}
+		return newKeyring(kf, src.AllowLegacy)
+	}
+
+	if src.Keys == "" {
+		return nil, ErrNoKeyring
+	}
+	kf := keyFile{
+		Active:   src.Active,
+		Keys:     make(map[string]string),
+		IndexKey: src.IndexKey,
+	}
+	for _, pair := range strings.Split(src.Keys, ",") {
+		kid, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			return nil, fmt.Errorf("PII_KEYS 格式不正确: %q", pair)
+		}
+		kf.Keys[kid] = key
+		if src.Active == "" {
+			kf.Active = kid
+		}
+	}
+	return newKeyring(kf, src.AllowLegacy)
+}
+
+func newKeyring(kf keyFile, allowLegacy bool) (*Keyring, error) {
+	kr := &Keyring{Active: kf.Active, Keys: make(map[string][]byte, len(kf.Keys)), AllowLegacy: allowLegacy}
+	for kid, encoded := range kf.Keys {
+		if strings.Contains(kid, ":") {
+			return nil, fmt.Errorf("密钥ID不能包含冒号: %q", kid)
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("密钥 %s: %w", kid, err)
+		}
+		kr.Keys[kid] = key
+	}
+	if _, ok := kr.Keys[kr.Active]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kr.Active)
+	}
+
+	indexKey, err := decodeKey(kf.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("盲索引密钥: %w", err)
+	}
+	kr.IndexKey = indexKey
+	return kr, nil
+}
+
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("不是有效的 base64")
+	}
+	if len(key) != 32 {
+		return nil, errors.New("长度必须为32字节")
+	}
+	return key, nil
+}
+
+// BlindIndex 计算用于等值查询的盲索引，相同明文得到相同结果
+func (kr *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, kr.IndexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	defaultMu      sync.RWMutex
+	defaultKeyring *Keyring
+)
+
+// SetDefault 设置 GORM 序列化器使用的密钥环，应在访问数据库前调用
+func SetDefault(kr *Keyring) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultKeyring = kr
+}
+
+// Default 返回当前密钥环
+func Default() (*Keyring, error) {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	if defaultKeyring == nil {
+		return nil, ErrNoKeyring
+	}
+	return defaultKeyring, nil
+}
+
+// BlindIndex 使用默认密钥环计算盲索引
+func BlindIndex(value string) (string, error) {
+	kr, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return kr.BlindIndex(value), nil
+}
@@ -0,0 +1,96 @@
+package fieldcrypt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// RowKeyer 包含加密字段的模型实现该接口，返回行的不可变标识，写入时作为密文的附加认证数据
+type RowKeyer interface {
+	EncryptionRowKey() string
+}
+
+// Serializer GORM 序列化器，写入时加密字符串字段，空字符串不加密。
+// GORM 按查询列的顺序逐个扫描字段，扫描到加密字段时行标识可能还没有读取，
+// 因此读取时只保存密文，由模型的 AfterFind 钩子调用 OpenFields 统一解密。
+// 使用前需通过 schema.RegisterSerializer 注册，并用 SetDefault 设置密钥环。
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("加密字段 %s 的类型不支持: %T", field.Name, dbValue)
+	}
+	return field.Set(ctx, dst, value)
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("加密字段 %s 必须是字符串", field.Name)
+	}
+	if value == "" {
+		return value, nil
+	}
+
+	kr, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return kr.Encrypt(value, Context{Table: field.Schema.Table, Column: field.DBName, Row: rowKey(dst)})
+}
+
+var schemaCache sync.Map
+
+// OpenFields 解密 model 中由 Serializer 读取的加密字段，在模型的 AfterFind 钩子中调用
+func OpenFields(tx *gorm.DB, model RowKeyer) error {
+	s, err := schema.Parse(model, &schemaCache, tx.NamingStrategy)
+	if err != nil {
+		return err
+	}
+	kr, err := Default()
+	if err != nil {
+		return err
+	}
+
+	ctx := tx.Statement.Context
+	rv := reflect.Indirect(reflect.ValueOf(model))
+	for _, field := range s.Fields {
+		if _, ok := field.Serializer.(Serializer); !ok {
+			continue
+		}
+		fv := field.ReflectValueOf(ctx, rv)
+		if fv.String() == "" {
+			continue
+		}
+		plaintext, err := kr.Decrypt(fv.String(), Context{Table: s.Table, Column: field.DBName, Row: model.EncryptionRowKey()})
+		if err != nil {
+			return fmt.Errorf("解密字段 %s 失败: %w", field.Name, err)
+		}
+		fv.SetString(plaintext)
+	}
+	return nil
+}
+
+func rowKey(dst reflect.Value) string {
+	if dst.CanAddr() {
+		if k, ok := dst.Addr().Interface().(RowKeyer); ok {
+			return k.EncryptionRowKey()
+		}
+	}
+	if k, ok := dst.Interface().(RowKeyer); ok {
+		return k.EncryptionRowKey()
+	}
+	return ""
+}
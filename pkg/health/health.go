@@ -0,0 +1,149 @@
+// Package health 提供存活和就绪探针。
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 服务所处阶段
+const (
+	PhaseStarting = "starting" // 正在初始化，不接收业务请求
+	PhaseRunning  = "running"
+	PhaseStopping = "stopping" // 收到退出信号，正在等待请求完成
+)
+
+// Check 检查一项依赖，返回 nil 表示正常
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// CheckResult 一项依赖的检查结果。探针接口无需认证，响应中只有状态，
+// 错误信息和耗时可能暴露内部地址等细节，只写入服务端日志
+type CheckResult struct {
+	Status  string        `json:"status"` // ok 或 error
+	Latency time.Duration `json:"-"`
+	Err     error         `json:"-"`
+}
+
+// Report 就绪检查的结果
+type Report struct {
+	Status string                 `json:"status"` // ok 或 unavailable
+	Phase  string                 `json:"phase"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// Checker 记录服务阶段并执行依赖检查，可在初始化过程中逐步注册检查项
+type Checker struct {
+	timeout time.Duration
+	phase   atomic.Value
+
+	mu     sync.RWMutex
+	checks []namedCheck
+}
+
+// NewChecker timeout 为每项检查的超时时间
+func NewChecker(timeout time.Duration) *Checker {
+	c := &Checker{timeout: timeout}
+	c.phase.Store(PhaseStarting)
+	return c
+}
+
+// Add 注册一项依赖检查
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+func (c *Checker) Phase() string {
+	return c.phase.Load().(string)
+}
+
+// MarkRunning 初始化完成，开始接收业务请求
+func (c *Checker) MarkRunning() {
+	c.phase.Store(PhaseRunning)
+}
+
+// MarkStopping 服务即将停止，就绪检查返回失败以便负载均衡摘除实例
+func (c *Checker) MarkStopping() {
+	c.phase.Store(PhaseStopping)
+}
+
+// Ready 并发执行所有检查，阶段为 running 且所有检查通过时就绪
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]namedCheck(nil), c.checks...)
+	c.mu.RUnlock()
+
+	report := Report{Status: "ok", Phase: c.Phase(), Checks: make(map[string]CheckResult, len(checks))}
+	results := make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, nc := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, nc.check)
+	}
+	wg.Wait()
+
+	for i, nc := range checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != "ok" {
+			report.Status = "unavailable"
+			log.Printf("就绪检查 %s 失败（耗时 %dms）: %v", nc.name, results[i].Latency.Milliseconds(), results[i].Err)
+		}
+	}
+	if report.Phase != PhaseRunning {
+		report.Status = "unavailable"
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	result := CheckResult{Status: "ok", Latency: time.Since(start), Err: err}
+	if err != nil {
+		result.Status = "error"
+	}
+	return result
+}
+
+// LivenessHandler 进程能够处理请求即返回 200，不检查依赖
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "phase": c.Phase()})
+	})
+}
+
+// ReadinessHandler 就绪时返回 200，否则返回 503，响应体为各项检查的名称和状态
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Ready(r.Context())
+		status := http.StatusOK
+		if report.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,29 @@
+package pkg
+
+import (
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// NewPublicID 生成对外使用的随机用户标识（UUID v4）
+func NewPublicID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("生成随机数失败: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// MaskIDCard 只保留证件号前4位和后4位，例如 1101**********1234
+func MaskIDCard(idCard string) string {
+	n := utf8.RuneCountInString(idCard)
+	if n <= 8 {
+		return strings.Repeat("*", n)
+	}
+	runes := []rune(idCard)
+	return string(runes[:4]) + strings.Repeat("*", n-8) + string(runes[n-4:])
+}
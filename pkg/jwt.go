@@ -1,44 +1,308 @@
 package pkg
 
 import (
-	"os"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
-	secretKey = []byte(os.Getenv("JWT_SECRET_KEY")) // 从环境变量读取
+	// ErrTokenPurpose 令牌用途不符，例如用 MFA 挑战令牌访问普通接口
+	ErrTokenPurpose = errors.New("令牌用途不正确")
+	// ErrUnknownSigningKey 令牌的 kid 不在已配置的密钥中，或签名算法与密钥不符
+	ErrUnknownSigningKey = errors.New("未知的令牌签名密钥")
+	// ErrVerifyOnlyKey 只有公钥的密钥不能用于签发令牌
+	ErrVerifyOnlyKey = errors.New("当前签名密钥只有公钥，不能签发令牌")
 )
 
+// 令牌用途，普通登录令牌的 Purpose 为空
+const TokenPurposeMFA = "mfa"
+
+// TokenTTL 登录令牌的有效期
+const TokenTTL = 24 * time.Hour
+
+// MFATokenTTL MFA 挑战令牌的有效期
+const MFATokenTTL = 5 * time.Minute
+
+// 支持的签名算法
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const tokenIssuer = "course-system"
+
 type Claims struct {
 	UserID   string `json:"user_id"`
 	UserRole string `json:"role"`
+	Purpose  string `json:"purpose,omitempty"`
+	Version  int    `json:"ver,omitempty"`   // 签发时用户的 TokenVersion
+	Nonce    string `json:"nonce,omitempty"` // 挑战令牌签发时用户两步验证状态的摘要，状态变化后令牌失效
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID, role string) (string, error) {
+// SigningKey 令牌签名密钥。轮换后旧密钥只保留公钥或密钥用于验证，直到旧令牌全部过期。
+type SigningKey struct {
+	ID        string // 写入令牌头部的 kid
+	Algorithm string
+	signKey   interface{} // 为 nil 时只能验证
+	verifyKey interface{}
+}
+
+// NewHMACKey HS256 密钥，id 为空时由密钥的哈希生成
+func NewHMACKey(id string, secret []byte) SigningKey {
+	if id == "" {
+		sum := sha256.Sum256(secret)
+		id = "hs-" + hex.EncodeToString(sum[:6])
+	}
+	return SigningKey{ID: id, Algorithm: AlgorithmHS256, signKey: secret, verifyKey: secret}
+}
+
+// ParsePrivateKeyPEM 解析 PEM 格式的 RSA（RS256）或 Ed25519（EdDSA）私钥，
+// 支持 PKCS#8 和 PKCS#1。id 为空时由公钥的哈希生成。
+func ParsePrivateKeyPEM(id string, data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, errors.New("私钥不是 PEM 格式")
+	}
+
+	var key interface{}
+	var err error
+	if block.Type == "RSA PRIVATE KEY" {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("解析私钥失败: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return newAsymmetricKey(id, AlgorithmRS256, k, k.Public())
+	case ed25519.PrivateKey:
+		return newAsymmetricKey(id, AlgorithmEdDSA, k, k.Public())
+	default:
+		return SigningKey{}, errors.New("只支持 RSA 和 Ed25519 私钥")
+	}
+}
+
+// ParsePublicKeyPEM 解析 PEM 格式的 RSA 或 Ed25519 公钥，得到只能验证的密钥
+func ParsePublicKeyPEM(id string, data []byte) (SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, errors.New("公钥不是 PEM 格式")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("解析公钥失败: %w", err)
+	}
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return newAsymmetricKey(id, AlgorithmRS256, nil, k)
+	case ed25519.PublicKey:
+		return newAsymmetricKey(id, AlgorithmEdDSA, nil, k)
+	default:
+		return SigningKey{}, errors.New("只支持 RSA 和 Ed25519 公钥")
+	}
+}
+
+func newAsymmetricKey(id, algorithm string, private crypto.Signer, public crypto.PublicKey) (SigningKey, error) {
+	if id == "" {
+		der, err := x509.MarshalPKIXPublicKey(public)
+		if err != nil {
+			return SigningKey{}, err
+		}
+		sum := sha256.Sum256(der)
+		id = base64.RawURLEncoding.EncodeToString(sum[:12])
+	}
+	key := SigningKey{ID: id, Algorithm: algorithm, verifyKey: public}
+	if private != nil {
+		key.signKey = private
+	}
+	return key, nil
+}
+
+func (k SigningKey) method() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// TokenSigner 使用当前密钥签发令牌，使用任一已配置的密钥验证令牌
+type TokenSigner struct {
+	active SigningKey
+	keys   map[string]SigningKey
+}
+
+// NewTokenSigner activeID 为签发新令牌使用的密钥，其余密钥只用于验证
+func NewTokenSigner(activeID string, keys ...SigningKey) (*TokenSigner, error) {
+	s := &TokenSigner{keys: make(map[string]SigningKey, len(keys))}
+	for _, k := range keys {
+		if k.method() == nil {
+			return nil, fmt.Errorf("不支持的签名算法: %s", k.Algorithm)
+		}
+		if _, ok := s.keys[k.ID]; ok {
+			return nil, fmt.Errorf("重复的签名密钥ID: %s", k.ID)
+		}
+		s.keys[k.ID] = k
+	}
+
+	active, ok := s.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSigningKey, activeID)
+	}
+	if active.signKey == nil {
+		return nil, ErrVerifyOnlyKey
+	}
+	s.active = active
+	return s, nil
+}
+
+// ActiveKeyID 签发新令牌使用的密钥ID
+func (s *TokenSigner) ActiveKeyID() string {
+	return s.active.ID
+}
+
+func (s *TokenSigner) GenerateToken(userID, role string, version int) (string, error) {
+	return s.generate(userID, role, "", version, "", TokenTTL)
+}
+
+// GenerateMFAToken 签发密码验证通过后、完成第二步验证前使用的短期挑战令牌
+func (s *TokenSigner) GenerateMFAToken(userID, role string, version int, nonce string) (string, error) {
+	return s.generate(userID, role, TokenPurposeMFA, version, nonce, MFATokenTTL)
+}
+
+func (s *TokenSigner) generate(userID, role, purpose string, version int, nonce string, ttl time.Duration) (string, error) {
 	claims := &Claims{
 		UserID:   userID,
 		UserRole: role,
+		Purpose:  purpose,
+		Version:  version,
+		Nonce:    nonce,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "course-system",
+			Issuer:    tokenIssuer,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(secretKey)
+	token := jwt.NewWithClaims(s.active.method(), claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.signKey)
+}
+
+// ParseToken 解析普通登录令牌，拒绝挑战令牌等其他用途的令牌
+func (s *TokenSigner) ParseToken(tokenString string) (*Claims, error) {
+	return s.parse(tokenString, "")
+}
+
+// ParseMFAToken 解析 MFA 挑战令牌
+func (s *TokenSigner) ParseMFAToken(tokenString string) (*Claims, error) {
+	return s.parse(tokenString, TokenPurposeMFA)
 }
 
-func ParseToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return secretKey, nil
-	})
+func (s *TokenSigner) parse(tokenString, purpose string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.verifyKey,
+		jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA}))
+	if err != nil {
+		return nil, err
+	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrTokenPurpose
+	}
+	return claims, nil
+}
+
+// verifyKey 按 kid 选择验证密钥，支持轮换前签发、没有 kid 的令牌使用当前密钥验证。
+// 令牌的算法必须与密钥一致，防止用公钥作为 HMAC 密钥伪造令牌。
+func (s *TokenSigner) verifyKey(t *jwt.Token) (interface{}, error) {
+	key := s.active
+	if kid, ok := t.Header["kid"].(string); ok {
+		if key, ok = s.keys[kid]; !ok {
+			return nil, ErrUnknownSigningKey
+		}
+	}
+	if t.Method.Alg() != key.Algorithm {
+		return nil, ErrUnknownSigningKey
+	}
+	return key.verifyKey, nil
+}
+
+// JWK 公钥的 JSON Web Key 表示（RFC 7517）
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	N         string `json:"n,omitempty"`   // RSA 模数
+	E         string `json:"e,omitempty"`   // RSA 指数
+	Curve     string `json:"crv,omitempty"` // OKP 曲线
+	X         string `json:"x,omitempty"`   // OKP 公钥
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS 所有非对称密钥的公钥，供其他服务验证令牌。HMAC 密钥不会公开。
+func (s *TokenSigner) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	enc := base64.RawURLEncoding
+	for _, k := range s.sortedKeys() {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "RSA",
+				KeyID:     k.ID,
+				Algorithm: k.Algorithm,
+				Use:       "sig",
+				N:         enc.EncodeToString(pub.N.Bytes()),
+				E:         enc.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				KeyType:   "OKP",
+				KeyID:     k.ID,
+				Algorithm: k.Algorithm,
+				Use:       "sig",
+				Curve:     "Ed25519",
+				X:         enc.EncodeToString(pub),
+			})
+		}
+	}
+	return set
+}
+
+// sortedKeys 当前密钥在前，其余按ID排序，使 JWKS 的输出稳定
+func (s *TokenSigner) sortedKeys() []SigningKey {
+	keys := []SigningKey{s.active}
+	var others []string
+	for id := range s.keys {
+		if id != s.active.ID {
+			others = append(others, id)
+		}
+	}
+	sort.Strings(others)
+	for _, id := range others {
+		keys = append(keys, s.keys[id])
 	}
-	return nil, err
+	return keys
 }
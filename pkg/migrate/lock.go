@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockName MySQL 命名锁的名称，lockKey 为 PostgreSQL 咨询锁的键
+const (
+	lockName = "course_system_schema_migrations"
+	lockKey  = 7402153910268471
+)
+
+// acquireConn 从连接池中单独占用一个连接执行迁移，直到返回的 release 被调用。
+// MySQL 和 PostgreSQL 在该连接上获取会话级的迁移锁；SQLite 只允许一个写入者，不需要加锁，
+// 但重建表时需要在该连接上关闭外键检查，否则删除旧表会级联删除引用它的行。
+func acquireConn(ctx context.Context, db *gorm.DB, timeout time.Duration) (*sql.Conn, func(), error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dialect := db.Dialector.Name()
+	var unlock string
+	switch dialect {
+	case "mysql":
+		err = lockMySQL(ctx, conn, timeout)
+		unlock = "SELECT RELEASE_LOCK('" + lockName + "')"
+	case "postgres":
+		err = lockPostgres(ctx, conn, timeout)
+		unlock = fmt.Sprintf("SELECT pg_advisory_unlock(%d)", lockKey)
+	case "sqlite":
+		_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF")
+		unlock = "PRAGMA foreign_keys = ON"
+	}
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	return conn, func() {
+		if unlock != "" {
+			if _, err := conn.ExecContext(context.Background(), unlock); err != nil {
+				log.Printf("释放迁移锁失败: %v", err)
+			}
+		}
+		conn.Close()
+	}, nil
+}
+
+// lockMySQL GET_LOCK 超时返回 0，出错返回 NULL
+func lockMySQL(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	var got sql.NullInt64
+	seconds := int(timeout / time.Second)
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lockName, seconds).Scan(&got); err != nil {
+		return fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		return ErrLockTimeout
+	}
+	return nil
+}
+
+// lockPostgres pg_advisory_lock 会一直阻塞，由 context 控制等待时间
+func lockPostgres(ctx context.Context, conn *sql.Conn, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return ErrLockTimeout
+		}
+		return fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	return nil
+}
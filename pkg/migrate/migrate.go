@@ -0,0 +1,250 @@
+// Package migrate 按版本号顺序执行数据库迁移，并在 schema_migrations 表中记录已执行的版本。
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownVersion 数据库中记录了程序不认识的版本，通常是较新版本的程序执行过迁移
+	ErrUnknownVersion = errors.New("数据库中存在未知的迁移版本")
+	// ErrIrreversible 迁移没有提供回滚操作
+	ErrIrreversible = errors.New("该迁移不能回滚")
+	// ErrLockTimeout 等待其他实例完成迁移超时
+	ErrLockTimeout = errors.New("等待迁移锁超时")
+)
+
+// Migration 一个迁移版本。Up 和 Down 在同一个事务中执行并更新版本记录，
+// 但 MySQL 的 DDL 会隐式提交，因此每个迁移应只做一件事，失败后可以安全重试。
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // 为 nil 表示不能回滚
+}
+
+// schemaMigration 已执行的迁移
+type schemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255;not null"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status 迁移版本的执行情况
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"` // 为空表示尚未执行
+	Unknown   bool       `json:"unknown,omitempty"`    // 数据库中有记录但程序中没有该版本
+}
+
+// Migrator 执行迁移。修改数据库前先获取数据库锁，多个实例同时启动时只有一个执行迁移。
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// New migrations 的版本号必须为正数且不重复，可以乱序传入
+func New(db *gorm.DB, migrations []Migration, lockTimeout time.Duration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("迁移 %s 的版本号必须大于0", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("重复的迁移版本号: %d", m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("迁移 %d 缺少 Up", m.Version)
+		}
+	}
+	return &Migrator{db: db, migrations: sorted, lockTimeout: lockTimeout}, nil
+}
+
+// Latest 程序中最新的迁移版本，没有迁移时为 0
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status 所有迁移版本的执行情况，按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Status
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if rec, ok := applied[mig.Version]; ok {
+			s.AppliedAt = &rec.AppliedAt
+			delete(applied, mig.Version)
+		}
+		result = append(result, s)
+	}
+	for _, rec := range applied {
+		rec := rec
+		result = append(result, Status{Version: rec.Version, Name: rec.Name, AppliedAt: &rec.AppliedAt, Unknown: true})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
+
+// Pending 尚未执行的迁移。数据库中有未知版本时返回 ErrUnknownVersion
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return m.pending(applied)
+}
+
+// Up 依次执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		pending, err := m.pending(applied)
+		if err != nil {
+			return err
+		}
+		for _, mig := range pending {
+			err := run(db, mig.Up, func(tx *gorm.DB) error {
+				return tx.Create(&schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("执行迁移 %d_%s 失败: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down 按版本号从新到旧回滚 steps 个已执行的迁移，返回本次回滚的迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.withLock(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if _, err := m.pending(applied); err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("%w: %d_%s", ErrIrreversible, mig.Version, mig.Name)
+			}
+			err := run(db, mig.Down, func(tx *gorm.DB) error {
+				return tx.Delete(&schemaMigration{Version: mig.Version}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("回滚迁移 %d_%s 失败: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// withLock 在持有迁移锁的连接上执行 fn
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	conn, release, err := acquireConn(ctx, m.db, m.lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	db := m.db.Session(&gorm.Session{Context: ctx, NewDB: true})
+	db.Statement.ConnPool = conn
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+	}
+	if err := fn(db); err != nil {
+		return err
+	}
+	if db.Dialector.Name() == "sqlite" {
+		return checkSQLiteForeignKeys(db)
+	}
+	return nil
+}
+
+// run 在一个事务中执行迁移并更新版本记录
+func run(db *gorm.DB, migrate, record func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var snapshot *sqliteSnapshot
+		if tx.Dialector.Name() == "sqlite" {
+			var err error
+			if snapshot, err = snapshotSQLite(tx); err != nil {
+				return err
+			}
+		}
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		if snapshot != nil {
+			if err := snapshot.restoreIndexes(tx); err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// applied 已执行的迁移，schema_migrations 表不存在时视为没有执行过任何迁移
+func (m *Migrator) applied(db *gorm.DB) (map[int64]schemaMigration, error) {
+	result := make(map[int64]schemaMigration)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return result, nil
+	}
+	var records []schemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		result[rec.Version] = rec
+	}
+	return result, nil
+}
+
+func (m *Migrator) pending(applied map[int64]schemaMigration) ([]Migration, error) {
+	known := make(map[int64]bool, len(m.migrations))
+	var pending []Migration
+	for _, mig := range m.migrations {
+		known[mig.Version] = true
+		if _, ok := applied[mig.Version]; !ok {
+			pending = append(pending, mig)
+		}
+	}
+	for version := range applied {
+		if !known[version] {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return pending, nil
+}
@@ -0,0 +1,164 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/config"
+	"github.com/liuyifan1996/course-selection-system/migrations"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"github.com/liuyifan1996/course-selection-system/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// newTestDB 打开只在本测试中使用的内存 SQLite 数据库
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := config.InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: "file::memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newMigrator(t *testing.T, db *gorm.DB, migrations []migrate.Migration) *migrate.Migrator {
+	t.Helper()
+	m, err := migrate.New(db, migrations, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMigrationsUpDownUp(t *testing.T) {
+	// 迁移 0002 和 0006 需要加密密钥
+	fieldcrypt.SetDefault(&fieldcrypt.Keyring{
+		Active:   "test",
+		Keys:     map[string][]byte{"test": bytes.Repeat([]byte{1}, 32)},
+		IndexKey: bytes.Repeat([]byte{2}, 32),
+	})
+	db := newTestDB(t)
+	ctx := context.Background()
+	all := migrations.All()
+	m := newMigrator(t, db, all)
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(all) {
+		t.Fatalf("执行了 %d 个迁移，应为 %d", len(done), len(all))
+	}
+	if !db.Migrator().HasTable("courses") || !db.Migrator().HasColumn("courses", "deleted_at") {
+		t.Fatal("courses 表结构不正确")
+	}
+
+	done, err = m.Down(ctx, len(all))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(all) {
+		t.Fatalf("回滚了 %d 个迁移，应为 %d", len(done), len(all))
+	}
+	for _, table := range []string{"users", "courses", "enrollments"} {
+		if db.Migrator().HasTable(table) {
+			t.Errorf("回滚后 %s 表仍然存在", table)
+		}
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(all) {
+		t.Fatalf("回滚后有 %d 个未执行的迁移，应为 %d", len(pending), len(all))
+	}
+
+	if done, err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(all) {
+		t.Fatalf("再次执行了 %d 个迁移，应为 %d", len(done), len(all))
+	}
+	if pending, err = m.Pending(ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("pending = %v, err = %v", pending, err)
+	}
+}
+
+// testMigrations 每个版本创建一张表，回滚时删除
+func testMigrations(versions ...int64) []migrate.Migration {
+	var result []migrate.Migration
+	for _, v := range versions {
+		table := fmt.Sprintf("t%d", v)
+		result = append(result, migrate.Migration{
+			Version: v,
+			Name:    table,
+			Up:      func(tx *gorm.DB) error { return tx.Exec("CREATE TABLE " + table + " (id INTEGER)").Error },
+			Down:    func(tx *gorm.DB) error { return tx.Exec("DROP TABLE " + table).Error },
+		})
+	}
+	return result
+}
+
+func TestMigratorUnknownVersion(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	if _, err := newMigrator(t, db, testMigrations(1, 2, 3)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 较旧的程序不认识数据库中的版本 3
+	old := newMigrator(t, db, testMigrations(1, 2))
+	if _, err := old.Pending(ctx); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("Pending() err = %v", err)
+	}
+	if _, err := old.Up(ctx); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("Up() err = %v", err)
+	}
+	if _, err := old.Down(ctx, 1); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("Down() err = %v", err)
+	}
+
+	status, err := old.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 3 || !status[2].Unknown || status[2].AppliedAt == nil {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestMigratorIrreversible(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	migs := testMigrations(1, 2)
+	migs[0].Down = nil
+	m := newMigrator(t, db, migs)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 版本 2 回滚成功，遇到不能回滚的版本 1 时停止
+	done, err := m.Down(ctx, 2)
+	if !errors.Is(err, migrate.ErrIrreversible) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(done) != 1 || done[0].Version != 2 {
+		t.Errorf("done = %+v", done)
+	}
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Version != 2 || !db.Migrator().HasTable("t1") {
+		t.Errorf("pending = %+v", pending)
+	}
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SQLite 不能修改列和约束，GORM 通过新建表、复制数据、删除旧表的方式实现，
+// 旧表上的索引会随之删除。sqliteSnapshot 记录迁移前的表和索引，迁移后为被重建的表补回原有的索引。
+type sqliteSnapshot struct {
+	rootpages map[string]int64 // 表名 => 根页号，重建后的表根页号不同
+	indexes   []sqliteObject
+}
+
+type sqliteObject struct {
+	Type     string
+	Name     string
+	TblName  string
+	Rootpage int64
+	SQL      *string
+}
+
+func loadSQLiteObjects(tx *gorm.DB) ([]sqliteObject, error) {
+	var objects []sqliteObject
+	err := tx.Raw("SELECT type, name, tbl_name, rootpage, sql FROM sqlite_master WHERE type IN ('table', 'index')").
+		Scan(&objects).Error
+	return objects, err
+}
+
+func snapshotSQLite(tx *gorm.DB) (*sqliteSnapshot, error) {
+	objects, err := loadSQLiteObjects(tx)
+	if err != nil {
+		return nil, err
+	}
+	s := &sqliteSnapshot{rootpages: make(map[string]int64)}
+	for _, o := range objects {
+		switch {
+		case o.Type == "table":
+			s.rootpages[o.Name] = o.Rootpage
+		case o.SQL != nil: // 自动创建的唯一索引没有 sql，随建表语句一起保留
+			s.indexes = append(s.indexes, o)
+		}
+	}
+	return s, nil
+}
+
+// restoreIndexes 迁移中主动删除的索引如果所在的表也被重建，会被重新创建，这类迁移应在重建表之后再删除索引
+func (s *sqliteSnapshot) restoreIndexes(tx *gorm.DB) error {
+	objects, err := loadSQLiteObjects(tx)
+	if err != nil {
+		return err
+	}
+	rebuilt := make(map[string]bool)
+	existing := make(map[string]bool)
+	for _, o := range objects {
+		if o.Type == "table" {
+			if page, ok := s.rootpages[o.Name]; ok && page != o.Rootpage {
+				rebuilt[o.Name] = true
+			}
+		} else {
+			existing[o.Name] = true
+		}
+	}
+	for _, idx := range s.indexes {
+		if !rebuilt[idx.TblName] || existing[idx.Name] {
+			continue
+		}
+		if err := tx.Exec(*idx.SQL).Error; err != nil {
+			return fmt.Errorf("恢复索引 %s 失败: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
+
+// checkSQLiteForeignKeys 迁移期间关闭了外键检查，完成后确认没有留下违反外键的数据
+func checkSQLiteForeignKeys(db *gorm.DB) error {
+	var violations []map[string]interface{}
+	if err := db.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("迁移后有 %d 行数据违反外键约束，第一行: %v", len(violations), violations[0])
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message 发给用户的一条通知
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier 通知的投递方式
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier 只在标准日志中记录发送了哪类通知，仅用于本地开发。
+// 正文含有重置令牌等凭据，收件人是个人信息，都不写入日志；需要查看内容时使用 FileNotifier。
+type LogNotifier struct{}
+
+func (LogNotifier) Send(msg Message) error {
+	log.Printf("notify subject=%q（未实际发送）", msg.Subject)
+	return nil
+}
+
+// FileNotifier 将通知以 JSON 行追加到文件，便于本地测试读取
+type FileNotifier struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{Path: path}
+}
+
+func (n *FileNotifier) Send(msg Message) error {
+	data, err := json.Marshal(struct {
+		Message
+		Time time.Time `json:"time"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// SMTPNotifier 通过 SMTP 发送邮件，Username 为空时不认证
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPNotifier) Send(msg Message) error {
+	addr := net.JoinHostPort(n.Host, fmt.Sprint(n.Port))
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", n.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(addr, auth, n.From, []string{msg.To}, []byte(b.String()))
+}
@@ -0,0 +1,20 @@
+package pkg
+
+import "golang.org/x/crypto/bcrypt"
+
+// MaxPasswordBytes bcrypt 只能处理不超过72字节的密码
+const MaxPasswordBytes = 72
+
+// HashPassword 计算保存到数据库的密码哈希，登录密码和历史密码都使用该格式
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword 判断 password 是否与 HashPassword 生成的哈希一致，hash 为空时总是返回 false
+func CheckPassword(hash, password string) bool {
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
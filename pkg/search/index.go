@@ -0,0 +1,304 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 参数
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// 非精确匹配时的得分折扣
+const (
+	prefixWeight  = 0.7
+	fuzzy1Weight  = 0.5
+	fuzzy2Weight  = 0.25
+	hanCharWeight = 0.5
+)
+
+// Hit 一条搜索结果
+type Hit struct {
+	ID         int64             `json:"id"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"` // 字段名 -> 转义为 HTML 并用 <em></em> 标出命中词的文本
+}
+
+type document struct {
+	fields map[string]string
+	tokens map[string][]Token
+}
+
+// Index 内存倒排索引，并发安全
+type Index struct {
+	mu       sync.RWMutex
+	boosts   map[string]float64
+	docs     map[int64]*document
+	postings map[string]map[int64]map[string]int // 词元 -> 文档 -> 字段 -> 词频
+	fieldLen map[string]int                      // 各字段的词元总数，用于计算平均长度
+}
+
+// NewIndex 创建索引，boosts 为参与检索的字段及其权重
+func NewIndex(boosts map[string]float64) *Index {
+	return &Index{
+		boosts:   boosts,
+		docs:     make(map[int64]*document),
+		postings: make(map[string]map[int64]map[string]int),
+		fieldLen: make(map[string]int),
+	}
+}
+
+// Put 写入或覆盖一个文档
+func (idx *Index) Put(id int64, fields map[string]string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+
+	doc := &document{fields: make(map[string]string), tokens: make(map[string][]Token)}
+	for field, text := range fields {
+		if _, ok := idx.boosts[field]; !ok {
+			continue
+		}
+		tokens := Tokenize(text)
+		doc.fields[field] = text
+		doc.tokens[field] = tokens
+		idx.fieldLen[field] += len(tokens)
+		for _, t := range tokens {
+			byDoc := idx.postings[t.Term]
+			if byDoc == nil {
+				byDoc = make(map[int64]map[string]int)
+				idx.postings[t.Term] = byDoc
+			}
+			if byDoc[id] == nil {
+				byDoc[id] = make(map[string]int)
+			}
+			byDoc[id][field]++
+		}
+	}
+	idx.docs[id] = doc
+}
+
+// Delete 从索引中移除文档
+func (idx *Index) Delete(id int64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int64) {
+	doc, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	for field, tokens := range doc.tokens {
+		idx.fieldLen[field] -= len(tokens)
+		for _, t := range tokens {
+			if byDoc := idx.postings[t.Term]; byDoc != nil {
+				delete(byDoc, id)
+				if len(byDoc) == 0 {
+					delete(idx.postings, t.Term)
+				}
+			}
+		}
+	}
+	delete(idx.docs, id)
+}
+
+// Len 返回索引中的文档数
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// Search 按相关度降序返回最多 limit 条结果，limit <= 0 表示不限制
+func (idx *Index) Search(query string, limit int) []Hit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryTokens := uniqueTerms(Tokenize(query))
+	if len(queryTokens) == 0 || len(idx.docs) == 0 {
+		return nil
+	}
+
+	type docScore struct {
+		score   float64
+		matched int
+		terms   map[string]bool // 命中的索引词元，用于高亮
+	}
+	scores := make(map[int64]*docScore)
+
+	for _, q := range queryTokens {
+		best := make(map[int64]float64)
+		bestTerm := make(map[int64][]string)
+		for term, weight := range idx.expand(q) {
+			byDoc := idx.postings[term]
+			idf := idx.idf(len(byDoc))
+			for id, tfs := range byDoc {
+				s := weight * idf * idx.fieldScore(id, tfs)
+				if s > best[id] {
+					best[id] = s
+				}
+				bestTerm[id] = append(bestTerm[id], term)
+			}
+		}
+		for id, s := range best {
+			ds := scores[id]
+			if ds == nil {
+				ds = &docScore{terms: make(map[string]bool)}
+				scores[id] = ds
+			}
+			ds.score += s
+			ds.matched++
+			for _, term := range bestTerm[id] {
+				ds.terms[term] = true
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, ds := range scores {
+		// 命中的查询词越多，得分越高
+		coverage := float64(ds.matched) / float64(len(queryTokens))
+		hits = append(hits, Hit{
+			ID:         id,
+			Score:      ds.score * coverage * coverage,
+			Highlights: idx.highlight(idx.docs[id], ds.terms),
+		})
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// expand 将查询词扩展为索引中可匹配的词元及其权重，实现前缀匹配和容错
+func (idx *Index) expand(q string) map[string]float64 {
+	terms := make(map[string]float64)
+	if _, ok := idx.postings[q]; ok {
+		terms[q] = 1
+	}
+
+	if isHanTerm(q) {
+		// 单个汉字匹配包含该字的二元组
+		if len([]rune(q)) == 1 {
+			for term := range idx.postings {
+				if term != q && isHanTerm(term) && strings.Contains(term, q) {
+					terms[term] = max(terms[term], hanCharWeight)
+				}
+			}
+		}
+		return terms
+	}
+
+	maxDist := 0
+	switch n := len([]rune(q)); {
+	case n >= 8:
+		maxDist = 2
+	case n >= 4:
+		maxDist = 1
+	}
+	for term := range idx.postings {
+		if term == q || isHanTerm(term) {
+			continue
+		}
+		if len(q) >= 2 && strings.HasPrefix(term, q) {
+			terms[term] = max(terms[term], prefixWeight)
+			continue
+		}
+		if maxDist == 0 {
+			continue
+		}
+		switch d := editDistance(q, term, maxDist); {
+		case d > maxDist:
+		case d == 1:
+			terms[term] = max(terms[term], fuzzy1Weight)
+		case d == 2:
+			terms[term] = max(terms[term], fuzzy2Weight)
+		}
+	}
+	return terms
+}
+
+func (idx *Index) idf(docFreq int) float64 {
+	n := float64(len(idx.docs))
+	df := float64(docFreq)
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// fieldScore 按字段计算 BM25 词频得分并乘以字段权重
+func (idx *Index) fieldScore(id int64, tfs map[string]int) float64 {
+	doc := idx.docs[id]
+	var score float64
+	for field, tf := range tfs {
+		avgLen := float64(idx.fieldLen[field]) / float64(len(idx.docs))
+		if avgLen == 0 {
+			avgLen = 1
+		}
+		docLen := float64(len(doc.tokens[field]))
+		f := float64(tf)
+		score += idx.boosts[field] * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*docLen/avgLen))
+	}
+	return score
+}
+
+// highlight 用 <em></em> 包裹命中的片段，相邻或重叠的片段会合并。
+// 原文中的 <、& 等字符会被转义，结果可以直接作为 HTML 输出。
+func (idx *Index) highlight(doc *document, terms map[string]bool) map[string]string {
+	result := make(map[string]string)
+	for field, tokens := range doc.tokens {
+		var spans [][2]int
+		for _, t := range tokens {
+			if !terms[t.Term] {
+				continue
+			}
+			if n := len(spans); n > 0 && t.Start <= spans[n-1][1] {
+				spans[n-1][1] = max(spans[n-1][1], t.End)
+				continue
+			}
+			spans = append(spans, [2]int{t.Start, t.End})
+		}
+		if len(spans) == 0 {
+			continue
+		}
+
+		text := doc.fields[field]
+		var b strings.Builder
+		last := 0
+		for _, span := range spans {
+			b.WriteString(html.EscapeString(text[last:span[0]]))
+			b.WriteString("<em>")
+			b.WriteString(html.EscapeString(text[span[0]:span[1]]))
+			b.WriteString("</em>")
+			last = span[1]
+		}
+		b.WriteString(html.EscapeString(text[last:]))
+		result[field] = b.String()
+	}
+	return result
+}
+
+func uniqueTerms(tokens []Token) []string {
+	seen := make(map[string]bool, len(tokens))
+	var terms []string
+	for _, t := range tokens {
+		if !seen[t.Term] {
+			seen[t.Term] = true
+			terms = append(terms, t.Term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func newTestIndex() *Index {
+	idx := NewIndex(map[string]float64{"name": 3, "remark": 1})
+	idx.Put(1, map[string]string{"name": "数据结构", "remark": "线性表、树和图"})
+	idx.Put(2, map[string]string{"name": "算法设计与分析", "remark": "数据结构的后续课程"})
+	idx.Put(3, map[string]string{"name": "Python 程序设计", "remark": "面向零基础"})
+	idx.Put(4, map[string]string{"name": "Database Systems", "remark": "关系数据库原理"})
+	return idx
+}
+
+func hitIDs(hits []Hit) []int64 {
+	ids := make([]int64, 0, len(hits))
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	idx := newTestIndex()
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{name: "无结果", query: "编译器", want: []int64{}},
+		{name: "空查询", query: "、", want: []int64{}},
+		{name: "名称命中排在备注命中之前", query: "数据结构", want: []int64{1, 2, 4}},
+		{name: "单个汉字匹配二元组", query: "树", want: []int64{1}},
+		{name: "前缀匹配", query: "pyth", want: []int64{3}},
+		{name: "调换字母超出短词容错范围", query: "pyhton", want: []int64{}},
+		{name: "容错一个字符", query: "pythn", want: []int64{3}},
+		{name: "长词容错两个字符", query: "databsae", want: []int64{4}},
+		{name: "大小写不敏感", query: "DATABASE", want: []int64{4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hitIDs(idx.Search(tt.query, 0)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := NewIndex(map[string]float64{"name": 1})
+	idx.Put(1, map[string]string{"name": "数据库 课程 课程 课程 实验 实践 综合 设计"})
+	idx.Put(2, map[string]string{"name": "数据库"})
+	idx.Put(3, map[string]string{"name": "数据库 数据库"})
+	idx.Put(4, map[string]string{"name": "操作系统"})
+
+	// 词频越高得分越高，较短的字段得分更高
+	hits := idx.Search("数据库", 0)
+	if got, want := hitIDs(hits), []int64{3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("排序 = %v, want %v", got, want)
+	}
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Fatalf("得分未降序: %v", hits)
+		}
+	}
+
+	// 命中更多查询词的文档优先，前缀匹配也计入命中
+	idx.Put(5, map[string]string{"name": "go"})
+	idx.Put(6, map[string]string{"name": "golang"})
+	idx.Put(7, map[string]string{"name": "go 操作系统"})
+	if got := hitIDs(idx.Search("go 操作系统", 0)); len(got) != 4 || got[0] != 7 || got[1] != 4 {
+		t.Fatalf("排序 = %v, want 7 和 4 在前且包含 5、6", got)
+	}
+
+	if got := idx.Search("数据库", 1); len(got) != 1 || got[0].ID != 3 {
+		t.Fatalf("limit 未生效: %v", got)
+	}
+
+	idx.Delete(3)
+	if got, want := hitIDs(idx.Search("数据库", 0)), []int64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("删除后 = %v, want %v", got, want)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		query  string
+		want   map[string]string
+	}{
+		{
+			name:   "合并相邻片段",
+			fields: map[string]string{"name": "数据结构", "remark": "树和图"},
+			query:  "数据结构",
+			want:   map[string]string{"name": "<em>数据结构</em>"},
+		},
+		{
+			name:   "多个片段",
+			fields: map[string]string{"name": "Go 与 Go 并发"},
+			query:  "go",
+			want:   map[string]string{"name": "<em>Go</em> 与 <em>Go</em> 并发"},
+		},
+		{
+			name:   "转义命中片段以外的文本",
+			fields: map[string]string{"name": "数据库", "remark": `<script>alert("x")</script> 数据库 & SQL`},
+			query:  "数据库",
+			want: map[string]string{
+				"name":   "<em>数据库</em>",
+				"remark": "&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt; <em>数据库</em> &amp; SQL",
+			},
+		},
+		{
+			name:   "转义命中片段",
+			fields: map[string]string{"name": "<b>script</b>"},
+			query:  "script",
+			want:   map[string]string{"name": "&lt;b&gt;<em>script</em>&lt;/b&gt;"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(map[string]float64{"name": 2, "remark": 1})
+			idx.Put(1, tt.fields)
+			hits := idx.Search(tt.query, 0)
+			if len(hits) != 1 {
+				t.Fatalf("hits = %v", hits)
+			}
+			if !reflect.DeepEqual(hits[0].Highlights, tt.want) {
+				t.Errorf("Highlights = %q, want %q", hits[0].Highlights, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token 分词结果，Start/End 为原文中的字节偏移
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize 将文本切分为词元：
+// 连续的汉字按二元组(bigram)切分，单个汉字保留为一元；
+// 字母和数字按单词切分并转为小写；其余字符作为分隔符。
+func Tokenize(text string) []Token {
+	var tokens []Token
+
+	type char struct {
+		r     rune
+		start int
+		end   int
+	}
+
+	var hanRun []char
+	flushHan := func() {
+		switch len(hanRun) {
+		case 0:
+		case 1:
+			tokens = append(tokens, Token{Term: string(hanRun[0].r), Start: hanRun[0].start, End: hanRun[0].end})
+		default:
+			for i := 0; i+1 < len(hanRun); i++ {
+				tokens = append(tokens, Token{
+					Term:  string([]rune{hanRun[i].r, hanRun[i+1].r}),
+					Start: hanRun[i].start,
+					End:   hanRun[i+1].end,
+				})
+			}
+		}
+		hanRun = hanRun[:0]
+	}
+
+	wordStart := -1
+	flushWord := func(end int) {
+		if wordStart >= 0 {
+			tokens = append(tokens, Token{Term: strings.ToLower(text[wordStart:end]), Start: wordStart, End: end})
+			wordStart = -1
+		}
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord(i)
+			hanRun = append(hanRun, char{r: r, start: i, end: i + size})
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			if wordStart < 0 {
+				wordStart = i
+			}
+		default:
+			flushHan()
+			flushWord(i)
+		}
+		i += size
+	}
+	flushHan()
+	flushWord(len(text))
+
+	return tokens
+}
+
+// isHanTerm 判断词元是否为汉字词元，汉字词元不做模糊匹配
+func isHanTerm(term string) bool {
+	r, _ := utf8.DecodeRuneInString(term)
+	return unicode.Is(unicode.Han, r)
+}
+
+// editDistance 计算两个词之间的 Levenshtein 距离，超过 max 时提前返回 max+1
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,86 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Token
+	}{
+		{name: "空文本", text: "", want: nil},
+		{name: "单个汉字", text: "课", want: []Token{{Term: "课", Start: 0, End: 3}}},
+		{
+			name: "汉字二元组",
+			text: "数据结构",
+			want: []Token{
+				{Term: "数据", Start: 0, End: 6},
+				{Term: "据结", Start: 3, End: 9},
+				{Term: "结构", Start: 6, End: 12},
+			},
+		},
+		{
+			name: "中英文混合",
+			text: "Go语言 Web开发2",
+			want: []Token{
+				{Term: "go", Start: 0, End: 2},
+				{Term: "语言", Start: 2, End: 8},
+				{Term: "web", Start: 9, End: 12},
+				{Term: "开发", Start: 12, End: 18},
+				{Term: "2", Start: 18, End: 19},
+			},
+		},
+		{
+			name: "标点分隔",
+			text: "C++、操作系统(OS)",
+			want: []Token{
+				{Term: "c", Start: 0, End: 1},
+				{Term: "操作", Start: 6, End: 12},
+				{Term: "作系", Start: 9, End: 15},
+				{Term: "系统", Start: 12, End: 18},
+				{Term: "os", Start: 19, End: 21},
+			},
+		},
+		{
+			name: "字母数字连在一起",
+			text: "CS101 MySQL8",
+			want: []Token{
+				{Term: "cs101", Start: 0, End: 5},
+				{Term: "mysql8", Start: 6, End: 12},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		max  int
+		want int
+	}{
+		{a: "python", b: "python", max: 2, want: 0},
+		{a: "python", b: "pyhton", max: 2, want: 2},
+		{a: "python", b: "pythn", max: 2, want: 1},
+		{a: "python", b: "pythons", max: 2, want: 1},
+		{a: "kitten", b: "sitting", max: 3, want: 3},
+		{a: "kitten", b: "sitting", max: 2, want: 3}, // 超过 max 时返回 max+1
+		{a: "go", b: "golang", max: 1, want: 2},      // 长度差超过 max
+		{a: "数据结构", b: "数据结果", max: 1, want: 1},      // 按字符而不是字节计算
+		{a: "", b: "abc", max: 3, want: 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b, tt.max); got != tt.want {
+			t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package seed
+
+import "github.com/liuyifan1996/course-selection-system/api/model"
+
+// 常见姓氏，按人口排序，靠前的被选中的概率更高
+var surnames = []string{
+	"王", "李", "张", "刘", "陈", "杨", "黄", "赵", "吴", "周",
+	"徐", "孙", "马", "朱", "胡", "郭", "何", "高", "林", "罗",
+	"郑", "梁", "谢", "宋", "唐", "许", "韩", "冯", "邓", "曹",
+	"彭", "曾", "肖", "田", "董", "袁", "潘", "于", "蒋", "蔡",
+	"余", "杜", "叶", "程", "苏", "魏", "吕", "丁", "任", "沈",
+	"姚", "卢", "姜", "崔", "钟", "谭", "陆", "汪", "范", "金",
+	"石", "廖", "贾", "夏", "韦", "付", "方", "白", "邹", "孟",
+	"熊", "秦", "邱", "江", "尹", "薛", "闫", "段", "雷", "侯",
+	"欧阳", "上官", "司马", "诸葛",
+}
+
+// 名字常用字
+var givenNameChars = []rune(
+	"伟芳娜秀英敏静丽强磊军洋勇艳杰娟涛明超兰霞平刚桂" +
+		"文华建国红玉志新海波宁浩然子轩梓涵宇欣怡一诺博思" +
+		"雨晨佳琪嘉俊晓东晗睿泽瑞雪婷慧颖鑫鹏飞辉晶琳倩诗" +
+		"天昊若曦逸凡书瑶铭可馨承宏亮悦梦旭阳清雅安乐家豪")
+
+// 生成证件号使用的县级行政区划代码
+var regionCodes = []string{
+	"110101", "110105", "110108", "120101", "130102", "140105", "210102",
+	"310101", "310104", "310115", "320102", "320506", "330106", "330203",
+	"340104", "350203", "360102", "370102", "410105", "420111", "430104",
+	"440103", "440304", "450103", "500103", "510107", "530102", "610113",
+}
+
+type departmentSpec struct {
+	code   string
+	name   string
+	prefix string // 课程编号前缀
+	majors []majorSpec
+}
+
+type majorSpec struct {
+	code string
+	name string
+}
+
+// 院系和专业，专业代码使用本科专业目录中的代码
+var departments = []departmentSpec{
+	{code: "CS", name: "计算机学院", prefix: "CS", majors: []majorSpec{
+		{code: "080901", name: "计算机科学与技术"},
+		{code: "080902", name: "软件工程"},
+	}},
+	{code: "MATH", name: "数学与统计学院", prefix: "MA", majors: []majorSpec{
+		{code: "070101", name: "数学与应用数学"},
+		{code: "071201", name: "统计学"},
+	}},
+	{code: "EM", name: "经济管理学院", prefix: "EC", majors: []majorSpec{
+		{code: "020101", name: "经济学"},
+		{code: "120203", name: "会计学"},
+	}},
+}
+
+type courseSpec struct {
+	name       string
+	department int // departments 的下标，-1 表示通识课程，由公共教学部开设
+	major      int // 核心课程所属专业在院系中的下标
+	category   string
+	hours      int
+	minLevel   int
+	tags       []string
+}
+
+// 课程目录。学科基础课对本院系各专业必修，专业核心课对所属专业必修，其余不限专业
+var catalog = []courseSpec{
+	{name: "大学英语", department: -1, category: model.CourseCategoryGeneral, hours: 64, tags: []string{"外语"}},
+	{name: "思想道德与法治", department: -1, category: model.CourseCategoryGeneral, hours: 48, tags: []string{"思政"}},
+	{name: "中国近现代史纲要", department: -1, category: model.CourseCategoryGeneral, hours: 48, tags: []string{"思政", "历史"}},
+	{name: "体育与健康", department: -1, category: model.CourseCategoryGeneral, hours: 32, tags: []string{"体育"}},
+	{name: "大学生心理健康", department: -1, category: model.CourseCategoryGeneral, hours: 32, tags: []string{"心理"}},
+	{name: "创新创业基础", department: -1, category: model.CourseCategoryPractice, hours: 32, tags: []string{"实践"}},
+
+	{name: "程序设计基础", department: 0, category: model.CourseCategoryFoundation, hours: 64, tags: []string{"编程"}},
+	{name: "离散数学", department: 0, category: model.CourseCategoryFoundation, hours: 48, tags: []string{"数学"}},
+	{name: "数据结构", department: 0, category: model.CourseCategoryCore, major: 0, hours: 64, minLevel: 2, tags: []string{"编程", "算法"}},
+	{name: "操作系统", department: 0, category: model.CourseCategoryCore, major: 0, hours: 64, minLevel: 2, tags: []string{"系统"}},
+	{name: "计算机网络", department: 0, category: model.CourseCategoryCore, major: 0, hours: 48, minLevel: 2, tags: []string{"网络"}},
+	{name: "软件工程导论", department: 0, category: model.CourseCategoryCore, major: 1, hours: 48, minLevel: 2, tags: []string{"工程"}},
+	{name: "软件测试", department: 0, category: model.CourseCategoryCore, major: 1, hours: 48, minLevel: 3, tags: []string{"工程", "测试"}},
+	{name: "人工智能导论", department: 0, category: model.CourseCategoryElective, hours: 32, tags: []string{"人工智能"}},
+	{name: "Web 应用开发", department: 0, category: model.CourseCategoryElective, hours: 48, tags: []string{"编程", "前端"}},
+	{name: "数据库课程设计", department: 0, category: model.CourseCategoryPractice, hours: 32, minLevel: 2, tags: []string{"实践", "数据库"}},
+
+	{name: "数学分析", department: 1, category: model.CourseCategoryFoundation, hours: 96, tags: []string{"数学"}},
+	{name: "高等代数", department: 1, category: model.CourseCategoryFoundation, hours: 64, tags: []string{"数学"}},
+	{name: "常微分方程", department: 1, category: model.CourseCategoryCore, major: 0, hours: 48, minLevel: 2, tags: []string{"数学"}},
+	{name: "复变函数", department: 1, category: model.CourseCategoryCore, major: 0, hours: 48, minLevel: 2, tags: []string{"数学"}},
+	{name: "概率论与数理统计", department: 1, category: model.CourseCategoryCore, major: 1, hours: 64, minLevel: 2, tags: []string{"统计"}},
+	{name: "多元统计分析", department: 1, category: model.CourseCategoryCore, major: 1, hours: 48, minLevel: 3, tags: []string{"统计"}},
+	{name: "数值分析", department: 1, category: model.CourseCategoryElective, hours: 48, tags: []string{"数学", "计算"}},
+	{name: "运筹学", department: 1, category: model.CourseCategoryElective, hours: 48, tags: []string{"优化"}},
+
+	{name: "微观经济学", department: 2, category: model.CourseCategoryFoundation, hours: 48, tags: []string{"经济"}},
+	{name: "管理学原理", department: 2, category: model.CourseCategoryFoundation, hours: 48, tags: []string{"管理"}},
+	{name: "宏观经济学", department: 2, category: model.CourseCategoryCore, major: 0, hours: 48, minLevel: 2, tags: []string{"经济"}},
+	{name: "计量经济学", department: 2, category: model.CourseCategoryCore, major: 0, hours: 48, minLevel: 3, tags: []string{"经济", "统计"}},
+	{name: "中级财务会计", department: 2, category: model.CourseCategoryCore, major: 1, hours: 64, minLevel: 2, tags: []string{"会计"}},
+	{name: "审计学", department: 2, category: model.CourseCategoryCore, major: 1, hours: 48, minLevel: 3, tags: []string{"会计", "审计"}},
+	{name: "市场营销", department: 2, category: model.CourseCategoryElective, hours: 32, tags: []string{"管理"}},
+	{name: "证券投资学", department: 2, category: model.CourseCategoryElective, hours: 32, tags: []string{"金融"}},
+}
+
+// 通识课程的编号前缀
+const generalPrefix = "GE"
+
+// 课程名额的可选值
+var capacities = []int{30, 40, 60, 80, 120}
@@ -0,0 +1,599 @@
+// Package seed 生成院系、教师、学生、课程和选课记录等演示和测试数据。
+// 相同的随机种子和数量总是生成相同的数据；按证件号、编号等自然键查找已有的行，重复执行不会产生重复数据。
+package seed
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/liuyifan1996/course-selection-system/api/model"
+	"github.com/liuyifan1996/course-selection-system/pkg"
+	"github.com/liuyifan1996/course-selection-system/pkg/fieldcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNegativeCount = errors.New("生成数量不能为负数")
+	ErrNoTeachers    = errors.New("生成课程需要至少一名教师")
+	ErrNoSessions    = errors.New("生成课程需要至少一个开课学期")
+	ErrNoPassword    = errors.New("未指定生成用户的登录密码")
+)
+
+// Options 生成数据的数量和随机种子
+type Options struct {
+	Seed        uint64
+	Teachers    int
+	Students    int
+	Courses     int       // 不同课程编号的数量
+	Sessions    int       // 每门课程开课的学期数。最近一个学期尚未开课、开放选课，之前的学期已结课并登记成绩
+	Enrollments int       // 选课记录总数，受名额、面向专业和年级限制，可能达不到
+	Password    string    // 生成用户的登录密码
+	Now         time.Time // 按该时间确定各开课学期，为零时使用当前时间。同一学期内多次执行生成的数据相同
+}
+
+// DefaultOptions seed 命令的默认参数。登录密码没有默认值，必须由调用方指定
+var DefaultOptions = Options{
+	Seed:        1,
+	Teachers:    20,
+	Students:    500,
+	Courses:     30,
+	Sessions:    2,
+	Enrollments: 3000,
+}
+
+// Result 生成结果。Teachers 和 Students 的 IDCard 为明文，可以直接用于登录
+type Result struct {
+	Teachers    []model.User
+	Students    []model.User
+	Courses     []model.Course
+	Enrollments int    // 生成的选课记录数，包括已存在的
+	Created     Counts // 本次新写入的行数，重复执行时全部为0
+}
+
+// Counts 各类数据的行数
+type Counts struct {
+	Departments int
+	Majors      int
+	Cohorts     int
+	Users       int
+	Courses     int
+	Enrollments int
+}
+
+// Run 在一个事务中写入生成的数据，已存在的行保持不变
+func Run(db *gorm.DB, opts Options) (*Result, error) {
+	if opts.Teachers < 0 || opts.Students < 0 || opts.Courses < 0 || opts.Sessions < 0 || opts.Enrollments < 0 {
+		return nil, ErrNegativeCount
+	}
+	if opts.Courses > 0 && opts.Teachers == 0 {
+		return nil, ErrNoTeachers
+	}
+	if opts.Courses > 0 && opts.Sessions == 0 {
+		return nil, ErrNoSessions
+	}
+	if opts.Password == "" && opts.Teachers+opts.Students > 0 {
+		return nil, ErrNoPassword
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Password != "" {
+		// 用户表保存密码哈希，所有生成的用户共用一个
+		hash, err := pkg.HashPassword(opts.Password)
+		if err != nil {
+			return nil, err
+		}
+		opts.Password = hash
+	}
+
+	p := newPlan(opts)
+	result := &Result{Enrollments: len(p.enrollments)}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return p.save(tx, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// plan 生成的全部数据，先在内存中按随机种子生成，再写入数据库，保证结果与数据库中已有的数据无关
+type plan struct {
+	departments []model.Department
+	majors      []model.Major
+	majorDept   []int // 专业所属院系在 departments 中的下标
+	cohorts     []model.Cohort
+	cohortMajor []int // 行政班所属专业在 majors 中的下标
+	teachers    []teacherPlan
+	students    []studentPlan
+	courses     []coursePlan
+	enrollments []enrollmentPlan
+}
+
+type teacherPlan struct {
+	user       model.User
+	department int
+}
+
+type studentPlan struct {
+	user   model.User
+	major  int
+	cohort int
+}
+
+type coursePlan struct {
+	course    model.Course
+	spec      int   // 在 catalog 中的下标，同一门课程各学期相同
+	teacher   int   // 授课教师在 teachers 中的下标
+	audiences []int // 面向的专业在 majors 中的下标
+	past      bool  // 是否已结课
+}
+
+type enrollmentPlan struct {
+	student int
+	course  int
+	status  string
+	score   *float64
+}
+
+func newPlan(opts Options) *plan {
+	r := rand.New(rand.NewPCG(opts.Seed, 0x5eed))
+	p := &plan{}
+	p.organizations(opts)
+	ids := make(map[string]bool)
+	p.generateTeachers(r, opts, ids)
+	p.generateStudents(r, opts, ids)
+	p.generateCourses(r, opts)
+	p.generateEnrollments(r, opts)
+	return p
+}
+
+// organizations 固定的院系、专业，以及在校四个年级每个专业两个行政班
+func (p *plan) organizations(opts Options) {
+	for d, spec := range departments {
+		p.departments = append(p.departments, model.Department{Code: spec.code, Name: spec.name})
+		for _, m := range spec.majors {
+			p.majors = append(p.majors, model.Major{Code: m.code, Name: m.name})
+			p.majorDept = append(p.majorDept, d)
+		}
+	}
+	if opts.Students == 0 {
+		return
+	}
+	year := academicYear(opts.Now)
+	for m := range p.majors {
+		for y := year - 3; y <= year; y++ {
+			for class := 1; class <= 2; class++ {
+				p.cohorts = append(p.cohorts, model.Cohort{Year: y, ClassName: fmt.Sprintf("%d班", class)})
+				p.cohortMajor = append(p.cohortMajor, m)
+			}
+		}
+	}
+}
+
+var titles = []string{"讲师", "讲师", "副教授", "副教授", "教授"}
+
+var weekdays = []string{"周一", "周二", "周三", "周四", "周五"}
+
+func (p *plan) generateTeachers(r *rand.Rand, opts Options, ids map[string]bool) {
+	for i := 0; i < opts.Teachers; i++ {
+		d := i % len(departments)
+		birth := randomDate(r, 1962, 1992)
+		hour := 8 + 2*r.IntN(5)
+		p.teachers = append(p.teachers, teacherPlan{
+			department: d,
+			user: model.User{
+				PublicID:     publicID(r),
+				IDCard:       idCard(r, birth, ids),
+				DocumentType: model.DocumentTypeIDCard,
+				Password:     opts.Password,
+				Name:         chineseName(r),
+				Role:         "teacher",
+				Email:        fmt.Sprintf("t%04d@example.edu.cn", i+1),
+				Phone:        phone(r),
+				Language:     "zh-CN",
+				Bio:          departments[d].name + titles[r.IntN(len(titles))],
+				OfficeHours:  fmt.Sprintf("%s %02d:00-%02d:00", weekdays[r.IntN(len(weekdays))], hour, hour+2),
+			},
+		})
+	}
+}
+
+func (p *plan) generateStudents(r *rand.Rand, opts Options, ids map[string]bool) {
+	year := academicYear(opts.Now)
+	seq := make(map[string]int) // 每个专业每个年级的学号序号
+	for i := 0; i < opts.Students; i++ {
+		m := r.IntN(len(p.majors))
+		enrollmentYear := year - r.IntN(4)
+		class := r.IntN(2)
+		cohort := 0
+		for c := range p.cohorts {
+			if p.cohortMajor[c] == m && p.cohorts[c].Year == enrollmentYear && p.cohorts[c].ClassName == fmt.Sprintf("%d班", class+1) {
+				cohort = c
+				break
+			}
+		}
+		key := fmt.Sprintf("%d%s", enrollmentYear, p.majors[m].Code)
+		seq[key]++
+		birthYear := enrollmentYear - 18 - r.IntN(2)
+		p.students = append(p.students, studentPlan{
+			major:  m,
+			cohort: cohort,
+			user: model.User{
+				PublicID:       publicID(r),
+				IDCard:         idCard(r, randomDate(r, birthYear, birthYear), ids),
+				DocumentType:   model.DocumentTypeIDCard,
+				Password:       opts.Password,
+				Name:           chineseName(r),
+				Role:           "student",
+				Email:          fmt.Sprintf("%s%03d@stu.example.edu.cn", key, seq[key]),
+				Phone:          phone(r),
+				Language:       "zh-CN",
+				EnrollmentYear: enrollmentYear,
+			},
+		})
+	}
+}
+
+var ordinals = []string{"", "（二）", "（三）", "（四）", "（五）", "（六）", "（七）", "（八）", "（九）", "（十）"}
+
+// generateCourses 课程目录用完后以“（二）”等后缀继续编排，每门课程在 Sessions 个学期开课
+func (p *plan) generateCourses(r *rand.Rand, opts Options) {
+	if opts.Courses == 0 {
+		return
+	}
+	terms := recentTerms(opts.Now, opts.Sessions)
+	order := r.Perm(len(catalog))
+	numbers := make(map[string]int)
+	for i := 0; i < opts.Courses; i++ {
+		s := order[i%len(catalog)]
+		spec := catalog[s]
+		name := spec.name
+		if round := i / len(catalog); round < len(ordinals) {
+			name += ordinals[round]
+		} else {
+			name += fmt.Sprintf("（%d）", round+1)
+		}
+		prefix := generalPrefix
+		if spec.department >= 0 {
+			prefix = departments[spec.department].prefix
+		}
+		numbers[prefix]++
+		code := fmt.Sprintf("%s%d", prefix, 100+numbers[prefix])
+
+		var audiences []int
+		var requirement string
+		if spec.department >= 0 {
+			for m := range p.majors {
+				if p.majorDept[m] != spec.department {
+					continue
+				}
+				switch spec.category {
+				case model.CourseCategoryFoundation:
+					audiences, requirement = append(audiences, m), model.RequirementRequired
+				case model.CourseCategoryCore:
+					if m == p.departmentMajor(spec.department, spec.major) {
+						audiences, requirement = append(audiences, m), model.RequirementRequired
+					}
+				case model.CourseCategoryPractice:
+					audiences, requirement = append(audiences, m), model.RequirementElective
+				}
+			}
+		}
+
+		teacher := p.pickTeacher(r, spec.department)
+		capacity := capacities[r.IntN(len(capacities))]
+		for t, term := range terms {
+			course := model.Course{
+				Code:          code,
+				Name:          name,
+				StudentMaxNum: capacity,
+				Hours:         spec.hours,
+				Credits:       float64(spec.hours) / 16,
+				Category:      spec.category,
+				Term:          term.name,
+				Status:        model.CourseStatusOpen,
+				StartDate:     term.start.AddDate(0, 0, r.IntN(7)),
+				MinYearLevel:  spec.minLevel,
+			}
+			past := t < len(terms)-1
+			if past {
+				course.Status = model.CourseStatusClosed
+			}
+			for _, tag := range spec.tags {
+				course.Tags = append(course.Tags, model.CourseTag{Tag: tag})
+			}
+			for range audiences {
+				course.Audiences = append(course.Audiences, model.CourseAudience{Requirement: requirement})
+			}
+			p.courses = append(p.courses, coursePlan{course: course, spec: s, teacher: teacher, audiences: audiences, past: past})
+		}
+	}
+}
+
+// departmentMajor 院系中第 i 个专业在 majors 中的下标
+func (p *plan) departmentMajor(department, i int) int {
+	for m := range p.majors {
+		if p.majorDept[m] != department {
+			continue
+		}
+		if i == 0 {
+			return m
+		}
+		i--
+	}
+	return -1
+}
+
+// pickTeacher 优先从开课院系的教师中选择，通识课程或院系没有教师时从全部教师中选择
+func (p *plan) pickTeacher(r *rand.Rand, department int) int {
+	var candidates []int
+	for t := range p.teachers {
+		if p.teachers[t].department == department {
+			candidates = append(candidates, t)
+		}
+	}
+	if len(candidates) == 0 {
+		return r.IntN(len(p.teachers))
+	}
+	return candidates[r.IntN(len(candidates))]
+}
+
+// generateEnrollments 随机选择学生和课程，跳过名额已满、不满足专业和年级限制以及已修过同一门课程的组合。
+// 已结课的选课记录登记成绩，60分及以上为通过。
+func (p *plan) generateEnrollments(r *rand.Rand, opts Options) {
+	if len(p.students) == 0 || len(p.courses) == 0 {
+		return
+	}
+	type studentCourse struct{ student, spec int }
+	taken := make(map[studentCourse]bool)
+	counts := make([]int, len(p.courses))
+	for attempts := 0; len(p.enrollments) < opts.Enrollments && attempts < opts.Enrollments*20; attempts++ {
+		s, c := r.IntN(len(p.students)), r.IntN(len(p.courses))
+		course := &p.courses[c]
+		key := studentCourse{s, course.spec}
+		if taken[key] || counts[c] >= course.course.StudentMaxNum || !p.eligible(s, c) {
+			continue
+		}
+		taken[key] = true
+		counts[c]++
+
+		e := enrollmentPlan{student: s, course: c, status: model.EnrollmentStatusEnrolled}
+		if course.past {
+			score := 45 + 55*math.Max(r.Float64(), r.Float64())
+			score = math.Round(score*2) / 2
+			e.score = &score
+			e.status = model.EnrollmentStatusPassed
+			if score < 60 {
+				e.status = model.EnrollmentStatusFailed
+			}
+		}
+		p.enrollments = append(p.enrollments, e)
+	}
+}
+
+// eligible 与选课时的检查一致：课程限定专业时学生须属于其中之一，开课时学生须在校且达到最低年级
+func (p *plan) eligible(s, c int) bool {
+	student, course := &p.students[s], &p.courses[c]
+	if len(course.audiences) > 0 {
+		allowed := false
+		for _, m := range course.audiences {
+			allowed = allowed || m == student.major
+		}
+		if !allowed {
+			return false
+		}
+	}
+	level := model.YearLevel(student.user.EnrollmentYear, course.course.StartDate)
+	return level >= 1 && level >= course.course.MinYearLevel
+}
+
+// save 按自然键查找已有的行，不存在时创建
+func (p *plan) save(tx *gorm.DB, result *Result) error {
+	created := &result.Created
+	for i := range p.departments {
+		if err := firstOrCreate(tx, &p.departments[i], &created.Departments, "code = ?", p.departments[i].Code); err != nil {
+			return fmt.Errorf("写入院系失败: %w", err)
+		}
+	}
+	for i := range p.majors {
+		p.majors[i].DepartmentID = p.departments[p.majorDept[i]].ID
+		if err := firstOrCreate(tx, &p.majors[i], &created.Majors, "code = ?", p.majors[i].Code); err != nil {
+			return fmt.Errorf("写入专业失败: %w", err)
+		}
+	}
+	for i := range p.cohorts {
+		c := &p.cohorts[i]
+		c.MajorID = p.majors[p.cohortMajor[i]].ID
+		if err := firstOrCreate(tx, c, &created.Cohorts, "major_id = ? AND year = ? AND class_name = ?", c.MajorID, c.Year, c.ClassName); err != nil {
+			return fmt.Errorf("写入行政班失败: %w", err)
+		}
+	}
+
+	for i := range p.teachers {
+		t := &p.teachers[i]
+		t.user.DepartmentID = &p.departments[t.department].ID
+		if err := saveUser(tx, &t.user, &created.Users); err != nil {
+			return err
+		}
+		result.Teachers = append(result.Teachers, t.user)
+	}
+	for i := range p.students {
+		s := &p.students[i]
+		s.user.DepartmentID = &p.departments[p.majorDept[s.major]].ID
+		s.user.MajorID = &p.majors[s.major].ID
+		s.user.CohortID = &p.cohorts[s.cohort].ID
+		if err := saveUser(tx, &s.user, &created.Users); err != nil {
+			return err
+		}
+		result.Students = append(result.Students, s.user)
+	}
+
+	for i := range p.courses {
+		c := &p.courses[i]
+		c.course.TeacherID = p.teachers[c.teacher].user.PublicID
+		for a, m := range c.audiences {
+			c.course.Audiences[a].MajorID = p.majors[m].ID
+		}
+		err := firstOrCreate(tx, &c.course, &created.Courses, "code = ? AND term = ?", c.course.Code, c.course.Term)
+		if err != nil {
+			return fmt.Errorf("写入课程 %s（%s）失败: %w", c.course.Code, c.course.Term, err)
+		}
+		result.Courses = append(result.Courses, c.course)
+	}
+
+	rows := make([]model.Enrollment, 0, len(p.enrollments))
+	for _, e := range p.enrollments {
+		rows = append(rows, model.Enrollment{
+			CourseID:  p.courses[e.course].course.ID,
+			StudentID: p.students[e.student].user.ID,
+			Status:    e.status,
+			Score:     e.score,
+		})
+	}
+	if len(rows) > 0 {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(rows, 500)
+		if res.Error != nil {
+			return fmt.Errorf("写入选课记录失败: %w", res.Error)
+		}
+		created.Enrollments = int(res.RowsAffected)
+	}
+	return nil
+}
+
+// saveUser 按证件号的盲索引查找用户，已存在时使用数据库中的记录
+func saveUser(tx *gorm.DB, user *model.User, created *int) error {
+	hash, err := fieldcrypt.BlindIndex(user.IDCard)
+	if err != nil {
+		return fmt.Errorf("计算证件号索引失败: %w", err)
+	}
+	if err := firstOrCreate(tx, user, created, "id_card_hash = ?", hash); err != nil {
+		return fmt.Errorf("写入用户 %s 失败: %w", user.Name, err)
+	}
+	return nil
+}
+
+// firstOrCreate 查找满足条件的行并覆盖 row，不存在时创建 row 并增加 created
+func firstOrCreate[T any](tx *gorm.DB, row *T, created *int, query string, args ...interface{}) error {
+	// 用 Find 而不是 First，找不到时不会在日志中输出 record not found
+	var existing []T
+	if err := tx.Where(query, args...).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		*row = existing[0]
+		return nil
+	}
+	if err := tx.Create(row).Error; err != nil {
+		return err
+	}
+	*created++
+	return nil
+}
+
+// academicYear 学年的起始年份，每年9月开始新学年
+func academicYear(now time.Time) int {
+	if now.Month() < time.September {
+		return now.Year() - 1
+	}
+	return now.Year()
+}
+
+type term struct {
+	name  string
+	start time.Time
+}
+
+// termStart 学年 year 第 n 学期的开学日期：第一学期9月1日，第二学期次年2月24日
+func termStart(year, n int, loc *time.Location) time.Time {
+	if n == 1 {
+		return time.Date(year, time.September, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(year+1, time.February, 24, 0, 0, 0, 0, loc)
+}
+
+// recentTerms 返回 now 之后开学的第一个学期，以及正在进行的学期之前的 count-1 个已结束的学期，按时间先后排列
+func recentTerms(now time.Time, count int) []term {
+	year, n := academicYear(now), 1
+	for !termStart(year, n, now.Location()).After(now) {
+		year, n = nextTerm(year, n)
+	}
+	terms := make([]term, count)
+	for i := count - 1; i >= 0; i-- {
+		terms[i] = term{name: fmt.Sprintf("%d-%d-%d", year, year+1, n), start: termStart(year, n, now.Location())}
+		if i == count-1 {
+			// 跳过正在进行的学期，它既不能选课也还没有成绩
+			year, n = previousTerm(year, n)
+		}
+		year, n = previousTerm(year, n)
+	}
+	return terms
+}
+
+func nextTerm(year, n int) (int, int) {
+	if n == 1 {
+		return year, 2
+	}
+	return year + 1, 1
+}
+
+func previousTerm(year, n int) (int, int) {
+	if n == 2 {
+		return year, 1
+	}
+	return year - 1, 2
+}
+
+// chineseName 姓氏加一到两个字的名字
+func chineseName(r *rand.Rand) string {
+	// 姓氏按常见程度取前面的概率更高
+	surname := surnames[min(r.IntN(len(surnames)), r.IntN(len(surnames)))]
+	given := string(givenNameChars[r.IntN(len(givenNameChars))])
+	if r.IntN(3) > 0 {
+		given += string(givenNameChars[r.IntN(len(givenNameChars))])
+	}
+	return surname + given
+}
+
+// idCard 生成出生日期为 birth 的18位身份证号，ids 记录已生成的号码以避免重复
+func idCard(r *rand.Rand, birth time.Time, ids map[string]bool) string {
+	for {
+		region := regionCodes[r.IntN(len(regionCodes))]
+		number := pkg.CompleteIDCard(fmt.Sprintf("%s%s%03d", region, birth.Format("20060102"), 1+r.IntN(999)))
+		if !ids[number] {
+			ids[number] = true
+			return number
+		}
+	}
+}
+
+// randomDate 在 from 年1月1日到 to 年12月31日之间随机选择一天
+func randomDate(r *rand.Rand, from, to int) time.Time {
+	start := time.Date(from, time.January, 1, 0, 0, 0, 0, time.UTC)
+	days := int(time.Date(to+1, time.January, 1, 0, 0, 0, 0, time.UTC).Sub(start).Hours() / 24)
+	return start.AddDate(0, 0, r.IntN(days))
+}
+
+var phonePrefixes = []string{"130", "135", "138", "139", "150", "158", "177", "186", "188", "199"}
+
+func phone(r *rand.Rand) string {
+	return fmt.Sprintf("%s%08d", phonePrefixes[r.IntN(len(phonePrefixes))], r.IntN(100000000))
+}
+
+// publicID 由随机数生成器生成 UUID v4 格式的标识，格式与 pkg.NewPublicID 相同
+func publicID(r *rand.Rand) string {
+	var b [16]byte
+	for i := 0; i < len(b); i += 8 {
+		v := r.Uint64()
+		for j := 0; j < 8; j++ {
+			b[i+j] = byte(v >> (8 * j))
+		}
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}